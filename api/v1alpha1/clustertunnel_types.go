@@ -24,6 +24,7 @@ import (
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
 //+kubebuilder:printcolumn:name="TunnelID",type=string,JSONPath=`.status.tunnelId`
+//+kubebuilder:printcolumn:name="Authenticated",type=string,JSONPath=`.status.conditions[?(@.type=="Authenticated")].status`
 
 // ClusterTunnel is the Schema for the clustertunnels API
 type ClusterTunnel struct {
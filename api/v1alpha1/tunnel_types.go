@@ -55,6 +55,7 @@ type CloudflareDetails struct {
 
 	//+kubebuilder:validation:Optional
 	// Account ID in Cloudflare. AccountId and AccountName cannot be both empty. If both are provided, Account ID is used if valid, else falls back to Account Name.
+	// Zones are looked up in the account, disambiguating API tokens spanning several accounts.
 	AccountId string `json:"accountId,omitempty"`
 
 	//+kubebuilder:validation:Optional
@@ -83,6 +84,67 @@ type CloudflareDetails struct {
 	CLOUDFLARE_TUNNEL_CREDENTIAL_SECRET string `json:"CLOUDFLARE_TUNNEL_CREDENTIAL_SECRET,omitempty"`
 }
 
+// OriginRequestSpec defines the default origin request configuration applied to all ingress rules of the tunnel
+type OriginRequestSpec struct {
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:default:=false
+	// NoHappyEyeballs disables the "happy eyeballs" algorithm for IPv4/IPv6 fallback when connecting to origins.
+	NoHappyEyeballs bool `json:"noHappyEyeballs,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:default:=false
+	// Http2Origin makes cloudflared connect to origins using HTTP/2. Origins must be served over HTTPS.
+	Http2Origin bool `json:"http2Origin,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// DisableChunkedEncoding disables chunked transfer encoding to origins, useful for WSGI servers.
+	// Unset leaves it to cloudflared, which keeps chunked encoding enabled.
+	DisableChunkedEncoding *bool `json:"disableChunkedEncoding,omitempty"`
+}
+
+// DNSSpec defines the defaults for the DNS records of the tunnel
+type DNSSpec struct {
+	//+kubebuilder:validation:Optional
+	// DefaultProxied sets whether DNS records are proxied through Cloudflare. Defaults to true.
+	// Unproxied records only resolve for clients that can reach the tunnel domain, like WARP clients.
+	DefaultProxied *bool `json:"defaultProxied,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=86400
+	// DefaultTTL sets the TTL of DNS records in seconds, 1 being automatic. Defaults to 1.
+	// Proxied records always use the automatic TTL.
+	DefaultTTL int `json:"defaultTTL,omitempty"`
+}
+
+// CaPoolSpec references a CA bundle in a Secret or ConfigMap in the namespace of the tunnel resources
+type CaPoolSpec struct {
+	//+kubebuilder:validation:Optional
+	// SecretName is the name of the Secret with the CA bundle. Exactly one of SecretName and ConfigMapName must be set.
+	SecretName string `json:"secretName,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// ConfigMapName is the name of the ConfigMap with the CA bundle, like one injected by a trust manager.
+	ConfigMapName string `json:"configMapName,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:default:="ca.crt"
+	// Key of the CA bundle in the Secret or ConfigMap. Defaults to ca.crt.
+	Key string `json:"key,omitempty"`
+}
+
+// ConfigTemplateSpec references a cloudflared configuration template in a ConfigMap in the namespace of the tunnel resources
+type ConfigTemplateSpec struct {
+	//+kubebuilder:validation:Required
+	// ConfigMapName is the name of the ConfigMap with the template.
+	ConfigMapName string `json:"configMapName"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:default:="config.yaml"
+	// Key of the template in the ConfigMap. Defaults to config.yaml.
+	Key string `json:"key,omitempty"`
+}
+
 // TunnelSpec defines the desired state of Tunnel
 type TunnelSpec struct {
 	//+kubebuilder:validation:Minimum=0
@@ -94,6 +156,7 @@ type TunnelSpec struct {
 	//+kubebuilder:default:="cloudflare/cloudflared:2022.12.1"
 	//+kubebuilder:validation:Optional
 	// Image sets the Cloudflared Image to use. Defaults to the image set during the release of the operator.
+	// Changing it rolls out the Deployment, it must be a valid image reference like cloudflare/cloudflared:2022.12.1.
 	Image string `json:"image,omitempty"`
 
 	//+kubebuilder:default:=false
@@ -101,23 +164,149 @@ type TunnelSpec struct {
 	// NoTlsVerify disables origin TLS certificate checks when the endpoint is HTTPS.
 	NoTlsVerify bool `json:"noTlsVerify,omitempty"`
 
+	//+kubebuilder:validation:Optional
+	// CaPool references a Secret or ConfigMap with the CA bundle trusted for all HTTPS origins of the tunnel.
+	// It is mounted into cloudflared and set as the originRequest.caPool default, taking precedence over the tls.crt of OriginCaPool.
+	CaPool *CaPoolSpec `json:"caPool,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// ConfigTemplate references a cloudflared config.yaml used as the base of the tunnel configuration, for settings like
+	// warp-routing or loglevel. The operator only sets its tunnel, credentials-file and ingress keys, keeping the rest as is.
+	ConfigTemplate *ConfigTemplateSpec `json:"configTemplate,omitempty"`
+
 	//+kubebuilder:validation:Optional
 	// OriginCaPool speficies the secret with tls.crt (and other certs as needed to be referred in the service annotation) of the Root CA to be trusted when sending traffic to HTTPS endpoints
 	OriginCaPool string `json:"originCaPool,omitempty"`
 
 	//+kubebuilder:validation:Optional
-	// NodeSelectors specifies the nodeSelectors to apply to the cloudflared tunnel deployment
+	// OriginRequest specifies the default origin request configuration for all services on this tunnel
+	OriginRequest OriginRequestSpec `json:"originRequest,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=0
+	// Retries sets the maximum number of retries for cloudflared connection and protocol errors, passed as --retries.
+	// Defaults to the cloudflared default.
+	Retries *int32 `json:"retries,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=8
+	// HAConnections sets the number of connections each cloudflared replica opens to the Cloudflare edge, passed as --ha-connections.
+	// More connections increase resilience to edge failures at the cost of resources. Defaults to the cloudflared default of 4.
+	HAConnections *int32 `json:"haConnections,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"
+	// GracePeriod sets how long cloudflared waits for in-flight requests on shutdown, as a duration like 30s, passed as --grace-period.
+	// Defaults to the cloudflared default.
+	GracePeriod string `json:"gracePeriod,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// ExtraArgs are appended to the cloudflared tunnel arguments, for flags the operator does not model like --loglevel=debug.
+	// Flags managed by the operator, like --config, --metrics, --token and the ones of the other fields, cannot be set.
+	// Changes roll out the Deployment once.
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Enum:="4";"6";"auto"
+	// EdgeIPVersion sets the IP version cloudflared uses to connect to the Cloudflare edge, passed as --edge-ip-version.
+	// Defaults to the cloudflared default.
+	EdgeIPVersion string `json:"edgeIPVersion,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// NoAutoupdate disables the cloudflared self update, passed as --no-autoupdate. Updates restart cloudflared outside of the operator.
+	// Defaults to true, which the generated configuration already sets.
+	NoAutoupdate *bool `json:"noAutoupdate,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// NodeSelectors specifies the nodeSelectors to apply to the cloudflared tunnel deployment, like nodes with egress.
+	// Changes roll out the Deployment once.
 	NodeSelectors map[string]string `json:"nodeSelectors,omitempty"`
 
 	//+kubebuilder:validation:Optional
-	// Tolerations specifies the tolerations to apply to the cloudflared tunnel deployment
+	// Tolerations specifies the tolerations to apply to the cloudflared tunnel deployment.
+	// Changes roll out the Deployment once.
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 
+	//+kubebuilder:validation:Optional
+	// TopologySpreadConstraints specifies the topology spread constraints to apply to the cloudflared tunnel deployment.
+	// A constraint without a labelSelector selects the cloudflared pods of this tunnel. Cannot be used with SpreadAcrossZones.
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// Resources specifies the resource requests and limits of the cloudflared container.
+	// Defaults to requests of 10m cpu and 30Mi memory, and limits of 500m cpu and 256Mi memory.
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// LivenessProbe specifies the liveness probe of the cloudflared container. Changes roll out the Deployment once.
+	// Defaults to the /ready endpoint of the metrics port, every 10s after 10s, restarting on the first failure.
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// ReadinessProbe specifies the readiness probe of the cloudflared container, so that rollouts wait for the new pods to be
+	// connected to the Cloudflare edge. Changes roll out the Deployment once.
+	// Defaults to the /ready endpoint of the metrics port, every 5s.
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// SecurityContext specifies the security context of the cloudflared container, like dropping all capabilities for
+	// the restricted Pod Security Standard. Changes roll out the Deployment once.
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// PodSecurityContext specifies the security context of the cloudflared pods, like runAsNonRoot and the seccomp profile.
+	// Changes roll out the Deployment once.
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:default:=false
+	// SpreadAcrossZones spreads the cloudflared pods across zones and nodes on a best effort basis.
+	// Shorthand for topology spread constraints on topology.kubernetes.io/zone and kubernetes.io/hostname.
+	SpreadAcrossZones bool `json:"spreadAcrossZones,omitempty"`
+
 	//+kubebuilder:validation:Optional
 	//+kubebuilder:default:="http_status:404"
 	// FallbackTarget speficies the target for requests that do not match an ingress. Defaults to http_status:404
 	FallbackTarget string `json:"fallbackTarget,omitempty"`
 
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:default:=false
+	// PreserveFallbackTarget keeps the existing catch-all rule in the ConfigMap instead of replacing it with FallbackTarget.
+	// Useful when the catch-all is managed outside the operator. FallbackTarget is used only if no catch-all rule exists.
+	PreserveFallbackTarget bool `json:"preserveFallbackTarget,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// ManageCatchAll set to false never adds a catch-all rule, keeping the existing one in the ConfigMap if any.
+	// For catch-all rules templated outside the operator, a Warning Event is emitted if the configuration has none. Defaults to true.
+	ManageCatchAll *bool `json:"manageCatchAll,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// RestartOnConfigChange restarts the cloudflared pods when the ingress configuration in the ConfigMap changes.
+	// Set to false when cloudflared does not run from the ConfigMap, like with a remote-managed configuration. Defaults to true.
+	RestartOnConfigChange *bool `json:"restartOnConfigChange,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// ManageDeployment set to false leaves the cloudflared Deployment to another controller, like Helm, the operator only manages
+	// the ConfigMap, Secret and DNS entries. Configuration changes are signalled with a checksum annotation on the ConfigMap
+	// instead of restarting the Deployment. Defaults to true.
+	ManageDeployment *bool `json:"manageDeployment,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// ArgoSmartRouting enables (true) or disables (false) Argo Smart Routing on the zone of the tunnel domain.
+	// This is a zone-wide and billable setting, left untouched unless specified.
+	ArgoSmartRouting *bool `json:"argoSmartRouting,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// ClusterDomain is the DNS domain of the cluster, like cluster.local, appended to the generated Service targets
+	// as <service>.<namespace>.svc.<clusterDomain>. Defaults to empty, leaving <service>.<namespace>.svc to the search domains of cloudflared.
+	ClusterDomain string `json:"clusterDomain,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	// DNS specifies the defaults for the DNS records created for the TunnelBindings of this tunnel
+	DNS DNSSpec `json:"dns,omitempty"`
+
 	//+kubebuilder:validation:Required
 	// Cloudflare Credentials
 	Cloudflare CloudflareDetails `json:"cloudflare,omitempty"`
@@ -139,11 +328,18 @@ type TunnelStatus struct {
 	TunnelName string `json:"tunnelName"`
 	AccountId  string `json:"accountId"`
 	ZoneId     string `json:"zoneId"`
+
+	//+kubebuilder:validation:Optional
+	//+listType=map
+	//+listMapKey=type
+	// Conditions of the tunnel, like Authenticated reporting whether the Cloudflare API credentials work
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="TunnelID",type=string,JSONPath=`.status.tunnelId`
+//+kubebuilder:printcolumn:name="Authenticated",type=string,JSONPath=`.status.conditions[?(@.type=="Authenticated")].status`
 
 // Tunnel is the Schema for the tunnels API
 type Tunnel struct {
@@ -20,6 +20,71 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// AccessSpec makes cloudflared validate the Cloudflare Access JWT of the requests to a subject
+type AccessSpec struct {
+	// Required rejects requests without a valid Access JWT, defaults to true
+	//+kubebuilder:validation:Optional
+	Required *bool `json:"required,omitempty"`
+
+	// TeamName is the Cloudflare Zero Trust team name, like myteam for myteam.cloudflareaccess.com.
+	// The full team domain is accepted as well.
+	//+kubebuilder:validation:Optional
+	TeamName string `json:"teamName,omitempty"`
+
+	// AudTag lists the Application Audience (AUD) tags of the Access applications allowed to reach the subject
+	//+kubebuilder:validation:Optional
+	AudTag []string `json:"audTag,omitempty"`
+}
+
+// SRVSpec defines an SRV record pointing to the hostname of a subject
+type SRVSpec struct {
+	// Service is the symbolic name of the service without the leading underscore, like minecraft or sip
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:Pattern="^[a-zA-Z0-9-]+$"
+	Service string `json:"service"`
+
+	// Proto is the transport protocol without the leading underscore
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Enum:="tcp";"udp"
+	//+kubebuilder:default:="tcp"
+	Proto string `json:"proto,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=65535
+	Priority int `json:"priority,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=65535
+	Weight int `json:"weight,omitempty"`
+
+	// Port clients connect to on the hostname
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	Port int `json:"port"`
+}
+
+// CacheSpec defines the edge cache settings of the hostname of a subject
+type CacheSpec struct {
+	// Bypass disables the cache for the hostname, EdgeTTL and BrowserTTL are ignored
+	//+kubebuilder:validation:Optional
+	Bypass bool `json:"bypass,omitempty"`
+
+	// EdgeTTL caches eligible responses at the edge for this many seconds, overriding the Cache-Control headers of the origin.
+	// The headers of the origin are respected if unset.
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=1
+	EdgeTTL int `json:"edgeTTL,omitempty"`
+
+	// BrowserTTL makes browsers cache the responses for this many seconds, overriding the Cache-Control headers of the origin.
+	// The headers of the origin are respected if unset.
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=1
+	BrowserTTL int `json:"browserTTL,omitempty"`
+}
+
 // TunnelBindingSubject defines the subject TunnelBinding connects to the Tunnel
 type TunnelBindingSubject struct {
 	// Kind can be Service
@@ -28,6 +93,9 @@ type TunnelBindingSubject struct {
 	Kind string `json:"kind"`
 	//+kubebuilder:validation:Required
 	Name string `json:"name"`
+	// Namespace of the Service, for targeting a Service in another namespace. Defaults to the TunnelBinding namespace
+	//+kubebuilder:validation:Optional
+	Namespace string `json:"namespace,omitempty"`
 	//+kubebuilder:validation:Optional
 	Spec TunnelBindingSubjectSpec `json:"spec"`
 }
@@ -40,19 +108,37 @@ type TunnelBindingSubjectSpec struct {
 	//+kubebuilder:validation:Optional
 	Fqdn string `json:"fqdn,omitempty"`
 
+	// DNSName specifies the name of the DNS record to create instead of the fqdn, which stays the hostname cloudflared matches on.
+	// Useful when the public name reaches the fqdn through a CNAME chain. Must be in the zone of the tunnel domain.
+	//+kubebuilder:validation:Optional
+	DNSName string `json:"dnsName,omitempty"`
+
 	// Protocol specifies the protocol for the service. Should be one of http, https, tcp, udp, ssh or rdp.
 	// Defaults to http, with the exceptions of https for 443, smb for 139 and 445, rdp for 3389 and ssh for 22 if the service has a TCP port.
+	// The appProtocol of a TCP port takes precedence over its number, like https for https and grpc, or http for kubernetes.io/h2c.
 	// The only available option for a UDP port is udp, which is default.
+	// A comma separated preference list like https,http can be provided: the default protocol of the port is used if listed,
+	// else the first valid protocol in the list.
 	//+kubebuilder:validation:Optional
 	Protocol string `json:"protocol,omitempty"`
 
+	// Port specifies the port of the external target for ExternalName Services, which do not need to list ports.
+	// Defaults to the first port of the Service if it has any. Ignored for other Service types.
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
+
 	// Path specifies a regular expression for to match on the request for http/https services
-	// If a rule does not specify a path, all paths will be matched.
+	// If a rule does not specify a path, all paths will be matched. Must be a valid Go regular expression.
 	//+kubebuilder:validation:Optional
 	Path string `json:"path,omitempty"`
 
 	// Target specified where the tunnel should proxy to.
 	// Defaults to the form of <protocol>://<service.metadata.name>.<service.metadata.namespace>.svc:<port>
+	// A unix socket shared with cloudflared can be targeted with unix:/absolute/path or unix:///absolute/path.
+	// A backend with a stable IP, like a VIP, can be targeted directly with <protocol>://<ip>:<port> or <ip>:<port>,
+	// with IPv6 addresses in brackets. The protocol defaults as for a TCP port of the Service.
 	//+kubebuilder:validation:Optional
 	Target string `json:"target,omitempty"`
 
@@ -67,6 +153,105 @@ type TunnelBindingSubjectSpec struct {
 	//+kubebuilder:default:=false
 	NoTlsVerify bool `json:"noTlsVerify"`
 
+	// NoHappyEyeballs disables the "happy eyeballs" IPv4/IPv6 fallback for this service.
+	// Defaults to tunnel.spec.originRequest.noHappyEyeballs.
+	//+kubebuilder:validation:Optional
+	NoHappyEyeballs *bool `json:"noHappyEyeballs,omitempty"`
+
+	// Http2Origin makes cloudflared connect to this service using HTTP/2. Only useful if the protocol is HTTPS.
+	// Defaults to true for a Service port with the kubernetes.io/h2c appProtocol served over http,
+	// else to tunnel.spec.originRequest.http2Origin. Set to false to disable it for this service only.
+	//+kubebuilder:validation:Optional
+	Http2Origin *bool `json:"http2Origin,omitempty"`
+
+	// Access makes cloudflared validate the Cloudflare Access JWT of the requests to this service.
+	// Both teamName and audTag are required, the hostname serves http_status:404 until a partial config is fixed.
+	//+kubebuilder:validation:Optional
+	Access *AccessSpec `json:"access,omitempty"`
+
+	// DisableChunkedEncoding disables chunked transfer encoding to this service. Only useful if the protocol is HTTP or HTTPS.
+	// Defaults to tunnel.spec.originRequest.disableChunkedEncoding, set to false to enable it for this service only.
+	//+kubebuilder:validation:Optional
+	DisableChunkedEncoding *bool `json:"disableChunkedEncoding,omitempty"`
+
+	// TCPKeepAlive sets the TCP keepalive interval for the connection to the service, as a duration like 30s.
+	// Only used if the protocol is tcp or udp.
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"
+	TCPKeepAlive string `json:"tcpKeepAlive,omitempty"`
+
+	// TLSTimeout sets how long cloudflared waits for the TLS handshake with the service, as a duration like 30s,
+	// for origins with large certificate chains or OCSP stapling. Unlike connectTimeout, it starts once the TCP connection is established.
+	// Only used if the protocol is https, defaults to the 10s of cloudflared.
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"
+	TLSTimeout string `json:"tlsTimeout,omitempty"`
+
+	// SRV creates an SRV record named _service._proto.hostname pointing to the hostname, for services discovered through SRV records.
+	// Only used for tcp and udp targets. The record is deleted with the DNS record of the hostname.
+	//+kubebuilder:validation:Optional
+	SRV *SRVSpec `json:"srv,omitempty"`
+
+	// HttpHostHeader sets the HTTP Host header sent to this service. Only useful if the protocol is HTTP or HTTPS.
+	//+kubebuilder:validation:Optional
+	HttpHostHeader string `json:"httpHostHeader,omitempty"`
+
+	// BastionMode makes cloudflared act as a jump host for this hostname, letting clients reach any destination
+	// the cloudflared pods can reach. The Service and target are not used, HTTP only options cannot be set.
+	//+kubebuilder:validation:Optional
+	BastionMode bool `json:"bastionMode,omitempty"`
+
+	// Maintenance makes cloudflared respond with a 503 status for this hostname instead of proxying to the service.
+	// The DNS record and the rest of the configuration are kept, so that unsetting it restores the service.
+	//+kubebuilder:validation:Optional
+	Maintenance bool `json:"maintenance,omitempty"`
+
+	// DirectPods makes cloudflared connect to the pods of a headless Service directly, on the targetPort of its first port,
+	// through the Service DNS name resolving to the pod IPs. Ignored with a Warning Event for Services with a ClusterIP.
+	//+kubebuilder:validation:Optional
+	DirectPods bool `json:"directPods,omitempty"`
+
+	// Weight sends this percentage of the requests to the hostname to this subject through a Cloudflare Load Balancer, for canary deployments.
+	// All the subjects of the TunnelBinding with the hostname need a weight, summing to 100, and no path. Requires the --manage-load-balancers
+	// flag of the operator, ignored with a Warning Event otherwise. The Load Balancer is removed with the DNS record of the hostname.
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=100
+	Weight *int `json:"weight,omitempty"`
+
+	// Before places the ingress rule of this subject above the rules of the given hostname, overriding the default order
+	// where specific hostnames come before wildcards. Hostnames without rules in the tunnel are ignored.
+	//+kubebuilder:validation:Optional
+	Before string `json:"before,omitempty"`
+
+	// After places the ingress rule of this subject below the rules of the given hostname, overriding the default order
+	// where specific hostnames come before wildcards. Hostnames without rules in the tunnel are ignored.
+	//+kubebuilder:validation:Optional
+	After string `json:"after,omitempty"`
+
+	// Proxied sets whether the DNS record is proxied through Cloudflare.
+	// Defaults to tunnel.spec.dns.defaultProxied.
+	//+kubebuilder:validation:Optional
+	Proxied *bool `json:"proxied,omitempty"`
+
+	// TTL sets the TTL of the DNS record in seconds, 1 being automatic. Proxied records always use the automatic TTL.
+	// Defaults to tunnel.spec.dns.defaultTTL.
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=86400
+	TTL int `json:"ttl,omitempty"`
+
+	// RegionKey restricts where Cloudflare terminates TLS and processes the requests to the hostname with Data Localization, like us or eu.
+	// Requires Regional Services on the account, skipped with a Warning Event otherwise. The setting is removed with the DNS record of the hostname.
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Pattern="^[a-z0-9_]+$"
+	RegionKey string `json:"regionKey,omitempty"`
+
+	// Cache sets the edge cache settings of the hostname with a cache rule of the zone, for static content.
+	// Requires the --manage-cache-rules flag of the operator, ignored with a Warning Event otherwise. The rule is removed with the DNS record of the hostname.
+	//+kubebuilder:validation:Optional
+	Cache *CacheSpec `json:"cache,omitempty"`
+
 	// cloudflared starts a proxy server to translate HTTP traffic into TCP when proxying, for example, SSH or RDP.
 
 	// ProxyAddress configures the listen address for that proxy
@@ -109,6 +294,10 @@ type ServiceInfo struct {
 	Hostname string `json:"hostname"`
 	// Target for cloudflared
 	Target string `json:"target"`
+	// Name of the DNS record, if different from the hostname
+	DNSName string `json:"dnsName,omitempty"`
+	// Http2Origin is set if the Service port has an h2c appProtocol, turning http2Origin on unless the subject sets it
+	Http2Origin bool `json:"http2Origin,omitempty"`
 }
 
 // TunnelBindingStatus defines the observed state of TunnelBinding
@@ -116,6 +305,16 @@ type TunnelBindingStatus struct {
 	// To show on the kubectl cli
 	Hostnames string        `json:"hostnames"`
 	Services  []ServiceInfo `json:"services"`
+
+	// DNSDeleteFailures counts the failed attempts to delete the DNS entries while the TunnelBinding is being deleted
+	//+kubebuilder:validation:Optional
+	DNSDeleteFailures int `json:"dnsDeleteFailures,omitempty"`
+
+	//+kubebuilder:validation:Optional
+	//+listType=map
+	//+listMapKey=type
+	// Conditions of the TunnelBinding, ConfigReady for the ingress rules and DNSReady for the DNS entries
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
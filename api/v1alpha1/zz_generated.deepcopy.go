@@ -22,9 +22,65 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessSpec) DeepCopyInto(out *AccessSpec) {
+	*out = *in
+	if in.Required != nil {
+		in, out := &in.Required, &out.Required
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AudTag != nil {
+		in, out := &in.AudTag, &out.AudTag
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessSpec.
+func (in *AccessSpec) DeepCopy() *AccessSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CaPoolSpec) DeepCopyInto(out *CaPoolSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaPoolSpec.
+func (in *CaPoolSpec) DeepCopy() *CaPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CaPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CacheSpec) DeepCopyInto(out *CacheSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheSpec.
+func (in *CacheSpec) DeepCopy() *CacheSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CacheSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CloudflareDetails) DeepCopyInto(out *CloudflareDetails) {
 	*out = *in
@@ -46,7 +102,7 @@ func (in *ClusterTunnel) DeepCopyInto(out *ClusterTunnel) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTunnel.
@@ -99,6 +155,41 @@ func (in *ClusterTunnelList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigTemplateSpec) DeepCopyInto(out *ConfigTemplateSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigTemplateSpec.
+func (in *ConfigTemplateSpec) DeepCopy() *ConfigTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSSpec) DeepCopyInto(out *DNSSpec) {
+	*out = *in
+	if in.DefaultProxied != nil {
+		in, out := &in.DefaultProxied, &out.DefaultProxied
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSSpec.
+func (in *DNSSpec) DeepCopy() *DNSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExistingTunnel) DeepCopyInto(out *ExistingTunnel) {
 	*out = *in
@@ -129,6 +220,41 @@ func (in *NewTunnel) DeepCopy() *NewTunnel {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OriginRequestSpec) DeepCopyInto(out *OriginRequestSpec) {
+	*out = *in
+	if in.DisableChunkedEncoding != nil {
+		in, out := &in.DisableChunkedEncoding, &out.DisableChunkedEncoding
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginRequestSpec.
+func (in *OriginRequestSpec) DeepCopy() *OriginRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OriginRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SRVSpec) DeepCopyInto(out *SRVSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SRVSpec.
+func (in *SRVSpec) DeepCopy() *SRVSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SRVSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceInfo) DeepCopyInto(out *ServiceInfo) {
 	*out = *in
@@ -150,7 +276,7 @@ func (in *Tunnel) DeepCopyInto(out *Tunnel) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tunnel.
@@ -179,7 +305,9 @@ func (in *TunnelBinding) DeepCopyInto(out *TunnelBinding) {
 	if in.Subjects != nil {
 		in, out := &in.Subjects, &out.Subjects
 		*out = make([]TunnelBindingSubject, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	out.TunnelRef = in.TunnelRef
 	in.Status.DeepCopyInto(&out.Status)
@@ -243,6 +371,13 @@ func (in *TunnelBindingStatus) DeepCopyInto(out *TunnelBindingStatus) {
 		*out = make([]ServiceInfo, len(*in))
 		copy(*out, *in)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelBindingStatus.
@@ -258,7 +393,7 @@ func (in *TunnelBindingStatus) DeepCopy() *TunnelBindingStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelBindingSubject) DeepCopyInto(out *TunnelBindingSubject) {
 	*out = *in
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelBindingSubject.
@@ -274,6 +409,46 @@ func (in *TunnelBindingSubject) DeepCopy() *TunnelBindingSubject {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelBindingSubjectSpec) DeepCopyInto(out *TunnelBindingSubjectSpec) {
 	*out = *in
+	if in.NoHappyEyeballs != nil {
+		in, out := &in.NoHappyEyeballs, &out.NoHappyEyeballs
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Http2Origin != nil {
+		in, out := &in.Http2Origin, &out.Http2Origin
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Access != nil {
+		in, out := &in.Access, &out.Access
+		*out = new(AccessSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DisableChunkedEncoding != nil {
+		in, out := &in.DisableChunkedEncoding, &out.DisableChunkedEncoding
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SRV != nil {
+		in, out := &in.SRV, &out.SRV
+		*out = new(SRVSpec)
+		**out = **in
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int)
+		**out = **in
+	}
+	if in.Proxied != nil {
+		in, out := &in.Proxied, &out.Proxied
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(CacheSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelBindingSubjectSpec.
@@ -336,6 +511,37 @@ func (in *TunnelRef) DeepCopy() *TunnelRef {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelSpec) DeepCopyInto(out *TunnelSpec) {
 	*out = *in
+	if in.CaPool != nil {
+		in, out := &in.CaPool, &out.CaPool
+		*out = new(CaPoolSpec)
+		**out = **in
+	}
+	if in.ConfigTemplate != nil {
+		in, out := &in.ConfigTemplate, &out.ConfigTemplate
+		*out = new(ConfigTemplateSpec)
+		**out = **in
+	}
+	in.OriginRequest.DeepCopyInto(&out.OriginRequest)
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.HAConnections != nil {
+		in, out := &in.HAConnections, &out.HAConnections
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ExtraArgs != nil {
+		in, out := &in.ExtraArgs, &out.ExtraArgs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NoAutoupdate != nil {
+		in, out := &in.NoAutoupdate, &out.NoAutoupdate
+		*out = new(bool)
+		**out = **in
+	}
 	if in.NodeSelectors != nil {
 		in, out := &in.NodeSelectors, &out.NodeSelectors
 		*out = make(map[string]string, len(*in))
@@ -350,6 +556,59 @@ func (in *TunnelSpec) DeepCopyInto(out *TunnelSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadinessProbe != nil {
+		in, out := &in.ReadinessProbe, &out.ReadinessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodSecurityContext != nil {
+		in, out := &in.PodSecurityContext, &out.PodSecurityContext
+		*out = new(v1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManageCatchAll != nil {
+		in, out := &in.ManageCatchAll, &out.ManageCatchAll
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RestartOnConfigChange != nil {
+		in, out := &in.RestartOnConfigChange, &out.RestartOnConfigChange
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ManageDeployment != nil {
+		in, out := &in.ManageDeployment, &out.ManageDeployment
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ArgoSmartRouting != nil {
+		in, out := &in.ArgoSmartRouting, &out.ArgoSmartRouting
+		*out = new(bool)
+		**out = **in
+	}
+	in.DNS.DeepCopyInto(&out.DNS)
 	out.Cloudflare = in.Cloudflare
 	out.ExistingTunnel = in.ExistingTunnel
 	out.NewTunnel = in.NewTunnel
@@ -368,6 +627,13 @@ func (in *TunnelSpec) DeepCopy() *TunnelSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelStatus) DeepCopyInto(out *TunnelStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelStatus.
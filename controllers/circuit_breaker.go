@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker backs off the Cloudflare API operations of a tunnel after consecutive failures, so that a tunnel with
+// broken credentials or zone is not retried on every reconcile of each of its TunnelBindings.
+// Once the cooldown expired the circuit half-opens, letting a single reconcile through to probe the API.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	tunnels map[tunnelKey]*circuitState
+}
+
+// circuitState is the breaker state of a tunnel
+type circuitState struct {
+	failures int
+	// openedAt is zero while the circuit is closed, and moved forward when a probe is let through
+	openedAt time.Time
+}
+
+// newCircuitBreaker returns a breaker opening after threshold consecutive failures, or nil if threshold is 0.
+// A nil breaker lets everything through.
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now, tunnels: map[tunnelKey]*circuitState{}}
+}
+
+// allow checks if an operation can run on the tunnel, else returns how long until the circuit half-opens.
+// The probe let through by a half-open circuit re-opens it for another cooldown until it reports its outcome,
+// so a probe that never reports does not keep the other reconciles blocked.
+func (b *circuitBreaker) allow(key tunnelKey) (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.tunnels[key]
+	if !ok || state.openedAt.IsZero() {
+		return true, 0
+	}
+	now := b.now()
+	if remaining := state.openedAt.Add(b.cooldown).Sub(now); remaining > 0 {
+		return false, remaining
+	}
+	state.openedAt = now
+	return true, 0
+}
+
+// success closes the circuit of the tunnel
+func (b *circuitBreaker) success(key tunnelKey) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.tunnels, key)
+	tunnelCircuitOpen.WithLabelValues(key.Kind, key.Namespace, key.Name).Set(0)
+}
+
+// failure records a failed operation on the tunnel, and returns true if it opened the circuit
+func (b *circuitBreaker) failure(key tunnelKey) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.tunnels[key]
+	if !ok {
+		state = &circuitState{}
+		b.tunnels[key] = state
+	}
+	state.failures++
+	if state.failures < b.threshold {
+		return false
+	}
+	// A failed probe re-opens the circuit for a full cooldown
+	opened := state.openedAt.IsZero()
+	state.openedAt = b.now()
+	tunnelCircuitOpen.WithLabelValues(key.Kind, key.Namespace, key.Name).Set(1)
+	return opened
+}
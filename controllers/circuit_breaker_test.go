@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = time.Minute
+	type step struct {
+		name string
+		// advance moves the clock before the operation
+		advance time.Duration
+		// op is allow, failure or success
+		op string
+		// want is the result of allow, or if failure opened the circuit
+		want bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "failed probe re-opens",
+			steps: []step{
+				{name: "closed", op: "allow", want: true},
+				{name: "first failure", op: "failure", want: false},
+				{name: "below threshold", op: "allow", want: true},
+				{name: "threshold reached", op: "failure", want: true},
+				{name: "open", op: "allow", want: false},
+				{name: "still open", advance: cooldown - time.Second, op: "allow", want: false},
+				{name: "half-open probe", advance: time.Second, op: "allow", want: true},
+				{name: "single probe", op: "allow", want: false},
+				{name: "probe fails", op: "failure", want: false},
+				{name: "re-opened", advance: cooldown - time.Second, op: "allow", want: false},
+				{name: "next probe", advance: time.Second, op: "allow", want: true},
+			},
+		},
+		{
+			name: "successful probe closes",
+			steps: []step{
+				{name: "first failure", op: "failure", want: false},
+				{name: "threshold reached", op: "failure", want: true},
+				{name: "open", op: "allow", want: false},
+				{name: "half-open probe", advance: cooldown, op: "allow", want: true},
+				{name: "probe succeeds", op: "success"},
+				{name: "closed", op: "allow", want: true},
+				{name: "failures counted again", op: "failure", want: false},
+				{name: "still closed", op: "allow", want: true},
+			},
+		},
+		{
+			name: "success resets the failures",
+			steps: []step{
+				{name: "first failure", op: "failure", want: false},
+				{name: "success", op: "success"},
+				{name: "first failure again", op: "failure", want: false},
+				{name: "closed", op: "allow", want: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+			breaker := newCircuitBreaker(2, cooldown)
+			breaker.now = func() time.Time { return now }
+			key := tunnelKey{Kind: "tunnel", Namespace: "default", Name: "breaker-test"}
+			for _, s := range tt.steps {
+				now = now.Add(s.advance)
+				var got bool
+				switch s.op {
+				case "allow":
+					got, _ = breaker.allow(key)
+				case "failure":
+					got = breaker.failure(key)
+				case "success":
+					breaker.success(key)
+					continue
+				}
+				if got != s.want {
+					t.Fatalf("%s: %s() = %v, want %v", s.name, s.op, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerRemaining(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := newCircuitBreaker(1, time.Minute)
+	breaker.now = func() time.Time { return now }
+	key := tunnelKey{Kind: "clustertunnel", Namespace: "cloudflare-operator-system", Name: "breaker-test"}
+
+	breaker.failure(key)
+	now = now.Add(20 * time.Second)
+	if allowed, remaining := breaker.allow(key); allowed || remaining != 40*time.Second {
+		t.Errorf("allow() = %v, %v, want false, 40s", allowed, remaining)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := newCircuitBreaker(0, time.Minute)
+	key := tunnelKey{Kind: "tunnel", Namespace: "default", Name: "breaker-test"}
+	for i := 0; i < 3; i++ {
+		if breaker.failure(key) {
+			t.Fatalf("failure() opened a disabled breaker")
+		}
+	}
+	if allowed, _ := breaker.allow(key); !allowed {
+		t.Errorf("allow() = false on a disabled breaker")
+	}
+}
@@ -5,7 +5,11 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/go-logr/logr"
@@ -14,6 +18,24 @@ import (
 // TXT_PREFIX is the prefix added to TXT records for whom the corresponding DNS records are managed by the operator.
 const TXT_PREFIX = "_managed."
 
+// managedRecordComment is the comment set on the DNS records managed by the operator
+const managedRecordComment = "Managed by cloudflare-operator"
+
+// errInvalidZone is returned when the domain is not a zone of the account
+var errInvalidZone = errors.New("domain is not a zone of the account")
+
+// errAmbiguousZone is returned when the domain is a zone of several accounts of the credentials
+var errAmbiguousZone = errors.New("zone is in more than one account")
+
+// errNotEntitled is returned when the account does not have the feature an API call needs
+var errNotEntitled = errors.New("account is not entitled to the feature")
+
+// isNotFound checks if the error is a 404 of the Cloudflare API, which cloudflare-go returns as a *NotFoundError
+func isNotFound(err error) bool {
+	var notFound *cloudflare.NotFoundError
+	return errors.As(err, &notFound)
+}
+
 // CloudflareAPI config object holding all relevant fields to use the API
 type CloudflareAPI struct {
 	Log              logr.Logger
@@ -74,7 +96,7 @@ func (c *CloudflareAPI) CreateCloudflareTunnel() (string, string, error) {
 
 	if err != nil {
 		c.Log.Error(err, "error creating tunnel")
-		return "", "", err
+		return "", "", fmt.Errorf("error creating tunnel %s: %w", c.TunnelName, err)
 	}
 
 	c.ValidTunnelId = tunnel.ID
@@ -106,14 +128,14 @@ func (c *CloudflareAPI) DeleteCloudflareTunnel() error {
 	err := c.CloudflareClient.CleanupTunnelConnections(ctx, rc, c.ValidTunnelId)
 	if err != nil {
 		c.Log.Error(err, "error cleaning tunnel connections", "tunnelId", c.TunnelId)
-		return err
+		return fmt.Errorf("error cleaning connections of tunnel %s: %w", c.ValidTunnelId, err)
 	}
 
 	ctx = context.Background()
 	err = c.CloudflareClient.DeleteTunnel(ctx, rc, c.ValidTunnelId)
 	if err != nil {
 		c.Log.Error(err, "error deleting tunnel", "tunnelId", c.TunnelId)
-		return err
+		return fmt.Errorf("error deleting tunnel %s: %w", c.ValidTunnelId, err)
 	}
 
 	return nil
@@ -156,7 +178,7 @@ func (c *CloudflareAPI) GetAccountId() (string, error) {
 		c.Log.Info("Account ID failed, falling back to Account Name")
 		accountIdFromName, err := c.getAccountIdByName()
 		if err != nil {
-			return "", fmt.Errorf("error fetching Account ID by Account Name")
+			return "", fmt.Errorf("error fetching Account ID by Account Name %q: %w", c.AccountName, err)
 		}
 		c.ValidAccountId = accountIdFromName
 	}
@@ -189,6 +211,7 @@ func (c *CloudflareAPI) getAccountIdByName() (string, error) {
 
 	if err != nil {
 		c.Log.Error(err, "error listing accounts", "accountName", c.AccountName)
+		return "", fmt.Errorf("error listing accounts: %w", err)
 	}
 
 	switch len(accounts) {
@@ -199,7 +222,7 @@ func (c *CloudflareAPI) getAccountIdByName() (string, error) {
 	case 1:
 		return accounts[0].ID, nil
 	default:
-		err := fmt.Errorf("more than one account in response")
+		err := fmt.Errorf("more than one account named %q, set tunnel.spec.cloudflare.accountId", c.AccountName)
 		c.Log.Error(err, "found more than one account, check accountName", "accountName", c.AccountName)
 		return "", err
 	}
@@ -225,7 +248,7 @@ func (c *CloudflareAPI) GetTunnelId() (string, error) {
 	c.Log.Info("Tunnel ID failed, falling back to Tunnel Name")
 	tunnelIdFromName, err := c.getTunnelIdByName()
 	if err != nil {
-		return "", fmt.Errorf("error fetching Tunnel ID by Tunnel Name")
+		return "", fmt.Errorf("error fetching Tunnel ID by Tunnel Name %q: %w", c.TunnelName, err)
 	}
 	c.ValidTunnelId = tunnelIdFromName
 	c.ValidTunnelName = c.TunnelName
@@ -271,7 +294,7 @@ func (c *CloudflareAPI) getTunnelIdByName() (string, error) {
 
 	if err != nil {
 		c.Log.Error(err, "error listing tunnels by name", "tunnelName", c.TunnelName)
-		return "", err
+		return "", fmt.Errorf("error listing tunnels: %w", err)
 	}
 
 	switch len(tunnels) {
@@ -323,33 +346,54 @@ func (c *CloudflareAPI) GetZoneId() (string, error) {
 		return "", err
 	}
 
-	zoneIdFromName, err := c.getZoneIdByName()
+	zoneIdFromName, err := c.VerifyZone(c.Domain)
 	if err != nil {
-		return "", fmt.Errorf("error fetching Zone ID by Zone Name")
+		return "", fmt.Errorf("error fetching Zone ID by Zone Name %q: %w", c.Domain, err)
 	}
 	c.ValidZoneId = zoneIdFromName
 	return c.ValidZoneId, nil
 }
 
-func (c *CloudflareAPI) getZoneIdByName() (string, error) {
+// VerifyZone checks that the domain is a zone of the account, returning its ID.
+// Errors wrap errInvalidZone if the zone does not exist or belongs to another account, and errAmbiguousZone if the
+// credentials have the zone in several accounts.
+func (c *CloudflareAPI) VerifyZone(domain string) (string, error) {
 	ctx := context.Background()
-	zones, err := c.CloudflareClient.ListZones(ctx, c.Domain)
 
+	// Tokens scoped to several accounts list the zones of all of them, scope the lookup to the account of the tunnel
+	if c.ValidAccountId == "" && (c.AccountId != "" || c.AccountName != "") {
+		if _, err := c.GetAccountId(); err != nil {
+			return "", err
+		}
+	}
+	res, err := c.CloudflareClient.ListZonesContext(ctx, cloudflare.WithZoneFilters(domain, c.ValidAccountId, ""))
 	if err != nil {
-		c.Log.Error(err, "error listing zones, check domain", "domain", c.Domain)
-		return "", err
+		c.Log.Error(err, "error listing zones, check domain", "domain", domain)
+		return "", fmt.Errorf("error listing zones: %w", err)
+	}
+	zones := res.Result
+
+	// The account filter is not applied by every API version
+	if c.ValidAccountId != "" {
+		accountZones := zones[:0]
+		for _, zone := range zones {
+			if zone.Account.ID == "" || zone.Account.ID == c.ValidAccountId {
+				accountZones = append(accountZones, zone)
+			}
+		}
+		zones = accountZones
 	}
 
 	switch len(zones) {
 	case 0:
-		err := fmt.Errorf("no zone in response")
-		c.Log.Error(err, "found no zone, check domain", "domain", c.Domain, "zones", zones)
+		err := fmt.Errorf("no zone %q in account %q: %w", domain, c.ValidAccountId, errInvalidZone)
+		c.Log.Error(err, "found no zone, check domain", "domain", domain, "zones", zones)
 		return "", err
 	case 1:
 		return zones[0].ID, nil
 	default:
-		err := fmt.Errorf("more than one zone in response")
-		c.Log.Error(err, "found more than one zone, check domain", "domain", c.Domain)
+		err := fmt.Errorf("zone %q: %w, set tunnel.spec.cloudflare.accountId", domain, errAmbiguousZone)
+		c.Log.Error(err, "found more than one zone, check domain", "domain", domain)
 		return "", err
 	}
 }
@@ -359,8 +403,14 @@ func ptr[T any](v T) *T {
 	return &v
 }
 
+// DNSRecordOptions configures the DNS CNAME records pointing to the tunnel
+type DNSRecordOptions struct {
+	Proxied bool
+	TTL     int // 1 for automatic
+}
+
 // InsertOrUpdateCName upsert DNS CNAME record for the given FQDN to point to the tunnel
-func (c *CloudflareAPI) InsertOrUpdateCName(fqdn, dnsId string) (string, error) {
+func (c *CloudflareAPI) InsertOrUpdateCName(fqdn, dnsId string, options DNSRecordOptions) (string, error) {
 	ctx := context.Background()
 	rc := cloudflare.ZoneIdentifier(c.ValidZoneId)
 	if dnsId != "" {
@@ -369,15 +419,15 @@ func (c *CloudflareAPI) InsertOrUpdateCName(fqdn, dnsId string) (string, error)
 			ID:      dnsId,
 			Type:    "CNAME",
 			Name:    fqdn,
-			Content: fmt.Sprintf("%s.cfargotunnel.com", c.ValidTunnelId),
-			Comment: "Managed by cloudflare-operator",
-			TTL:     1,         // Automatic TTL
-			Proxied: ptr(true), // For Cloudflare tunnels
+			Content: c.TunnelCName(),
+			Comment: managedRecordComment,
+			TTL:     options.TTL,
+			Proxied: ptr(options.Proxied),
 		}
 		err := c.CloudflareClient.UpdateDNSRecord(ctx, rc, updateParams)
 		if err != nil {
 			c.Log.Error(err, "error code in setting/updating DNS record, check fqdn", "fqdn", fqdn)
-			return "", err
+			return "", fmt.Errorf("error updating DNS record %s for %s: %w", dnsId, fqdn, err)
 		}
 		c.Log.Info("DNS record updated successfully", "fqdn", fqdn)
 		return dnsId, nil
@@ -386,15 +436,15 @@ func (c *CloudflareAPI) InsertOrUpdateCName(fqdn, dnsId string) (string, error)
 		createParams := cloudflare.CreateDNSRecordParams{
 			Type:    "CNAME",
 			Name:    fqdn,
-			Content: fmt.Sprintf("%s.cfargotunnel.com", c.ValidTunnelId),
-			Comment: "Managed by cloudflare-operator",
-			TTL:     1,         // Automatic TTL
-			Proxied: ptr(true), // For Cloudflare tunnels
+			Content: c.TunnelCName(),
+			Comment: managedRecordComment,
+			TTL:     options.TTL,
+			Proxied: ptr(options.Proxied),
 		}
 		resp, err := c.CloudflareClient.CreateDNSRecord(ctx, rc, createParams)
 		if err != nil {
 			c.Log.Error(err, "error creating DNS record, check fqdn", "fqdn", fqdn)
-			return "", err
+			return "", fmt.Errorf("error creating DNS record for %s: %w", fqdn, err)
 		}
 		c.Log.Info("DNS record created successfully", "fqdn", fqdn)
 		return resp.Result.ID, nil
@@ -414,7 +464,7 @@ func (c *CloudflareAPI) DeleteDNSId(fqdn, dnsId string, created bool) error {
 
 	if err != nil {
 		c.Log.Error(err, "error deleting DNS record, check fqdn", "dnsId", dnsId, "fqdn", fqdn)
-		return err
+		return fmt.Errorf("error deleting DNS record %s for %s: %w", dnsId, fqdn, err)
 	}
 
 	return nil
@@ -422,9 +472,15 @@ func (c *CloudflareAPI) DeleteDNSId(fqdn, dnsId string, created bool) error {
 
 // GetDNSCNameId returns the ID of the CNAME record requested
 func (c *CloudflareAPI) GetDNSCNameId(fqdn string) (string, error) {
+	record, err := c.GetDNSCNameRecord(fqdn)
+	return record.ID, err
+}
+
+// GetDNSCNameRecord returns the CNAME record requested
+func (c *CloudflareAPI) GetDNSCNameRecord(fqdn string) (cloudflare.DNSRecord, error) {
 	if _, err := c.GetZoneId(); err != nil {
 		c.Log.Error(err, "error in getting Zone ID")
-		return "", err
+		return cloudflare.DNSRecord{}, err
 	}
 
 	ctx := context.Background()
@@ -436,23 +492,658 @@ func (c *CloudflareAPI) GetDNSCNameId(fqdn string) (string, error) {
 	records, _, err := c.CloudflareClient.ListDNSRecords(ctx, rc, params)
 	if err != nil {
 		c.Log.Error(err, "error listing DNS records, check fqdn", "fqdn", fqdn)
-		return "", err
+		return cloudflare.DNSRecord{}, fmt.Errorf("error listing CNAME records for %s: %w", fqdn, err)
 	}
 
 	switch len(records) {
 	case 0:
-		err := fmt.Errorf("no records returned")
+		err := fmt.Errorf("no records returned for %s", fqdn)
 		c.Log.Info("no records returned for fqdn", "fqdn", fqdn)
-		return "", err
+		return cloudflare.DNSRecord{}, err
 	case 1:
-		return records[0].ID, nil
+		return records[0], nil
 	default:
-		err := fmt.Errorf("multiple records returned")
+		err := fmt.Errorf("multiple records returned for %s", fqdn)
 		c.Log.Error(err, "multiple records returned for fqdn", "fqdn", fqdn)
-		return "", err
+		return cloudflare.DNSRecord{}, err
 	}
 }
 
+// TunnelCName returns the CNAME target that DNS records for the tunnel point to
+func (c *CloudflareAPI) TunnelCName() string {
+	return fmt.Sprintf("%s.cfargotunnel.com", c.ValidTunnelId)
+}
+
+// CNameInSync checks if the CNAME record points to the tunnel with the options
+func (c *CloudflareAPI) CNameInSync(record cloudflare.DNSRecord, options DNSRecordOptions) bool {
+	return record.Content == c.TunnelCName() && record.Proxied != nil && *record.Proxied == options.Proxied && record.TTL == options.TTL
+}
+
+// GetArgoSmartRouting returns whether Argo Smart Routing is enabled on the zone
+func (c *CloudflareAPI) GetArgoSmartRouting() (bool, error) {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return false, err
+	}
+
+	ctx := context.Background()
+	setting, err := c.CloudflareClient.ArgoSmartRouting(ctx, c.ValidZoneId)
+	if err != nil {
+		c.Log.Error(err, "error reading Argo Smart Routing setting, check token permissions", "domain", c.Domain)
+		return false, fmt.Errorf("error reading Argo Smart Routing setting of zone %s: %w", c.Domain, err)
+	}
+	return setting.Value == "on", nil
+}
+
+// SetArgoSmartRouting enables or disables Argo Smart Routing on the zone. Argo Smart Routing is billed per zone.
+func (c *CloudflareAPI) SetArgoSmartRouting(enabled bool) error {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return err
+	}
+
+	value := "off"
+	if enabled {
+		value = "on"
+	}
+	ctx := context.Background()
+	if _, err := c.CloudflareClient.UpdateArgoSmartRouting(ctx, c.ValidZoneId, value); err != nil {
+		c.Log.Error(err, "error updating Argo Smart Routing setting, check token permissions", "domain", c.Domain, "value", value)
+		return fmt.Errorf("error setting Argo Smart Routing of zone %s to %s: %w", c.Domain, value, err)
+	}
+	return nil
+}
+
+// ListManagedDnsRecords lists the CNAME and TXT records in the zone managed by the operator for this tunnel
+func (c *CloudflareAPI) ListManagedDnsRecords() ([]cloudflare.DNSRecord, error) {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rc := cloudflare.ZoneIdentifier(c.ValidZoneId)
+	records, _, err := c.CloudflareClient.ListDNSRecords(ctx, rc, cloudflare.ListDNSRecordsParams{Comment: managedRecordComment})
+	if err != nil {
+		c.Log.Error(err, "error listing managed DNS records", "domain", c.Domain)
+		return nil, fmt.Errorf("error listing managed DNS records of zone %s: %w", c.Domain, err)
+	}
+
+	// Other tunnels can manage records in the same zone
+	managed := []cloudflare.DNSRecord{}
+	for _, record := range records {
+		switch record.Type {
+		case "CNAME":
+			if record.Content == c.TunnelCName() {
+				managed = append(managed, record)
+			}
+		case "TXT":
+			var dnsTxtResponse DnsManagedRecordTxt
+			if err := json.Unmarshal([]byte(record.Content), &dnsTxtResponse); err == nil && dnsTxtResponse.TunnelId == c.ValidTunnelId {
+				managed = append(managed, record)
+			}
+		}
+	}
+	return managed, nil
+}
+
+// SRVRecordOptions are the options of an SRV record pointing to a hostname
+type SRVRecordOptions struct {
+	Service  string // Without the leading underscore
+	Proto    string // Without the leading underscore
+	Priority int
+	Weight   int
+	Port     int
+}
+
+// regionalHostname is a Data Localization regional hostname
+type regionalHostname struct {
+	Hostname  string `json:"hostname,omitempty"`
+	RegionKey string `json:"region_key"`
+}
+
+// SyncRegionalHostname sets the Data Localization region of the hostname, or removes it if regionKey is empty.
+// Errors wrap errNotEntitled if the account does not have Regional Services.
+func (c *CloudflareAPI) SyncRegionalHostname(hostname, regionKey string) error {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return err
+	}
+
+	// cloudflare-go does not support the regional hostnames API yet
+	ctx := context.Background()
+	endpoint := fmt.Sprintf("/zones/%s/addressing/regional_hostnames", c.ValidZoneId)
+	current := regionalHostname{}
+	if raw, err := c.CloudflareClient.Raw(ctx, http.MethodGet, endpoint+"/"+hostname, nil, nil); err == nil {
+		if err := json.Unmarshal(raw, &current); err != nil {
+			return fmt.Errorf("error parsing regional hostname %s: %w", hostname, err)
+		}
+	} else if !isNotFound(err) {
+		return regionalHostnameError(hostname, err)
+	}
+
+	var err error
+	switch {
+	case current.RegionKey == regionKey:
+		return nil
+	case regionKey == "":
+		c.Log.Info("Deleting regional hostname", "hostname", hostname, "regionKey", current.RegionKey)
+		_, err = c.CloudflareClient.Raw(ctx, http.MethodDelete, endpoint+"/"+hostname, nil, nil)
+	case current.RegionKey == "":
+		c.Log.Info("Creating regional hostname", "hostname", hostname, "regionKey", regionKey)
+		_, err = c.CloudflareClient.Raw(ctx, http.MethodPost, endpoint, regionalHostname{Hostname: hostname, RegionKey: regionKey}, nil)
+	default:
+		c.Log.Info("Updating regional hostname", "hostname", hostname, "regionKey", regionKey)
+		_, err = c.CloudflareClient.Raw(ctx, http.MethodPatch, endpoint+"/"+hostname, regionalHostname{RegionKey: regionKey}, nil)
+	}
+	if err != nil {
+		return regionalHostnameError(hostname, err)
+	}
+	return nil
+}
+
+// regionalHostnameError wraps the error of the regional hostnames API, with errNotEntitled if it is a permission error.
+// cloudflare-go returns the 403 of an account without the feature as a *AuthenticationError.
+func regionalHostnameError(hostname string, err error) error {
+	var forbidden *cloudflare.AuthenticationError
+	if errors.As(err, &forbidden) || strings.Contains(strings.ToLower(err.Error()), "not entitled") {
+		return fmt.Errorf("error setting regional hostname %s: %w: %s", hostname, errNotEntitled, err.Error())
+	}
+	return fmt.Errorf("error setting regional hostname %s: %w", hostname, err)
+}
+
+// CacheRuleOptions are the edge cache settings of a hostname
+type CacheRuleOptions struct {
+	Bypass     bool
+	EdgeTTL    uint // Seconds, the origin headers are respected if 0
+	BrowserTTL uint // Seconds, the origin headers are respected if 0
+}
+
+// certificateStatusActive is the status of a certificate pack serving HTTPS
+const certificateStatusActive = "active"
+
+// certificateStatusUncovered is reported for hostnames no certificate pack of the zone covers,
+// like a.b.example.com with only the universal certificate for example.com and *.example.com
+const certificateStatusUncovered = "uncovered"
+
+// certificatePagesSize is the number of certificate packs read per request
+const certificatePagesSize = 50
+
+// certificatePack is a certificate pack of the zone, cloudflare.CertificatePack does not have its status
+type certificatePack struct {
+	Hosts  []string `json:"hosts"`
+	Status string   `json:"status"`
+}
+
+// GetCertificateStatuses returns the status of the edge certificate of each hostname: active if an active certificate pack covers it,
+// else the status of a certificate pack covering it like pending_validation, or uncovered if none does.
+func (c *CloudflareAPI) GetCertificateStatuses(hostnames []string) (map[string]string, error) {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var packs []certificatePack
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("/zones/%s/ssl/certificate_packs?status=all&page=%d&per_page=%d", c.ValidZoneId, page, certificatePagesSize)
+		raw, err := c.CloudflareClient.Raw(ctx, http.MethodGet, endpoint, nil, nil)
+		if err != nil {
+			c.Log.Error(err, "error listing certificate packs")
+			return nil, fmt.Errorf("error listing certificate packs: %w", err)
+		}
+		var result []certificatePack
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("error parsing certificate packs: %w", err)
+		}
+		packs = append(packs, result...)
+		if len(result) < certificatePagesSize {
+			break
+		}
+	}
+
+	statuses := make(map[string]string, len(hostnames))
+	for _, hostname := range hostnames {
+		statuses[hostname] = certificateStatusUncovered
+		for _, pack := range packs {
+			if !certificateCovers(pack.Hosts, hostname) {
+				continue
+			}
+			if statuses[hostname] == certificateStatusUncovered || pack.Status == certificateStatusActive {
+				statuses[hostname] = pack.Status
+			}
+			if pack.Status == certificateStatusActive {
+				break
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// certificateCovers checks if one of the hosts of a certificate matches the hostname, a wildcard matching a single label
+func certificateCovers(hosts []string, hostname string) bool {
+	hostname = strings.ToLower(hostname)
+	_, parent, _ := strings.Cut(hostname, ".")
+	for _, host := range hosts {
+		host = strings.ToLower(host)
+		if host == hostname || (parent != "" && host == "*."+parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheRuleDescriptionPrefix identifies the cache rules managed by the operator, followed by their hostname
+const cacheRuleDescriptionPrefix = "Managed by cloudflare-operator for "
+
+// SyncCacheRule sets the cache rule of the hostname in the cache settings phase of the zone, or removes it if cache is nil.
+// The other rules of the phase are kept in place.
+func (c *CloudflareAPI) SyncCacheRule(hostname string, cache *CacheRuleOptions) error {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return err
+	}
+
+	ctx := context.Background()
+	phase := string(cloudflare.RulesetPhaseHTTPRequestCacheSettings)
+	ruleset, err := c.CloudflareClient.GetZoneRulesetPhase(ctx, c.ValidZoneId, phase)
+	// Zones without cache rules yet have no ruleset for the phase
+	if err != nil && !isNotFound(err) {
+		c.Log.Error(err, "error getting cache rules", "hostname", hostname)
+		return fmt.Errorf("error getting cache rules for %s: %w", hostname, err)
+	}
+
+	description := cacheRuleDescriptionPrefix + hostname
+	rules := make([]cloudflare.RulesetRule, 0, len(ruleset.Rules)+1)
+	current := -1
+	for _, rule := range ruleset.Rules {
+		if rule.Description == description {
+			current = len(rules)
+		}
+		// Read only fields are not accepted back
+		rule.Version, rule.LastUpdated = nil, nil
+		rules = append(rules, rule)
+	}
+
+	switch {
+	case cache == nil && current == -1:
+		return nil
+	case cache == nil:
+		c.Log.Info("Deleting cache rule", "hostname", hostname, "ruleId", rules[current].ID)
+		rules = append(rules[:current], rules[current+1:]...)
+	case current == -1:
+		c.Log.Info("Inserting cache rule", "hostname", hostname)
+		rules = append(rules, cacheRule(hostname, description, cache))
+	default:
+		desired := cacheRule(hostname, description, cache)
+		if cacheRuleInSync(rules[current], desired) {
+			return nil
+		}
+		c.Log.Info("Updating cache rule", "hostname", hostname, "ruleId", rules[current].ID)
+		desired.ID = rules[current].ID
+		rules[current] = desired
+	}
+
+	if _, err := c.CloudflareClient.UpdateZoneRulesetPhase(ctx, c.ValidZoneId, phase, cloudflare.Ruleset{Rules: rules}); err != nil {
+		c.Log.Error(err, "error updating cache rules", "hostname", hostname)
+		return fmt.Errorf("error updating cache rules for %s: %w", hostname, err)
+	}
+	return nil
+}
+
+// cacheRule returns the cache rule matching the requests to the hostname
+func cacheRule(hostname, description string, cache *CacheRuleOptions) cloudflare.RulesetRule {
+	enabled := true
+	cacheEligible := !cache.Bypass
+	params := &cloudflare.RulesetRuleActionParameters{Cache: &cacheEligible}
+	if cacheEligible && cache.EdgeTTL > 0 {
+		edgeTTL := cache.EdgeTTL
+		params.EdgeTTL = &cloudflare.RulesetRuleActionParametersEdgeTTL{Mode: "override_origin", Default: &edgeTTL}
+	}
+	if cacheEligible && cache.BrowserTTL > 0 {
+		browserTTL := cache.BrowserTTL
+		params.BrowserTTL = &cloudflare.RulesetRuleActionParametersBrowserTTL{Mode: "override_origin", Default: &browserTTL}
+	}
+	return cloudflare.RulesetRule{
+		Action:           "set_cache_settings",
+		ActionParameters: params,
+		Expression:       fmt.Sprintf("(http.host eq %q)", hostname),
+		Description:      description,
+		Enabled:          &enabled,
+	}
+}
+
+// cacheRuleInSync checks if the cache rule has the settings of the desired one
+func cacheRuleInSync(rule, desired cloudflare.RulesetRule) bool {
+	if rule.Action != desired.Action || rule.Expression != desired.Expression || (rule.Enabled != nil && !*rule.Enabled) || rule.ActionParameters == nil {
+		return false
+	}
+	current, want := rule.ActionParameters, desired.ActionParameters
+	ttl := func(mode string, value *uint) string {
+		if value == nil {
+			return mode
+		}
+		return fmt.Sprintf("%s/%d", mode, *value)
+	}
+	var currentEdge, wantEdge, currentBrowser, wantBrowser string
+	if current.EdgeTTL != nil {
+		currentEdge = ttl(current.EdgeTTL.Mode, current.EdgeTTL.Default)
+	}
+	if want.EdgeTTL != nil {
+		wantEdge = ttl(want.EdgeTTL.Mode, want.EdgeTTL.Default)
+	}
+	if current.BrowserTTL != nil {
+		currentBrowser = ttl(current.BrowserTTL.Mode, current.BrowserTTL.Default)
+	}
+	if want.BrowserTTL != nil {
+		wantBrowser = ttl(want.BrowserTTL.Mode, want.BrowserTTL.Default)
+	}
+	return current.Cache != nil && *current.Cache == *want.Cache && currentEdge == wantEdge && currentBrowser == wantBrowser
+}
+
+// loadBalancerDescriptionPrefix identifies the Load Balancers and pools managed by the operator, followed by the TunnelBinding owning them
+const loadBalancerDescriptionPrefix = "Managed by cloudflare-operator for "
+
+// LoadBalancerOrigin is a weighted origin of a Load Balancer, reaching the tunnel with the hostname of its ingress rule as Host header
+type LoadBalancerOrigin struct {
+	Hostname string
+	Weight   int // Percentage of the requests
+}
+
+// loadBalancerPoolName returns the name of the pool of the Load Balancer of the hostname, pool names not allowing dots
+func loadBalancerPoolName(hostname string) string {
+	return strings.ReplaceAll(hostname, ".", "-")
+}
+
+// ManagedLoadBalancers returns the hostnames of the Load Balancers of the zone owned by the TunnelBinding
+func (c *CloudflareAPI) ManagedLoadBalancers(owner string) ([]string, error) {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return nil, err
+	}
+	loadBalancers, err := c.CloudflareClient.ListLoadBalancers(context.Background(), cloudflare.ZoneIdentifier(c.ValidZoneId), cloudflare.ListLoadBalancerParams{})
+	if err != nil {
+		c.Log.Error(err, "error listing load balancers")
+		return nil, fmt.Errorf("error listing load balancers: %w", err)
+	}
+	var hostnames []string
+	for _, lb := range loadBalancers {
+		if lb.Description == loadBalancerDescriptionPrefix+owner {
+			hostnames = append(hostnames, lb.Name)
+		}
+	}
+	return hostnames, nil
+}
+
+// SyncLoadBalancer sets the Load Balancer of the hostname splitting its requests between the origins by weight, with a pool of the
+// same name, or removes both if there are no origins. Load Balancers and pools of the hostname not owned by the TunnelBinding are not touched.
+func (c *CloudflareAPI) SyncLoadBalancer(hostname, owner string, origins []LoadBalancerOrigin) error {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return err
+	}
+	if _, err := c.GetAccountId(); err != nil {
+		c.Log.Error(err, "error in getting account ID")
+		return err
+	}
+
+	ctx := context.Background()
+	zone, account := cloudflare.ZoneIdentifier(c.ValidZoneId), cloudflare.AccountIdentifier(c.ValidAccountId)
+	description := loadBalancerDescriptionPrefix + owner
+
+	loadBalancers, err := c.CloudflareClient.ListLoadBalancers(ctx, zone, cloudflare.ListLoadBalancerParams{})
+	if err != nil {
+		c.Log.Error(err, "error listing load balancers", "hostname", hostname)
+		return fmt.Errorf("error listing load balancers for %s: %w", hostname, err)
+	}
+	var current *cloudflare.LoadBalancer
+	for i := range loadBalancers {
+		if strings.EqualFold(loadBalancers[i].Name, hostname) {
+			current = &loadBalancers[i]
+		}
+	}
+	if current != nil && current.Description != description {
+		return fmt.Errorf("load balancer %s is not managed by %s", hostname, owner)
+	}
+
+	pools, err := c.CloudflareClient.ListLoadBalancerPools(ctx, account, cloudflare.ListLoadBalancerPoolParams{})
+	if err != nil {
+		c.Log.Error(err, "error listing load balancer pools", "hostname", hostname)
+		return fmt.Errorf("error listing load balancer pools for %s: %w", hostname, err)
+	}
+	var currentPool *cloudflare.LoadBalancerPool
+	for i := range pools {
+		if pools[i].Name == loadBalancerPoolName(hostname) {
+			currentPool = &pools[i]
+		}
+	}
+	if currentPool != nil && currentPool.Description != description {
+		return fmt.Errorf("load balancer pool %s is not managed by %s", currentPool.Name, owner)
+	}
+
+	// The pool is in use until its Load Balancer is deleted
+	if len(origins) == 0 {
+		if current != nil {
+			c.Log.Info("Deleting load balancer", "hostname", hostname, "loadBalancerId", current.ID)
+			if err := c.CloudflareClient.DeleteLoadBalancer(ctx, zone, current.ID); err != nil {
+				c.Log.Error(err, "error deleting load balancer", "hostname", hostname)
+				return fmt.Errorf("error deleting load balancer %s: %w", hostname, err)
+			}
+		}
+		if currentPool != nil {
+			c.Log.Info("Deleting load balancer pool", "hostname", hostname, "poolId", currentPool.ID)
+			if err := c.CloudflareClient.DeleteLoadBalancerPool(ctx, account, currentPool.ID); err != nil {
+				c.Log.Error(err, "error deleting load balancer pool", "hostname", hostname)
+				return fmt.Errorf("error deleting load balancer pool %s: %w", currentPool.Name, err)
+			}
+		}
+		return nil
+	}
+
+	pool := cloudflare.LoadBalancerPool{
+		Name:           loadBalancerPoolName(hostname),
+		Description:    description,
+		Enabled:        true,
+		OriginSteering: &cloudflare.LoadBalancerOriginSteering{Policy: "random"},
+	}
+	for _, origin := range origins {
+		pool.Origins = append(pool.Origins, cloudflare.LoadBalancerOrigin{
+			Name:    loadBalancerPoolName(origin.Hostname),
+			Address: c.TunnelCName(),
+			Enabled: true,
+			Weight:  float64(origin.Weight) / 100,
+			Header:  map[string][]string{"Host": {origin.Hostname}},
+		})
+	}
+	switch {
+	case currentPool == nil:
+		c.Log.Info("Creating load balancer pool", "hostname", hostname)
+		created, err := c.CloudflareClient.CreateLoadBalancerPool(ctx, account, cloudflare.CreateLoadBalancerPoolParams{LoadBalancerPool: pool})
+		if err != nil {
+			c.Log.Error(err, "error creating load balancer pool", "hostname", hostname)
+			return fmt.Errorf("error creating load balancer pool %s: %w", pool.Name, err)
+		}
+		pool.ID = created.ID
+	case !reflect.DeepEqual(currentPool.Origins, pool.Origins) || !currentPool.Enabled:
+		c.Log.Info("Updating load balancer pool", "hostname", hostname, "poolId", currentPool.ID)
+		pool.ID = currentPool.ID
+		if _, err := c.CloudflareClient.UpdateLoadBalancerPool(ctx, account, cloudflare.UpdateLoadBalancerPoolParams{LoadBalancer: pool}); err != nil {
+			c.Log.Error(err, "error updating load balancer pool", "hostname", hostname)
+			return fmt.Errorf("error updating load balancer pool %s: %w", pool.Name, err)
+		}
+	default:
+		pool.ID = currentPool.ID
+	}
+
+	loadBalancer := cloudflare.LoadBalancer{
+		Name:           hostname,
+		Description:    description,
+		FallbackPool:   pool.ID,
+		DefaultPools:   []string{pool.ID},
+		Proxied:        true,
+		SteeringPolicy: "off",
+	}
+	switch {
+	case current == nil:
+		c.Log.Info("Creating load balancer", "hostname", hostname)
+		if _, err := c.CloudflareClient.CreateLoadBalancer(ctx, zone, cloudflare.CreateLoadBalancerParams{LoadBalancer: loadBalancer}); err != nil {
+			c.Log.Error(err, "error creating load balancer", "hostname", hostname)
+			return fmt.Errorf("error creating load balancer %s: %w", hostname, err)
+		}
+	case current.FallbackPool != pool.ID || !reflect.DeepEqual(current.DefaultPools, loadBalancer.DefaultPools) || !current.Proxied:
+		c.Log.Info("Updating load balancer", "hostname", hostname, "loadBalancerId", current.ID)
+		loadBalancer.ID = current.ID
+		if _, err := c.CloudflareClient.UpdateLoadBalancer(ctx, zone, cloudflare.UpdateLoadBalancerParams{LoadBalancer: loadBalancer}); err != nil {
+			c.Log.Error(err, "error updating load balancer", "hostname", hostname)
+			return fmt.Errorf("error updating load balancer %s: %w", hostname, err)
+		}
+	}
+	return nil
+}
+
+// SyncSRV upserts the SRV record for the fqdn and deletes its other managed SRV records, or all of them if srv is nil
+func (c *CloudflareAPI) SyncSRV(fqdn string, srv *SRVRecordOptions) error {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return err
+	}
+
+	ctx := context.Background()
+	rc := cloudflare.ZoneIdentifier(c.ValidZoneId)
+	records, _, err := c.CloudflareClient.ListDNSRecords(ctx, rc, cloudflare.ListDNSRecordsParams{Type: "SRV", Comment: managedRecordComment})
+	if err != nil {
+		c.Log.Error(err, "error listing SRV records", "fqdn", fqdn)
+		return fmt.Errorf("error listing SRV records for %s: %w", fqdn, err)
+	}
+
+	// SRV records of the fqdn are named _service._proto.fqdn
+	var existing []cloudflare.DNSRecord
+	for _, record := range records {
+		labels := strings.SplitN(record.Name, ".", 3)
+		if len(labels) == 3 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_") && strings.EqualFold(labels[2], fqdn) {
+			existing = append(existing, record)
+		}
+	}
+
+	keepId := ""
+	if srv != nil {
+		name := fmt.Sprintf("_%s._%s.%s", srv.Service, srv.Proto, fqdn)
+		data := map[string]interface{}{"priority": srv.Priority, "weight": srv.Weight, "port": srv.Port, "target": fqdn}
+		for _, record := range existing {
+			if strings.EqualFold(record.Name, name) {
+				keepId = record.ID
+				if !srvInSync(record, data) {
+					c.Log.Info("Updating existing SRV record", "name", name, "dnsId", record.ID)
+					if err := c.CloudflareClient.UpdateDNSRecord(ctx, rc, cloudflare.UpdateDNSRecordParams{
+						ID:      record.ID,
+						Type:    "SRV",
+						Name:    name,
+						Data:    data,
+						Comment: managedRecordComment,
+						TTL:     1, // Automatic TTL
+					}); err != nil {
+						c.Log.Error(err, "error updating SRV record", "name", name)
+						return fmt.Errorf("error updating SRV record %s: %w", name, err)
+					}
+				}
+				break
+			}
+		}
+		if keepId == "" {
+			c.Log.Info("Inserting SRV record", "name", name)
+			resp, err := c.CloudflareClient.CreateDNSRecord(ctx, rc, cloudflare.CreateDNSRecordParams{
+				Type:    "SRV",
+				Name:    name,
+				Data:    data,
+				Comment: managedRecordComment,
+				TTL:     1, // Automatic TTL
+			})
+			if err != nil {
+				c.Log.Error(err, "error creating SRV record", "name", name)
+				return fmt.Errorf("error creating SRV record %s: %w", name, err)
+			}
+			keepId = resp.Result.ID
+		}
+	}
+
+	for _, record := range existing {
+		if record.ID == keepId {
+			continue
+		}
+		c.Log.Info("Deleting SRV record", "name", record.Name, "dnsId", record.ID)
+		if err := c.CloudflareClient.DeleteDNSRecord(ctx, rc, record.ID); err != nil {
+			c.Log.Error(err, "error deleting SRV record", "name", record.Name)
+			return fmt.Errorf("error deleting SRV record %s: %w", record.Name, err)
+		}
+	}
+	return nil
+}
+
+// srvInSync checks the SRV record has the expected data, numbers being decoded as float64 from the API
+func srvInSync(record cloudflare.DNSRecord, data map[string]interface{}) bool {
+	existing, ok := record.Data.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for key, value := range data {
+		if fmt.Sprint(existing[key]) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsApex checks if the fqdn is the apex of the tunnel domain
+func (c *CloudflareAPI) IsApex(fqdn string) bool {
+	return strings.EqualFold(strings.TrimSuffix(fqdn, "."), c.Domain)
+}
+
+// InZone checks the fqdn is the tunnel domain or a subdomain of it, so that its DNS records can be managed
+func (c *CloudflareAPI) InZone(fqdn string) bool {
+	fqdn = strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	domain := strings.ToLower(c.Domain)
+	return fqdn == domain || strings.HasSuffix(fqdn, "."+domain)
+}
+
+// ValidateApexCName checks that a CNAME record to the tunnel can be created at the zone apex using CNAME flattening
+func (c *CloudflareAPI) ValidateApexCName(fqdn string) error {
+	if _, err := c.GetZoneId(); err != nil {
+		c.Log.Error(err, "error in getting Zone ID")
+		return err
+	}
+
+	ctx := context.Background()
+	setting, err := c.CloudflareClient.ZoneSingleSetting(ctx, c.ValidZoneId, "cname_flattening")
+	if err != nil {
+		c.Log.Error(err, "error reading CNAME flattening setting, check token permissions", "domain", c.Domain)
+		return fmt.Errorf("error reading CNAME flattening setting of zone %s needed for apex %s: %w", c.Domain, fqdn, err)
+	}
+	if value, _ := setting.Value.(string); value != "flatten_at_root" && value != "flatten_all" {
+		err := fmt.Errorf("zone %s does not support CNAME flattening at the apex (cname_flattening: %v), cannot route %s", c.Domain, setting.Value, fqdn)
+		c.Log.Error(err, "CNAME flattening not supported", "domain", c.Domain)
+		return err
+	}
+
+	// A flattened CNAME cannot coexist with address records at the apex
+	rc := cloudflare.ZoneIdentifier(c.ValidZoneId)
+	for _, recordType := range []string{"A", "AAAA"} {
+		records, _, err := c.CloudflareClient.ListDNSRecords(ctx, rc, cloudflare.ListDNSRecordsParams{
+			Type: recordType,
+			Name: fqdn,
+		})
+		if err != nil {
+			c.Log.Error(err, "error listing DNS records, check fqdn", "fqdn", fqdn)
+			return fmt.Errorf("error listing %s records for %s: %w", recordType, fqdn, err)
+		}
+		if len(records) > 0 {
+			err := fmt.Errorf("apex %s has %d existing %s records conflicting with the CNAME to the tunnel", fqdn, len(records), recordType)
+			c.Log.Error(err, "conflicting records at apex", "fqdn", fqdn)
+			return err
+		}
+	}
+	return nil
+}
+
 // GetManagedDnsTxt gets the TXT record corresponding to the fqdn
 func (c *CloudflareAPI) GetManagedDnsTxt(fqdn string) (string, DnsManagedRecordTxt, bool, error) {
 	if _, err := c.GetZoneId(); err != nil {
@@ -469,7 +1160,7 @@ func (c *CloudflareAPI) GetManagedDnsTxt(fqdn string) (string, DnsManagedRecordT
 	records, _, err := c.CloudflareClient.ListDNSRecords(ctx, rc, params)
 	if err != nil {
 		c.Log.Error(err, "error listing DNS records, check fqdn", "fqdn", fqdn)
-		return "", DnsManagedRecordTxt{}, false, err
+		return "", DnsManagedRecordTxt{}, false, fmt.Errorf("error listing TXT records for %s: %w", fqdn, err)
 	}
 
 	switch len(records) {
@@ -481,13 +1172,13 @@ func (c *CloudflareAPI) GetManagedDnsTxt(fqdn string) (string, DnsManagedRecordT
 		if err := json.Unmarshal([]byte(records[0].Content), &dnsTxtResponse); err != nil {
 			// TXT record exists, but not in JSON
 			c.Log.Error(err, "could not read TXT content in getting zoneId, check domain", "domain", c.Domain)
-			return records[0].ID, dnsTxtResponse, false, err
+			return records[0].ID, dnsTxtResponse, false, fmt.Errorf("error reading TXT record content for %s: %w", fqdn, err)
 		} else if dnsTxtResponse.TunnelId == c.ValidTunnelId {
 			// TXT record exists and controlled by our tunnel
 			return records[0].ID, dnsTxtResponse, true, nil
 		}
 	default:
-		err := fmt.Errorf("multiple records returned")
+		err := fmt.Errorf("multiple TXT records returned for %s", fqdn)
 		c.Log.Error(err, "multiple TXT records returned for fqdn", "fqdn", fqdn)
 		return "", DnsManagedRecordTxt{}, false, err
 	}
@@ -503,7 +1194,7 @@ func (c *CloudflareAPI) InsertOrUpdateTXT(fqdn, txtId, dnsId string) error {
 	})
 	if err != nil {
 		c.Log.Error(err, "error marhsalling txt record json", "fqdn", fqdn)
-		return err
+		return fmt.Errorf("error marshalling TXT record for %s: %w", fqdn, err)
 	}
 	ctx := context.Background()
 	rc := cloudflare.ZoneIdentifier(c.ValidZoneId)
@@ -516,14 +1207,14 @@ func (c *CloudflareAPI) InsertOrUpdateTXT(fqdn, txtId, dnsId string) error {
 			Type:    "TXT",
 			Name:    fmt.Sprintf("%s%s", TXT_PREFIX, fqdn),
 			Content: string(content),
-			Comment: "Managed by cloudflare-operator",
+			Comment: managedRecordComment,
 			TTL:     1,          // Automatic TTL
 			Proxied: ptr(false), // TXT cannot be proxied
 		}
 		err := c.CloudflareClient.UpdateDNSRecord(ctx, rc, updateParams)
 		if err != nil {
 			c.Log.Error(err, "error in updating DNS record, check fqdn", "fqdn", fqdn)
-			return err
+			return fmt.Errorf("error updating TXT record %s for %s: %w", txtId, fqdn, err)
 		}
 		c.Log.Info("DNS record updated successfully", "fqdn", fqdn)
 		return nil
@@ -533,16 +1224,183 @@ func (c *CloudflareAPI) InsertOrUpdateTXT(fqdn, txtId, dnsId string) error {
 			Type:    "TXT",
 			Name:    fmt.Sprintf("%s%s", TXT_PREFIX, fqdn),
 			Content: string(content),
-			Comment: "Managed by cloudflare-operator",
+			Comment: managedRecordComment,
 			TTL:     1,          // Automatic TTL
 			Proxied: ptr(false), // For Cloudflare tunnels
 		}
 		_, err := c.CloudflareClient.CreateDNSRecord(ctx, rc, createParams)
 		if err != nil {
 			c.Log.Error(err, "error creating DNS record, check fqdn", "fqdn", fqdn)
-			return err
+			return fmt.Errorf("error creating TXT record for %s: %w", fqdn, err)
 		}
 		c.Log.Info("DNS TXT record created successfully", "fqdn", fqdn)
 		return nil
 	}
 }
+
+// dnsBatchSize is the maximum number of records changed by a single batch request, the lowest limit across plans
+const dnsBatchSize = 200
+
+// CNameUpsert is a CNAME record pointing to the tunnel, updated if DnsId is set and created otherwise,
+// along with its TXT ownership record, updated if TxtId is set
+type CNameUpsert struct {
+	FQDN    string
+	DnsId   string
+	TxtId   string
+	Options DNSRecordOptions
+}
+
+// dnsBatchRecord is a record of a batch request, only the ID is set for deletions
+type dnsBatchRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	TTL     int    `json:"ttl,omitempty"`
+	Proxied *bool  `json:"proxied,omitempty"`
+}
+
+// dnsBatch is the body of a batch request, whose changes are applied in a single transaction
+type dnsBatch struct {
+	Deletes []dnsBatchRecord `json:"deletes,omitempty"`
+	Patches []dnsBatchRecord `json:"patches,omitempty"`
+	Posts   []dnsBatchRecord `json:"posts,omitempty"`
+}
+
+// dnsBatchResult is the result of a batch request, records being in the order of the request
+type dnsBatchResult struct {
+	Patches []cloudflare.DNSRecord `json:"patches"`
+	Posts   []cloudflare.DNSRecord `json:"posts"`
+}
+
+// add adds the record to the patches if it has an ID, else to the posts
+func (b *dnsBatch) add(record dnsBatchRecord) {
+	if record.ID != "" {
+		b.Patches = append(b.Patches, record)
+	} else {
+		b.Posts = append(b.Posts, record)
+	}
+}
+
+// InsertOrUpdateCNames upserts the CNAME records and their TXT records with batch requests, in chunks of dnsBatchSize records:
+// one for the CNAME records, then one for the TXT records naming them. If the TXT records fail, the CNAME records created by the
+// chunk are deleted. It falls back to a request per record if the batch endpoint is not available.
+func (c *CloudflareAPI) InsertOrUpdateCNames(records []CNameUpsert) error {
+	// A single record does not gain anything from the batch endpoint
+	if len(records) == 1 {
+		return c.insertOrUpdateCNameAndTXT(records[0])
+	}
+	for start := 0; start < len(records); start += dnsBatchSize {
+		end := start + dnsBatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		err := c.batchInsertOrUpdateCNames(records[start:end])
+		if isNotFound(err) {
+			c.Log.Info("DNS batch endpoint not available, upserting records one by one")
+			for _, record := range records[start:] {
+				if err := c.insertOrUpdateCNameAndTXT(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertOrUpdateCNameAndTXT upserts the CNAME record and its TXT record, deleting the CNAME record if the TXT record fails
+func (c *CloudflareAPI) insertOrUpdateCNameAndTXT(record CNameUpsert) error {
+	newDnsId, err := c.InsertOrUpdateCName(record.FQDN, record.DnsId, record.Options)
+	if err != nil {
+		return err
+	}
+	if err := c.InsertOrUpdateTXT(record.FQDN, record.TxtId, newDnsId); err != nil {
+		if err := c.DeleteDNSId(record.FQDN, newDnsId, record.DnsId != ""); err != nil {
+			c.Log.Info("Failed to delete DNS entry, left in broken state", "fqdn", record.FQDN)
+			return fmt.Errorf("failed to delete DNS entry for %s, left in broken state: %w", record.FQDN, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// batchInsertOrUpdateCNames upserts the records with a batch request for the CNAME records and one for the TXT records
+func (c *CloudflareAPI) batchInsertOrUpdateCNames(records []CNameUpsert) error {
+	cnames := dnsBatch{}
+	for _, record := range records {
+		cnames.add(dnsBatchRecord{
+			ID:      record.DnsId,
+			Type:    "CNAME",
+			Name:    record.FQDN,
+			Content: c.TunnelCName(),
+			Comment: managedRecordComment,
+			TTL:     record.Options.TTL,
+			Proxied: ptr(record.Options.Proxied),
+		})
+	}
+	c.Log.Info("Upserting DNS records in a batch", "updates", len(cnames.Patches), "inserts", len(cnames.Posts))
+	result, err := c.batchDNSRecords(cnames)
+	if err != nil {
+		return fmt.Errorf("error upserting DNS records: %w", err)
+	}
+	if len(result.Posts) != len(cnames.Posts) {
+		return fmt.Errorf("error upserting DNS records: %d records created instead of %d", len(result.Posts), len(cnames.Posts))
+	}
+
+	txts := dnsBatch{}
+	created := dnsBatch{}
+	for _, record := range records {
+		dnsId := record.DnsId
+		if dnsId == "" {
+			dnsId = result.Posts[len(created.Deletes)].ID
+			created.Deletes = append(created.Deletes, dnsBatchRecord{ID: dnsId})
+		}
+		content, err := json.Marshal(DnsManagedRecordTxt{
+			DnsId:      dnsId,
+			TunnelId:   c.ValidTunnelId,
+			TunnelName: c.ValidTunnelName,
+		})
+		if err != nil {
+			return fmt.Errorf("error marshalling TXT record for %s: %w", record.FQDN, err)
+		}
+		txts.add(dnsBatchRecord{
+			ID:      record.TxtId,
+			Type:    "TXT",
+			Name:    fmt.Sprintf("%s%s", TXT_PREFIX, record.FQDN),
+			Content: string(content),
+			Comment: managedRecordComment,
+			TTL:     1,          // Automatic TTL
+			Proxied: ptr(false), // TXT cannot be proxied
+		})
+	}
+	if _, err := c.batchDNSRecords(txts); err != nil {
+		// Do not leave records pointing to the tunnel without an ownership record
+		if len(created.Deletes) > 0 {
+			if _, err := c.batchDNSRecords(created); err != nil {
+				c.Log.Info("Failed to delete DNS entries, left in broken state", "count", len(created.Deletes))
+				return fmt.Errorf("failed to delete DNS entries, left in broken state: %w", err)
+			}
+		}
+		return fmt.Errorf("error upserting TXT records: %w", err)
+	}
+	c.Log.Info("DNS records upserted successfully", "count", len(records))
+	return nil
+}
+
+// batchDNSRecords applies the changes of the batch in a single transaction, cloudflare-go does not support it yet
+func (c *CloudflareAPI) batchDNSRecords(batch dnsBatch) (dnsBatchResult, error) {
+	result := dnsBatchResult{}
+	ctx := context.Background()
+	raw, err := c.CloudflareClient.Raw(ctx, http.MethodPost, fmt.Sprintf("/zones/%s/dns_records/batch", c.ValidZoneId), batch, nil)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return result, fmt.Errorf("error parsing DNS batch result: %w", err)
+	}
+	return result, nil
+}
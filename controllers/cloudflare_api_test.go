@@ -0,0 +1,522 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeCloudflare is a mocked Cloudflare API recording the requests it gets
+type fakeCloudflare struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   map[string][]string
+	// responses by "METHOD path", the status code and the result or the error message
+	responses map[string]fakeResponse
+	// fallback handles the requests without a response
+	fallback func(w http.ResponseWriter, r *http.Request, body []byte)
+}
+
+type fakeResponse struct {
+	status int
+	result interface{}
+}
+
+func newFakeCloudflare(t *testing.T) (*fakeCloudflare, *CloudflareAPI) {
+	f := &fakeCloudflare{bodies: map[string][]string{}, responses: map[string]fakeResponse{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	client, err := cloudflare.NewWithAPIToken("token", cloudflare.BaseURL(server.URL), cloudflare.UsingRateLimit(1000), cloudflare.UsingRetryPolicy(0, 0, 0))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return f, &CloudflareAPI{
+		Log:              logr.Discard(),
+		Domain:           "example.com",
+		ValidAccountId:   "account",
+		ValidTunnelId:    "tunnel-id",
+		ValidTunnelName:  "tunnel",
+		ValidZoneId:      "zone",
+		CloudflareClient: client,
+	}
+}
+
+func (f *fakeCloudflare) respond(method, path string, status int, result interface{}) {
+	f.responses[method+" "+path] = fakeResponse{status: status, result: result}
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	key := r.Method + " " + r.URL.Path
+	f.mu.Lock()
+	f.requests = append(f.requests, key)
+	f.bodies[key] = append(f.bodies[key], string(body))
+	response, ok := f.responses[key]
+	f.mu.Unlock()
+
+	if !ok {
+		if f.fallback != nil {
+			f.fallback(w, r, body)
+			return
+		}
+		response = fakeResponse{status: http.StatusNotFound, result: "no route"}
+	}
+	writeFakeResponse(w, response.status, response.result)
+}
+
+// writeFakeResponse writes the result, or the error message for an error status, in the Cloudflare API envelope
+func writeFakeResponse(w http.ResponseWriter, status int, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if status >= http.StatusBadRequest {
+		fmt.Fprintf(w, `{"success":false,"errors":[{"code":%d,"message":%q}],"messages":[],"result":null}`, status, fmt.Sprint(result))
+		return
+	}
+	raw, _ := json.Marshal(result)
+	// A single page, paginated lists would be fetched forever without one
+	fmt.Fprintf(w, `{"success":true,"errors":[],"messages":[],"result":%s,"result_info":{"page":1,"total_pages":1}}`, raw)
+}
+
+func (f *fakeCloudflare) count(method, path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, request := range f.requests {
+		if request == method+" "+path {
+			n++
+		}
+	}
+	return n
+}
+
+// newFakeClient returns a fake Kubernetes client with the core and operator types and the objects
+func newFakeClient(t *testing.T, objects ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := networkingv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build()
+}
+
+// respondZones lists the zones of the name filter of the request
+func (f *fakeCloudflare) respondZones(zones map[string][]cloudflare.Zone) {
+	f.fallback = func(w http.ResponseWriter, r *http.Request, _ []byte) {
+		if r.Method != http.MethodGet || r.URL.Path != "/zones" {
+			writeFakeResponse(w, http.StatusNotFound, "no route")
+			return
+		}
+		result := zones[r.URL.Query().Get("name")]
+		if result == nil {
+			result = []cloudflare.Zone{}
+		}
+		writeFakeResponse(w, http.StatusOK, result)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	f, api := newFakeCloudflare(t)
+	f.respond(http.MethodGet, "/missing", http.StatusNotFound, "not found")
+	f.respond(http.MethodGet, "/forbidden", http.StatusForbidden, "forbidden")
+
+	_, err := api.CloudflareClient.Raw(context.Background(), http.MethodGet, "/missing", nil, nil)
+	if !isNotFound(err) || !isNotFound(fmt.Errorf("wrapped: %w", err)) {
+		t.Errorf("isNotFound(%v) = false, want true", err)
+	}
+	_, err = api.CloudflareClient.Raw(context.Background(), http.MethodGet, "/forbidden", nil, nil)
+	if isNotFound(err) {
+		t.Errorf("isNotFound(%v) = true, want false", err)
+	}
+}
+
+func TestInsertOrUpdateCNames(t *testing.T) {
+	records := []CNameUpsert{
+		{FQDN: "new.example.com", Options: DNSRecordOptions{Proxied: true, TTL: 1}},
+		{FQDN: "old.example.com", DnsId: "old-id", TxtId: "old-txt", Options: DNSRecordOptions{Proxied: true, TTL: 1}},
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		f, api := newFakeCloudflare(t)
+		f.fallback = func(w http.ResponseWriter, r *http.Request, body []byte) {
+			batch := dnsBatch{}
+			if err := json.Unmarshal(body, &batch); err != nil {
+				t.Errorf("invalid batch body: %v", err)
+			}
+			result := dnsBatchResult{}
+			for _, record := range batch.Patches {
+				result.Patches = append(result.Patches, cloudflare.DNSRecord{ID: record.ID, Name: record.Name})
+			}
+			for _, record := range batch.Posts {
+				result.Posts = append(result.Posts, cloudflare.DNSRecord{ID: record.Name + "-id", Name: record.Name})
+			}
+			writeFakeResponse(w, http.StatusOK, result)
+		}
+		if err := api.InsertOrUpdateCNames(records); err != nil {
+			t.Fatalf("InsertOrUpdateCNames() error = %v", err)
+		}
+		if n := f.count(http.MethodPost, "/zones/zone/dns_records/batch"); n != 2 {
+			t.Fatalf("got %d batch requests, want 2", n)
+		}
+		txts := dnsBatch{}
+		if err := json.Unmarshal([]byte(f.bodies["POST /zones/zone/dns_records/batch"][1]), &txts); err != nil {
+			t.Fatalf("invalid TXT batch body: %v", err)
+		}
+		if len(txts.Posts) != 1 || txts.Posts[0].Name != "_managed.new.example.com" || !strings.Contains(txts.Posts[0].Content, `"DnsId":"new.example.com-id"`) {
+			t.Errorf("TXT posts = %+v, want the TXT record of new.example.com naming its created record", txts.Posts)
+		}
+		if len(txts.Patches) != 1 || txts.Patches[0].ID != "old-txt" || !strings.Contains(txts.Patches[0].Content, `"DnsId":"old-id"`) {
+			t.Errorf("TXT patches = %+v, want the TXT record of old.example.com", txts.Patches)
+		}
+	})
+
+	t.Run("TXT failure deletes the created records", func(t *testing.T) {
+		f, api := newFakeCloudflare(t)
+		calls := 0
+		f.fallback = func(w http.ResponseWriter, r *http.Request, body []byte) {
+			calls++
+			switch calls {
+			case 1:
+				writeFakeResponse(w, http.StatusOK, dnsBatchResult{
+					Patches: []cloudflare.DNSRecord{{ID: "old-id"}},
+					Posts:   []cloudflare.DNSRecord{{ID: "new-id"}},
+				})
+			case 2:
+				writeFakeResponse(w, http.StatusBadRequest, "invalid TXT")
+			default:
+				batch := dnsBatch{}
+				_ = json.Unmarshal(body, &batch)
+				if len(batch.Deletes) != 1 || batch.Deletes[0].ID != "new-id" || len(batch.Posts)+len(batch.Patches) != 0 {
+					t.Errorf("cleanup batch = %s, want the deletion of new-id only", body)
+				}
+				writeFakeResponse(w, http.StatusOK, dnsBatchResult{})
+			}
+		}
+		if err := api.InsertOrUpdateCNames(records); err == nil {
+			t.Fatal("InsertOrUpdateCNames() error = nil, want the TXT error")
+		}
+		if calls != 3 {
+			t.Errorf("got %d batch requests, want 3", calls)
+		}
+	})
+
+	t.Run("falls back without batch endpoint", func(t *testing.T) {
+		f, api := newFakeCloudflare(t)
+		f.respond(http.MethodPost, "/zones/zone/dns_records/batch", http.StatusNotFound, "no route")
+		f.respond(http.MethodPost, "/zones/zone/dns_records", http.StatusOK, cloudflare.DNSRecord{ID: "created-id"})
+		f.respond(http.MethodPatch, "/zones/zone/dns_records/old-id", http.StatusOK, cloudflare.DNSRecord{ID: "old-id"})
+		f.respond(http.MethodPatch, "/zones/zone/dns_records/old-txt", http.StatusOK, cloudflare.DNSRecord{ID: "old-txt"})
+		if err := api.InsertOrUpdateCNames(records); err != nil {
+			t.Fatalf("InsertOrUpdateCNames() error = %v", err)
+		}
+		// The CNAME and the TXT record of new.example.com
+		if n := f.count(http.MethodPost, "/zones/zone/dns_records"); n != 2 {
+			t.Errorf("got %d record creations, want 2", n)
+		}
+		if n := f.count(http.MethodPatch, "/zones/zone/dns_records/old-id") + f.count(http.MethodPatch, "/zones/zone/dns_records/old-txt"); n != 2 {
+			t.Errorf("got %d record updates, want 2", n)
+		}
+	})
+}
+
+func TestSyncRegionalHostname(t *testing.T) {
+	const endpoint = "/zones/zone/addressing/regional_hostnames"
+	tests := []struct {
+		name        string
+		current     *regionalHostname // nil for a hostname without region
+		getStatus   int
+		regionKey   string
+		want        string // "METHOD path" of the change, empty for none
+		status      int    // Status of the change
+		wantErr     bool
+		notEntitled bool
+	}{
+		{name: "create", regionKey: "eu", want: "POST " + endpoint, status: http.StatusOK},
+		{name: "update", current: &regionalHostname{Hostname: "app.example.com", RegionKey: "us"}, regionKey: "eu", want: "PATCH " + endpoint + "/app.example.com", status: http.StatusOK},
+		{name: "delete", current: &regionalHostname{Hostname: "app.example.com", RegionKey: "us"}, want: "DELETE " + endpoint + "/app.example.com", status: http.StatusOK},
+		{name: "unchanged", current: &regionalHostname{Hostname: "app.example.com", RegionKey: "eu"}, regionKey: "eu"},
+		{name: "nothing to delete", regionKey: ""},
+		{name: "not entitled", getStatus: http.StatusForbidden, regionKey: "eu", wantErr: true, notEntitled: true},
+		{name: "not entitled on create", regionKey: "eu", want: "POST " + endpoint, status: http.StatusForbidden, wantErr: true, notEntitled: true},
+		{name: "other error", regionKey: "eu", want: "POST " + endpoint, status: http.StatusBadRequest, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, api := newFakeCloudflare(t)
+			switch {
+			case tt.getStatus != 0:
+				f.respond(http.MethodGet, endpoint+"/app.example.com", tt.getStatus, "forbidden")
+			case tt.current != nil:
+				f.respond(http.MethodGet, endpoint+"/app.example.com", http.StatusOK, tt.current)
+			default:
+				f.respond(http.MethodGet, endpoint+"/app.example.com", http.StatusNotFound, "not found")
+			}
+			if tt.want != "" {
+				method, path, _ := strings.Cut(tt.want, " ")
+				f.respond(method, path, tt.status, regionalHostname{Hostname: "app.example.com", RegionKey: tt.regionKey})
+			}
+
+			err := api.SyncRegionalHostname("app.example.com", tt.regionKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SyncRegionalHostname() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, errNotEntitled) != tt.notEntitled {
+				t.Errorf("SyncRegionalHostname() error = %v, want errNotEntitled %v", err, tt.notEntitled)
+			}
+			var changes []string
+			for _, request := range f.requests {
+				if !strings.HasPrefix(request, "GET ") {
+					changes = append(changes, request)
+				}
+			}
+			if tt.want == "" && len(changes) > 0 || tt.want != "" && (len(changes) != 1 || changes[0] != tt.want) {
+				t.Errorf("changes = %v, want %q", changes, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncCacheRule(t *testing.T) {
+	const endpoint = "/zones/zone/rulesets/phases/http_request_cache_settings/entrypoint"
+	other := cloudflare.RulesetRule{ID: "other", Action: "set_cache_settings", Expression: `(http.host eq "other.example.com")`, Description: "Managed elsewhere"}
+	current := cacheRule("app.example.com", cacheRuleDescriptionPrefix+"app.example.com", &CacheRuleOptions{EdgeTTL: 60})
+	current.ID = "current"
+
+	tests := []struct {
+		name     string
+		existing []cloudflare.RulesetRule // nil for a zone without the ruleset
+		cache    *CacheRuleOptions
+		// IDs of the rules written, nil if the ruleset is not updated
+		want        []string
+		wantEdgeTTL uint // Edge TTL of the rule of the hostname
+	}{
+		{name: "create the ruleset", cache: &CacheRuleOptions{EdgeTTL: 60}, want: []string{""}, wantEdgeTTL: 60},
+		{name: "insert", existing: []cloudflare.RulesetRule{other}, cache: &CacheRuleOptions{EdgeTTL: 60}, want: []string{"other", ""}, wantEdgeTTL: 60},
+		{name: "update", existing: []cloudflare.RulesetRule{current, other}, cache: &CacheRuleOptions{EdgeTTL: 120}, want: []string{"current", "other"}, wantEdgeTTL: 120},
+		{name: "unchanged", existing: []cloudflare.RulesetRule{current, other}, cache: &CacheRuleOptions{EdgeTTL: 60}},
+		{name: "delete", existing: []cloudflare.RulesetRule{other, current}, want: []string{"other"}},
+		{name: "nothing to delete", existing: []cloudflare.RulesetRule{other}},
+		{name: "nothing to delete without ruleset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, api := newFakeCloudflare(t)
+			if tt.existing == nil {
+				f.respond(http.MethodGet, endpoint, http.StatusNotFound, "could not find entrypoint ruleset")
+			} else {
+				f.respond(http.MethodGet, endpoint, http.StatusOK, cloudflare.Ruleset{ID: "ruleset", Rules: tt.existing})
+			}
+			f.respond(http.MethodPut, endpoint, http.StatusOK, cloudflare.Ruleset{ID: "ruleset"})
+
+			if err := api.SyncCacheRule("app.example.com", tt.cache); err != nil {
+				t.Fatalf("SyncCacheRule() error = %v", err)
+			}
+			puts := f.bodies["PUT "+endpoint]
+			if tt.want == nil {
+				if len(puts) != 0 {
+					t.Errorf("ruleset updated with %s, want no update", puts[0])
+				}
+				return
+			}
+			if len(puts) != 1 {
+				t.Fatalf("got %d ruleset updates, want 1", len(puts))
+			}
+			written := cloudflare.Ruleset{}
+			if err := json.Unmarshal([]byte(puts[0]), &written); err != nil {
+				t.Fatalf("invalid ruleset body: %v", err)
+			}
+			var ids []string
+			for _, rule := range written.Rules {
+				ids = append(ids, rule.ID)
+				if rule.Description != cacheRuleDescriptionPrefix+"app.example.com" {
+					continue
+				}
+				if rule.ActionParameters == nil || rule.ActionParameters.EdgeTTL == nil || *rule.ActionParameters.EdgeTTL.Default != tt.wantEdgeTTL {
+					t.Errorf("rule of the hostname = %+v, want an edge TTL of %d", rule.ActionParameters, tt.wantEdgeTTL)
+				}
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("written rules = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateTunnelStatusZone(t *testing.T) {
+	zone := func(id, account string) cloudflare.Zone {
+		z := cloudflare.Zone{ID: id, Name: "example.com"}
+		z.Account.ID = account
+		return z
+	}
+	tests := []struct {
+		name       string
+		zones      []cloudflare.Zone
+		wantZoneId string
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{name: "valid zone", zones: []cloudflare.Zone{zone("zone-id", "account")}, wantZoneId: "zone-id", wantStatus: metav1.ConditionTrue, wantReason: "ZoneFound"},
+		{name: "zone of another account", zones: []cloudflare.Zone{zone("zone-id", "other")}, wantStatus: metav1.ConditionFalse, wantReason: "ZoneNotFound"},
+		{name: "no zone", wantStatus: metav1.ConditionFalse, wantReason: "ZoneNotFound"},
+		{name: "zone in several accounts", zones: []cloudflare.Zone{zone("zone-a", ""), zone("zone-b", "")}, wantStatus: metav1.ConditionFalse, wantReason: "ZoneInSeveralAccounts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, api := newFakeCloudflare(t)
+			api.ValidZoneId = ""
+			f.respondZones(map[string][]cloudflare.Zone{"example.com": tt.zones})
+
+			tunnel := &networkingv1alpha1.Tunnel{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tunnel"}}
+			tunnel.Spec.Cloudflare.Domain = "example.com"
+			r := &TunnelReconciler{
+				Client:   newFakeClient(t, tunnel),
+				Recorder: record.NewFakeRecorder(10),
+				ctx:      context.Background(),
+				log:      logr.Discard(),
+				tunnel:   TunnelAdapter{tunnel},
+				cfAPI:    api,
+			}
+			// The tunnel is still set up on an invalid zone, only DNS records are skipped
+			if err := updateTunnelStatus(r); err != nil {
+				t.Fatalf("updateTunnelStatus() error = %v", err)
+			}
+
+			saved := &networkingv1alpha1.Tunnel{}
+			if err := r.Get(context.Background(), apitypes.NamespacedName{Namespace: "default", Name: "tunnel"}, saved); err != nil {
+				t.Fatal(err)
+			}
+			if saved.Status.TunnelId != "tunnel-id" || saved.Status.AccountId != "account" || saved.Status.ZoneId != tt.wantZoneId {
+				t.Errorf("status = %+v, want the tunnel and account IDs with zone ID %q", saved.Status, tt.wantZoneId)
+			}
+			condition := meta.FindStatusCondition(saved.Status.Conditions, conditionZoneValid)
+			if condition == nil || condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("ZoneValid condition = %+v, want %s %s", condition, tt.wantStatus, tt.wantReason)
+			}
+			if !meta.IsStatusConditionTrue(saved.Status.Conditions, conditionAuthenticated) {
+				t.Errorf("Authenticated condition is not true, a zone error is not a credentials error")
+			}
+		})
+	}
+}
+
+func TestVerifyZoneMultiAccountToken(t *testing.T) {
+	// The token has the zone in two accounts
+	zones := []cloudflare.Zone{{ID: "zone-a", Name: "example.com"}, {ID: "zone-b", Name: "example.com"}}
+	zones[0].Account.ID, zones[1].Account.ID = "account-a", "account-b"
+	tests := []struct {
+		name        string
+		accountId   string
+		accountName string
+		wantZoneId  string
+		wantErr     error
+	}{
+		{name: "explicit accountId", accountId: "account-b", wantZoneId: "zone-b"},
+		{name: "accountName of one account", accountName: "Team A", wantZoneId: "zone-a"},
+		{name: "no account", wantErr: errAmbiguousZone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, api := newFakeCloudflare(t)
+			api.ValidAccountId, api.ValidZoneId = "", ""
+			api.AccountId, api.AccountName = tt.accountId, tt.accountName
+			var accountFilters []string
+			f.fallback = func(w http.ResponseWriter, r *http.Request, _ []byte) {
+				switch {
+				case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/accounts/"):
+					writeFakeResponse(w, http.StatusOK, cloudflare.Account{ID: strings.TrimPrefix(r.URL.Path, "/accounts/")})
+				case r.Method == http.MethodGet && r.URL.Path == "/accounts":
+					writeFakeResponse(w, http.StatusOK, []cloudflare.Account{{ID: "account-a", Name: r.URL.Query().Get("name")}})
+				case r.Method == http.MethodGet && r.URL.Path == "/zones":
+					account := r.URL.Query().Get("account.id")
+					accountFilters = append(accountFilters, account)
+					result := []cloudflare.Zone{}
+					for _, zone := range zones {
+						if account == "" || zone.Account.ID == account {
+							result = append(result, zone)
+						}
+					}
+					writeFakeResponse(w, http.StatusOK, result)
+				default:
+					writeFakeResponse(w, http.StatusNotFound, "no route")
+				}
+			}
+
+			zoneId, err := api.VerifyZone("example.com")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyZone() error = %v, want %v", err, tt.wantErr)
+			}
+			if zoneId != tt.wantZoneId {
+				t.Errorf("VerifyZone() = %q, want %q", zoneId, tt.wantZoneId)
+			}
+			if tt.wantZoneId != "" && (len(accountFilters) != 1 || accountFilters[0] != api.ValidAccountId) {
+				t.Errorf("zones listed with account filters %v, want the account %s", accountFilters, api.ValidAccountId)
+			}
+		})
+	}
+}
+
+func TestVerifyZoneDomains(t *testing.T) {
+	f, api := newFakeCloudflare(t)
+	zone := cloudflare.Zone{ID: "zone-id", Name: "example.com"}
+	zone.Account.ID = "account"
+	f.respondZones(map[string][]cloudflare.Zone{"example.com": {zone}})
+
+	tests := []struct {
+		domain     string
+		wantZoneId string
+		wantErr    error
+	}{
+		{domain: "example.com", wantZoneId: "zone-id"},
+		{domain: "other.com", wantErr: errInvalidZone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			zoneId, err := api.VerifyZone(tt.domain)
+			if !errors.Is(err, tt.wantErr) || zoneId != tt.wantZoneId {
+				t.Errorf("VerifyZone(%q) = %q, %v, want %q, %v", tt.domain, zoneId, err, tt.wantZoneId, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInZone(t *testing.T) {
+	api := &CloudflareAPI{Domain: "Example.com"}
+	tests := []struct {
+		fqdn string
+		want bool
+	}{
+		{fqdn: "example.com", want: true},
+		{fqdn: "app.example.com", want: true},
+		{fqdn: "App.Example.com.", want: true},
+		{fqdn: "app.other.com", want: false},
+		{fqdn: "badexample.com", want: false},
+		{fqdn: "example.com.other.com", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fqdn, func(t *testing.T) {
+			if got := api.InZone(tt.fqdn); got != tt.want {
+				t.Errorf("InZone(%q) = %v, want %v", tt.fqdn, got, tt.want)
+			}
+		})
+	}
+}
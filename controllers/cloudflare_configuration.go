@@ -1,7 +1,13 @@
 package controllers
 
 import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	yaml "gopkg.in/yaml.v3"
 )
 
 // https://github.com/cloudflare/cloudflared/blob/master/config/configuration.go
@@ -18,10 +24,17 @@ type Configuration struct {
 
 // UnvalidatedIngressRule is a cloudflared ingress entry model
 type UnvalidatedIngressRule struct {
-	Hostname      string `yaml:"hostname,omitempty"`
-	Path          string `yaml:"path,omitempty"`
-	Service       string
+	Hostname      string              `yaml:"hostname,omitempty"`
+	Path          string              `yaml:"path,omitempty"`
+	Service       string              `yaml:"service"`
 	OriginRequest OriginRequestConfig `yaml:"originRequest,omitempty"`
+	// ManagedBy is written as a comment above the rule to identify what produced it, it is not read back
+	ManagedBy string `yaml:"-"`
+	// Group is written as a comment above the first rule of the group, it is not read back
+	Group string `yaml:"-"`
+	// Before and After are hostnames the rule is ordered above or below, they are not written
+	Before string `yaml:"-"`
+	After  string `yaml:"-"`
 }
 
 // WarpRoutingConfig is a cloudflared warp routing model
@@ -57,6 +70,8 @@ type OriginRequestConfig struct {
 	// Disables chunked transfer encoding.
 	// Useful if you are running a WSGI server.
 	DisableChunkedEncoding *bool `yaml:"disableChunkedEncoding,omitempty"`
+	// Attempt to connect to origin using HTTP2. Origin must be configured as https.
+	Http2Origin *bool `yaml:"http2Origin,omitempty"`
 	// Runs as jump host
 	BastionMode *bool `yaml:"bastionMode,omitempty"`
 	// Listen address for the proxy.
@@ -67,6 +82,15 @@ type OriginRequestConfig struct {
 	ProxyType *string `yaml:"proxyType,omitempty"`
 	// IP rules for the proxy service
 	IPRules []IngressIPRule `yaml:"ipRules,omitempty"`
+	// Validates the Cloudflare Access JWT of the requests
+	Access *AccessConfig `yaml:"access,omitempty"`
+}
+
+// AccessConfig is a cloudflared origin Access validation config model
+type AccessConfig struct {
+	Required bool     `yaml:"required"`
+	TeamName string   `yaml:"teamName"`
+	AudTag   []string `yaml:"audTag"`
 }
 
 // IngressIPRule is a cloudflared origin ingress IP rule config model
@@ -75,3 +99,287 @@ type IngressIPRule struct {
 	Ports  []int   `yaml:"ports,omitempty"`
 	Allow  bool    `yaml:"allow,omitempty"`
 }
+
+// marshalConfiguration marshals the configuration to YAML, annotating each ingress rule with a
+// `# managed-by:` comment when ManagedBy is set, and the first rule of each group with a `# group:` comment
+func marshalConfiguration(config *Configuration) ([]byte, error) {
+	node := &yaml.Node{}
+	if err := node.Encode(config); err != nil {
+		return nil, err
+	}
+
+	// The encoded configuration is a mapping of alternating key and value nodes
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "ingress" {
+			continue
+		}
+		rules := node.Content[i+1].Content
+		if len(rules) != len(config.Ingress) {
+			return nil, fmt.Errorf("encoded %d ingress rules instead of %d", len(rules), len(config.Ingress))
+		}
+		for j, rule := range config.Ingress {
+			var comments []string
+			if rule.Group != "" && (j == 0 || config.Ingress[j-1].Group != rule.Group) {
+				comments = append(comments, "group: "+rule.Group)
+			}
+			if rule.ManagedBy != "" {
+				comments = append(comments, "managed-by: "+rule.ManagedBy)
+			}
+			rules[j].HeadComment = strings.Join(comments, "\n")
+		}
+	}
+	return yaml.Marshal(node)
+}
+
+// templateManagedKeys are the keys of a configuration template always set by the operator
+var templateManagedKeys = map[string]bool{"tunnel": true, "credentials-file": true, "ingress": true}
+
+// applyConfigTemplate returns the template with the keys of templateManagedKeys set to the ones of the encoded configuration.
+// The other keys of the configuration are only added if the template does not have them, and the comments of the template are kept.
+func applyConfigTemplate(template, config []byte) ([]byte, error) {
+	templateDoc := &yaml.Node{}
+	if err := yaml.Unmarshal(template, templateDoc); err != nil {
+		return nil, fmt.Errorf("invalid configuration template: %w", err)
+	}
+	if len(templateDoc.Content) == 0 {
+		templateDoc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	templateRoot := templateDoc.Content[0]
+	if templateRoot.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid configuration template: not a mapping")
+	}
+	configDoc := &yaml.Node{}
+	if err := yaml.Unmarshal(config, configDoc); err != nil {
+		return nil, err
+	}
+	configRoot := configDoc.Content[0]
+
+	// Mappings are alternating key and value nodes
+	keys := map[string]int{}
+	for i := 0; i+1 < len(templateRoot.Content); i += 2 {
+		keys[templateRoot.Content[i].Value] = i
+	}
+	for i := 0; i+1 < len(configRoot.Content); i += 2 {
+		key, value := configRoot.Content[i], configRoot.Content[i+1]
+		if j, ok := keys[key.Value]; !ok {
+			templateRoot.Content = append(templateRoot.Content, key, value)
+		} else if templateManagedKeys[key.Value] {
+			// The comment after a replaced value belongs to the template
+			value.LineComment = templateRoot.Content[j+1].LineComment
+			templateRoot.Content[j+1] = value
+		}
+	}
+	return yaml.Marshal(templateDoc)
+}
+
+// sortIngressByPrecedence orders the rules so that cloudflared, matching them from top to bottom, does not shadow a hostname
+// with a wildcard: specific hostnames first, then wildcards with the most labels first, then rules without hostname.
+// The order is otherwise kept, like the paths of a hostname.
+func sortIngressByPrecedence(rules []UnvalidatedIngressRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return hostnamePrecedence(rules[i].Hostname) < hostnamePrecedence(rules[j].Hostname)
+	})
+}
+
+// orderIngressByConstraints moves the rules with a Before or After hostname right above or below the rules of that hostname,
+// keeping the order of the rules otherwise. If the constraints form a cycle, the rules are left as is and an error naming
+// the hostnames of the cycle is returned.
+func orderIngressByConstraints(rules []UnvalidatedIngressRule) error {
+	byHostname := map[string][]int{}
+	for i, rule := range rules {
+		if rule.Hostname != "" {
+			hostname := strings.ToLower(rule.Hostname)
+			byHostname[hostname] = append(byHostname[hostname], i)
+		}
+	}
+
+	// A rule is placed once all the rules it has to come after are
+	successors := make([][]int, len(rules))
+	predecessors := make([]int, len(rules))
+	constrained := false
+	for i, rule := range rules {
+		hostname := strings.ToLower(rule.Hostname)
+		if before := strings.ToLower(rule.Before); before != "" && before != hostname {
+			for _, j := range byHostname[before] {
+				successors[i] = append(successors[i], j)
+				predecessors[j]++
+				constrained = true
+			}
+		}
+		if after := strings.ToLower(rule.After); after != "" && after != hostname {
+			for _, j := range byHostname[after] {
+				successors[j] = append(successors[j], i)
+				predecessors[i]++
+				constrained = true
+			}
+		}
+	}
+	if !constrained {
+		return nil
+	}
+
+	// Place the first rule in the current order with no predecessor left, so unconstrained rules do not move
+	ordered := make([]UnvalidatedIngressRule, 0, len(rules))
+	placed := make([]bool, len(rules))
+	for len(ordered) < len(rules) {
+		next := -1
+		for i := range rules {
+			if !placed[i] && predecessors[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var hostnames []string
+			seen := map[string]bool{}
+			for i, rule := range rules {
+				if !placed[i] && !seen[rule.Hostname] {
+					seen[rule.Hostname] = true
+					hostnames = append(hostnames, rule.Hostname)
+				}
+			}
+			return fmt.Errorf("before and after form a cycle between the rules of %s", strings.Join(hostnames, ", "))
+		}
+		placed[next] = true
+		ordered = append(ordered, rules[next])
+		for _, j := range successors[next] {
+			predecessors[j]--
+		}
+	}
+	copy(rules, ordered)
+	return nil
+}
+
+// hostnamePrecedence ranks the hostname of a rule, lower ranks having to come first
+func hostnamePrecedence(hostname string) int {
+	switch {
+	case hostname == "" || hostname == "*":
+		return 1 << 16
+	case strings.HasPrefix(hostname, "*"):
+		// *.a.example.com comes before *.example.com
+		return 1<<15 - strings.Count(hostname, ".")
+	default:
+		return 0
+	}
+}
+
+// configDiffContext is the number of unchanged lines shown around the changes of a configuration diff
+const configDiffContext = 2
+
+// configDiffMaxCells caps the size of the longest common subsequence table of the changed lines, beyond it they are
+// reported as all removed and added
+const configDiffMaxCells = 1 << 20
+
+// configDiffLine is a line of a configuration diff, op being ' ', '-' or '+'
+type configDiffLine struct {
+	op   byte
+	text string
+}
+
+// configDiff returns a diff of the current and desired configurations, empty if they are the same.
+// Both are encoded from the model, so that key ordering, formatting and comments of the ConfigMap are not reported.
+func configDiff(current, desired *Configuration) (string, error) {
+	currentBytes, err := yaml.Marshal(current)
+	if err != nil {
+		return "", err
+	}
+	desiredBytes, err := yaml.Marshal(desired)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(currentBytes, desiredBytes) {
+		return "", nil
+	}
+	a := strings.Split(strings.TrimSuffix(string(currentBytes), "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(string(desiredBytes), "\n"), "\n")
+
+	// Only the lines between the common prefix and suffix are diffed, usually the few of the changed rules
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var lines []configDiffLine
+	for _, text := range a[:prefix] {
+		lines = append(lines, configDiffLine{' ', text})
+	}
+	lines = append(lines, diffLines(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, configDiffLine{' ', text})
+	}
+
+	// Keep the changed lines with some context, separating the hunks
+	var out strings.Builder
+	out.WriteString("--- current\n+++ desired\n")
+	last := -1
+	for k, l := range lines {
+		near := false
+		for c := k - configDiffContext; c <= k+configDiffContext; c++ {
+			if c >= 0 && c < len(lines) && lines[c].op != ' ' {
+				near = true
+				break
+			}
+		}
+		if !near {
+			continue
+		}
+		if last != k-1 {
+			out.WriteString("@@\n")
+		}
+		out.WriteByte(l.op)
+		out.WriteString(l.text)
+		out.WriteByte('\n')
+		last = k
+	}
+	return out.String(), nil
+}
+
+// diffLines returns the lines removed from a and added from b around their longest common subsequence
+func diffLines(a, b []string) []configDiffLine {
+	var lines []configDiffLine
+	if len(a) == 0 || len(b) == 0 || (len(a)+1)*(len(b)+1) > configDiffMaxCells {
+		for _, text := range a {
+			lines = append(lines, configDiffLine{'-', text})
+		}
+		for _, text := range b {
+			lines = append(lines, configDiffLine{'+', text})
+		}
+		return lines
+	}
+
+	// Longest common subsequence of the lines, lcs[i][j] being the one of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, configDiffLine{' ', a[i]})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, configDiffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, configDiffLine{'+', b[j]})
+			j++
+		}
+	}
+	return lines
+}
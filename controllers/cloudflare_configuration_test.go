@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalConfigurationRule(t *testing.T) {
+	connectTimeout := 30 * time.Second
+	config := &Configuration{
+		TunnelId:   "tunnel-id",
+		SourceFile: "/etc/cloudflared/creds/credentials.json",
+		Ingress: []UnvalidatedIngressRule{
+			{
+				Hostname: "app.example.com",
+				Path:     "^/api/.*",
+				Service:  "https://app.default.svc:443",
+				OriginRequest: OriginRequestConfig{
+					ConnectTimeout:   &connectTimeout,
+					OriginServerName: ptr("app.example.com"),
+					NoTLSVerify:      ptr(true),
+				},
+				ManagedBy: "TunnelBinding/default/app",
+			},
+			{Service: "http_status:404"},
+		},
+	}
+	got, err := marshalConfiguration(config)
+	if err != nil {
+		t.Fatalf("marshalConfiguration() error = %v", err)
+	}
+	want := `tunnel: tunnel-id
+ingress:
+    # managed-by: TunnelBinding/default/app
+    - hostname: app.example.com
+      path: ^/api/.*
+      service: https://app.default.svc:443
+      originRequest:
+        connectTimeout: 30s
+        originServerName: app.example.com
+        noTLSVerify: true
+    - service: http_status:404
+credentials-file: /etc/cloudflared/creds/credentials.json
+`
+	if string(got) != want {
+		t.Errorf("marshalConfiguration() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConfigDiff(t *testing.T) {
+	current := &Configuration{TunnelId: "tunnel-id", SourceFile: "/etc/cloudflared/creds/credentials.json"}
+	for i := 0; i < 10; i++ {
+		current.Ingress = append(current.Ingress, UnvalidatedIngressRule{Hostname: fmt.Sprintf("app%d.example.com", i), Service: "http://app:80"})
+	}
+	current.Ingress = append(current.Ingress, UnvalidatedIngressRule{Service: "http_status:404"})
+
+	if diff, err := configDiff(current, current); err != nil || diff != "" {
+		t.Errorf("configDiff() of the same configuration = %q, %v, want no diff", diff, err)
+	}
+
+	desired := *current
+	desired.Ingress = append([]UnvalidatedIngressRule{}, current.Ingress...)
+	desired.Ingress[5].Service = "http://other:80"
+	diff, err := configDiff(current, &desired)
+	if err != nil {
+		t.Fatalf("configDiff() error = %v", err)
+	}
+	want := `--- current
++++ desired
+@@
+       service: http://app:80
+     - hostname: app5.example.com
+-      service: http://app:80
++      service: http://other:80
+     - hostname: app6.example.com
+       service: http://app:80
+`
+	if diff != want {
+		t.Errorf("configDiff() =\n%s\nwant\n%s", diff, want)
+	}
+}
+
+func TestDiffLinesOverCap(t *testing.T) {
+	// The changed lines of configurations too large for the table are reported as removed and added
+	var a, b []string
+	for i := 0; i < 1100; i++ {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+	lines := diffLines(a, b)
+	if len(lines) != len(a)+len(b) {
+		t.Fatalf("diffLines() returned %d lines, want %d", len(lines), len(a)+len(b))
+	}
+	for i, line := range lines {
+		want := byte('-')
+		if i >= len(a) {
+			want = '+'
+		}
+		if line.op != want {
+			t.Fatalf("line %d = %c%s, want %c", i, line.op, line.text, want)
+		}
+	}
+
+	// Small ones keep their common lines
+	lines = diffLines(strings.Split("a b c", " "), strings.Split("a x c", " "))
+	var got []string
+	for _, line := range lines {
+		got = append(got, string(line.op)+line.text)
+	}
+	if strings.Join(got, ",") != " a,-b,+x, c" {
+		t.Errorf("diffLines() = %q", got)
+	}
+}
+
+// hostnames returns the hostnames of the rules, with their path if set
+func hostnames(rules []UnvalidatedIngressRule) string {
+	var names []string
+	for _, rule := range rules {
+		name := rule.Hostname
+		if rule.Path != "" {
+			name += rule.Path
+		}
+		if name == "" {
+			name = "-"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestSortIngressByPrecedence(t *testing.T) {
+	rules := []UnvalidatedIngressRule{
+		{Hostname: "*.example.com"},
+		{Service: "http_status:404"},
+		{Hostname: "app.example.com", Path: "/api"},
+		{Hostname: "*.dev.example.com"},
+		{Hostname: "*"},
+		{Hostname: "app.dev.example.com"},
+		{Hostname: "app.example.com", Path: "/"},
+		{Hostname: "*.a.dev.example.com"},
+	}
+	sortIngressByPrecedence(rules)
+	want := "app.example.com/api app.dev.example.com app.example.com/ *.a.dev.example.com *.dev.example.com *.example.com - *"
+	if got := hostnames(rules); got != want {
+		t.Errorf("sortIngressByPrecedence() = %s, want %s", got, want)
+	}
+}
+
+func TestHostnamePrecedence(t *testing.T) {
+	ordered := []string{"app.example.com", "*.a.b.example.com", "*.b.example.com", "*.example.com", ""}
+	for i := 0; i+1 < len(ordered); i++ {
+		if hostnamePrecedence(ordered[i]) >= hostnamePrecedence(ordered[i+1]) {
+			t.Errorf("hostnamePrecedence(%q) is not below hostnamePrecedence(%q)", ordered[i], ordered[i+1])
+		}
+	}
+	if hostnamePrecedence("*") != hostnamePrecedence("") {
+		t.Errorf("hostnamePrecedence(\"*\") = %d, want the one of a rule without hostname", hostnamePrecedence("*"))
+	}
+	if hostnamePrecedence("a.example.com") != hostnamePrecedence("a.b.c.example.com") {
+		t.Errorf("specific hostnames should share a precedence so that their order is kept")
+	}
+}
+
+func TestOrderIngressByConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []UnvalidatedIngressRule
+		want    string
+		wantErr string
+	}{
+		{
+			name: "unconstrained",
+			rules: []UnvalidatedIngressRule{
+				{Hostname: "b.example.com"}, {Hostname: "a.example.com"}, {Hostname: "*.example.com"},
+			},
+			want: "b.example.com a.example.com *.example.com",
+		},
+		{
+			name: "before",
+			rules: []UnvalidatedIngressRule{
+				{Hostname: "a.example.com"}, {Hostname: "b.example.com"}, {Hostname: "c.example.com", Before: "A.example.com"},
+			},
+			want: "b.example.com c.example.com a.example.com",
+		},
+		{
+			name: "after all rules of the hostname",
+			rules: []UnvalidatedIngressRule{
+				{Hostname: "a.example.com", After: "c.example.com"},
+				{Hostname: "b.example.com"},
+				{Hostname: "c.example.com", Path: "/api"},
+				{Hostname: "c.example.com", Path: "/"},
+			},
+			want: "b.example.com c.example.com/api c.example.com/ a.example.com",
+		},
+		{
+			name: "unknown and own hostnames are ignored",
+			rules: []UnvalidatedIngressRule{
+				{Hostname: "a.example.com", After: "a.example.com"}, {Hostname: "b.example.com", Before: "missing.example.com"},
+			},
+			want: "a.example.com b.example.com",
+		},
+		{
+			name: "cycle",
+			rules: []UnvalidatedIngressRule{
+				{Hostname: "x.example.com"},
+				{Hostname: "a.example.com", Before: "b.example.com"},
+				{Hostname: "b.example.com", Before: "c.example.com"},
+				{Hostname: "c.example.com", Before: "a.example.com"},
+			},
+			want:    "x.example.com a.example.com b.example.com c.example.com",
+			wantErr: "before and after form a cycle between the rules of a.example.com, b.example.com, c.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := orderIngressByConstraints(tt.rules)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("orderIngressByConstraints() error = %v", err)
+			} else if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("orderIngressByConstraints() error = %v, want %s", err, tt.wantErr)
+			}
+			if got := hostnames(tt.rules); got != tt.want {
+				t.Errorf("orderIngressByConstraints() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyConfigTemplate(t *testing.T) {
+	template := `# Shared cloudflared settings
+tunnel: placeholder # replaced by the operator
+credentials-file: /tmp/credentials.json
+# Keep the metrics port stable for scraping
+metrics: 0.0.0.0:9090
+loglevel: debug
+originRequest:
+  connectTimeout: 10s
+ingress:
+  - service: http_status:503
+`
+	config := &Configuration{
+		TunnelId:      "tunnel-id",
+		SourceFile:    "/etc/cloudflared/creds/credentials.json",
+		Metrics:       "0.0.0.0:2000",
+		NoAutoUpdate:  true,
+		OriginRequest: OriginRequestConfig{NoTLSVerify: ptr(false)},
+		Ingress: []UnvalidatedIngressRule{
+			{Hostname: "app.example.com", Service: "http://app.default.svc:80", ManagedBy: "TunnelBinding/default/app"},
+			{Service: "http_status:404"},
+		},
+	}
+	configBytes, err := marshalConfiguration(config)
+	if err != nil {
+		t.Fatalf("marshalConfiguration() error = %v", err)
+	}
+
+	got, err := applyConfigTemplate([]byte(template), configBytes)
+	if err != nil {
+		t.Fatalf("applyConfigTemplate() error = %v", err)
+	}
+	want := `# Shared cloudflared settings
+tunnel: tunnel-id # replaced by the operator
+credentials-file: /etc/cloudflared/creds/credentials.json
+# Keep the metrics port stable for scraping
+metrics: 0.0.0.0:9090
+loglevel: debug
+originRequest:
+    connectTimeout: 10s
+ingress:
+    # managed-by: TunnelBinding/default/app
+    - hostname: app.example.com
+      service: http://app.default.svc:80
+    - service: http_status:404
+no-autoupdate: true
+`
+	if string(got) != want {
+		t.Errorf("applyConfigTemplate() =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := applyConfigTemplate([]byte("- not\n- a mapping\n"), configBytes); err == nil {
+		t.Errorf("applyConfigTemplate() of a sequence template did not fail")
+	}
+}
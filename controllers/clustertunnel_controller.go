@@ -18,15 +18,21 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
 	"github.com/go-logr/logr"
@@ -38,15 +44,19 @@ type ClusterTunnelReconciler struct {
 	Scheme    *runtime.Scheme
 	Recorder  record.EventRecorder
 	Namespace string
+	// Credentials reads the Cloudflare credentials of the tunnels, defaults to their Secret
+	Credentials CredentialProvider
+	// DeploymentCheckInterval requeues the tunnels to recreate their Deployment if deleted, disabled if 0
+	DeploymentCheckInterval time.Duration
 
 	// Custom data for ease of (re)use
 
-	ctx         context.Context
-	log         logr.Logger
-	tunnel      Tunnel
-	cfAPI       *CloudflareAPI
-	cfSecret    *corev1.Secret
-	tunnelCreds string
+	ctx           context.Context
+	log           logr.Logger
+	tunnel        Tunnel
+	cfAPI         *CloudflareAPI
+	cfCredentials map[string][]byte
+	tunnelCreds   string
 }
 
 func (r *ClusterTunnelReconciler) GetClient() client.Client {
@@ -81,8 +91,8 @@ func (r *ClusterTunnelReconciler) SetCfAPI(in *CloudflareAPI) {
 	r.cfAPI = in
 }
 
-func (r *ClusterTunnelReconciler) GetCfSecret() *corev1.Secret {
-	return r.cfSecret
+func (r *ClusterTunnelReconciler) GetCfCredentials() map[string][]byte {
+	return r.cfCredentials
 }
 
 func (r *ClusterTunnelReconciler) GetTunnelCreds() string {
@@ -99,9 +109,10 @@ func (r *ClusterTunnelReconciler) initStruct(ctx context.Context, tunnel Tunnel)
 
 	var err error
 
-	if r.cfAPI, r.cfSecret, err = getAPIDetails(r.ctx, r.Client, r.log, r.tunnel.GetSpec(), r.tunnel.GetStatus(), r.tunnel.GetNamespace()); err != nil {
+	if r.cfAPI, r.cfCredentials, err = getAPIDetails(r.ctx, credentialProviderOrDefault(r.Credentials, r.Client), r.log, r.tunnel.GetSpec(), r.tunnel.GetStatus(), r.tunnel.GetNamespace()); err != nil {
 		r.log.Error(err, "unable to get API details")
 		r.Recorder.Event(r.tunnel.GetObject(), corev1.EventTypeWarning, "ErrSpecSecret", "Error reading Secret to configure API")
+		setAuthFailedCondition(r, "InvalidSecret", err)
 		return err
 	}
 
@@ -136,10 +147,11 @@ func (r *ClusterTunnelReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
 			r.log.Info("Tunnel deleted, nothing to do")
+			deleteTunnelMetrics(tunnelKey{Kind: "clustertunnel", Namespace: r.Namespace, Name: req.Name})
 			return ctrl.Result{}, nil
 		}
 		r.log.Error(err, "unable to fetch Tunnel")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, fmt.Errorf("failed to fetch ClusterTunnel %s: %w", req.NamespacedName, err)
 	}
 
 	if err := r.initStruct(ctx, ClusterTunnelAdapter{tunnel, r.Namespace}); err != nil {
@@ -155,14 +167,46 @@ func (r *ClusterTunnelReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, err
 	}
 
+	// Configure zone settings opted in to
+	if err := syncArgoSmartRouting(r); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Create necessary resources
 	if res, ok, err := createManagedResources(r); !ok {
 		return res, err
 	}
 
+	// Check the Deployment again later, in case its deletion was missed
+	if deploymentManaged(r.tunnel) {
+		return ctrl.Result{RequeueAfter: r.DeploymentCheckInterval}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
+// clusterTunnelsForSecret returns reconcile requests for the ClusterTunnels using the Cloudflare credentials in the Secret,
+// so that rotated credentials are picked up and verified right away.
+func (r *ClusterTunnelReconciler) clusterTunnelsForSecret(secret client.Object) []reconcile.Request {
+	// ClusterTunnels only read Secrets from the cluster resource namespace
+	if secret.GetNamespace() != r.Namespace {
+		return nil
+	}
+
+	clusterTunnelList := &networkingv1alpha1.ClusterTunnelList{}
+	if err := r.List(context.Background(), clusterTunnelList); err != nil {
+		ctrllog.Log.Error(err, "failed to list ClusterTunnels for Secret", "secret", secret.GetName(), "namespace", secret.GetNamespace())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, clusterTunnel := range clusterTunnelList.Items {
+		if clusterTunnel.Spec.Cloudflare.Secret == secret.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: apitypes.NamespacedName{Name: clusterTunnel.Name}})
+		}
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterTunnelReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.Recorder = mgr.GetEventRecorderFor("cloudflare-operator")
@@ -171,5 +215,6 @@ func (r *ClusterTunnelReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Secret{}).
 		Owns(&appsv1.Deployment{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.clusterTunnelsForSecret)).
 		Complete(r)
 }
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CredentialProvider reads the Cloudflare credentials of a tunnel, like the API token and the tunnel credentials file.
+// The values are keyed by the key names of tunnel.spec.cloudflare, like CLOUDFLARE_API_TOKEN.
+// Implement it and set it on the reconcilers to read the credentials from an external secret store instead of a Secret.
+type CredentialProvider interface {
+	Credentials(ctx context.Context, tunnelSpec networkingv1alpha1.TunnelSpec, namespace string) (map[string][]byte, error)
+}
+
+// SecretCredentialProvider reads the credentials from the Secret of tunnel.spec.cloudflare.secret, in the namespace of the tunnel
+type SecretCredentialProvider struct {
+	client.Client
+}
+
+// Credentials returns the data of the Secret of the tunnel
+func (p SecretCredentialProvider) Credentials(ctx context.Context, tunnelSpec networkingv1alpha1.TunnelSpec, namespace string) (map[string][]byte, error) {
+	cfSecret := &corev1.Secret{}
+	if err := p.Get(ctx, apitypes.NamespacedName{Name: tunnelSpec.Cloudflare.Secret, Namespace: namespace}, cfSecret); err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %w", namespace, tunnelSpec.Cloudflare.Secret, err)
+	}
+	return cfSecret.Data, nil
+}
+
+// credentialProviderOrDefault returns the provider, or the one reading the Secrets with the client if unset
+func credentialProviderOrDefault(provider CredentialProvider, c client.Client) CredentialProvider {
+	if provider != nil {
+		return provider
+	}
+	return SecretCredentialProvider{c}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeCredentialProvider returns the same credentials for every tunnel, like an external secret store would
+type fakeCredentialProvider struct {
+	credentials map[string][]byte
+	err         error
+	// tunnels records the namespace/secret of the requested credentials
+	tunnels []string
+}
+
+func (p *fakeCredentialProvider) Credentials(_ context.Context, tunnelSpec networkingv1alpha1.TunnelSpec, namespace string) (map[string][]byte, error) {
+	p.tunnels = append(p.tunnels, namespace+"/"+tunnelSpec.Cloudflare.Secret)
+	return p.credentials, p.err
+}
+
+func testTunnelSpec() networkingv1alpha1.TunnelSpec {
+	spec := networkingv1alpha1.TunnelSpec{}
+	spec.Cloudflare.Secret = "cloudflare"
+	spec.Cloudflare.Domain = "example.com"
+	spec.Cloudflare.AccountId = "account"
+	spec.Cloudflare.CLOUDFLARE_API_TOKEN = "CLOUDFLARE_API_TOKEN"
+	spec.Cloudflare.CLOUDFLARE_API_KEY = "CLOUDFLARE_API_KEY"
+	return spec
+}
+
+func TestGetAPIDetailsCredentialProvider(t *testing.T) {
+	provider := &fakeCredentialProvider{credentials: map[string][]byte{"CLOUDFLARE_API_TOKEN": []byte("token"), "credentials.json": []byte("{}")}}
+	status := networkingv1alpha1.TunnelStatus{TunnelId: "tunnel-id", ZoneId: "zone"}
+
+	cfAPI, credentials, err := getAPIDetails(context.Background(), provider, logr.Discard(), testTunnelSpec(), status, "default")
+	if err != nil {
+		t.Fatalf("getAPIDetails() error = %v", err)
+	}
+	if len(provider.tunnels) != 1 || provider.tunnels[0] != "default/cloudflare" {
+		t.Errorf("credentials requested for %v, want default/cloudflare", provider.tunnels)
+	}
+	if cfAPI.APIToken != "token" || cfAPI.APIKey != "" || cfAPI.CloudflareClient == nil {
+		t.Errorf("getAPIDetails() = %+v, want a client with the API token of the provider", cfAPI)
+	}
+	if cfAPI.Domain != "example.com" || cfAPI.AccountId != "account" || cfAPI.ValidTunnelId != "tunnel-id" || cfAPI.ValidZoneId != "zone" {
+		t.Errorf("getAPIDetails() = %+v, want the tunnel spec and status", cfAPI)
+	}
+	if string(credentials["credentials.json"]) != "{}" {
+		t.Errorf("credentials = %v, want the ones of the provider", credentials)
+	}
+
+	provider = &fakeCredentialProvider{err: errors.New("secret store unavailable")}
+	if _, _, err := getAPIDetails(context.Background(), provider, logr.Discard(), testTunnelSpec(), status, "default"); !errors.Is(err, provider.err) {
+		t.Errorf("getAPIDetails() error = %v, want the provider error", err)
+	}
+}
+
+func TestSecretCredentialProvider(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cloudflare"},
+		Data:       map[string][]byte{"CLOUDFLARE_API_TOKEN": []byte("token")},
+	}
+	c := newFakeClient(t, secret)
+	provider := credentialProviderOrDefault(nil, c)
+
+	credentials, err := provider.Credentials(context.Background(), testTunnelSpec(), "default")
+	if err != nil {
+		t.Fatalf("Credentials() error = %v", err)
+	}
+	if string(credentials["CLOUDFLARE_API_TOKEN"]) != "token" {
+		t.Errorf("Credentials() = %v, want the data of the Secret", credentials)
+	}
+	if _, err := provider.Credentials(context.Background(), testTunnelSpec(), "other"); err == nil {
+		t.Errorf("Credentials() error = nil, want the Secret of the tunnel namespace only")
+	}
+
+	custom := &fakeCredentialProvider{}
+	if credentialProviderOrDefault(custom, c) != custom {
+		t.Errorf("credentialProviderOrDefault() did not keep the custom provider")
+	}
+}
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	yaml "gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// DNSSweepPath is the path on the metrics server triggering a DNS sweep
+const DNSSweepPath = "/dns-sweep"
+
+// defaultDNSSweepInterval paces the records of a sweep below the Cloudflare API rate limit of 4 requests per second.
+// Each record takes about 5 requests: the CNAME read of the sweep, then the TXT and CNAME reads and the CNAME and TXT
+// upserts of createDNSLogic, so 2.5 requests per second.
+const defaultDNSSweepInterval = 2 * time.Second
+
+// DNSSweep re-asserts the DNS records of all TunnelBindings in the background on a POST request, for recovering from a
+// Cloudflare outage, and reports the progress of the running or last sweep on a GET request.
+// Only DNS records are touched, the tunnel configurations are not rewritten so cloudflared is not restarted.
+type DNSSweep struct {
+	Bindings *TunnelBindingReconciler
+	// Interval between two records, defaults to defaultDNSSweepInterval
+	Interval time.Duration
+
+	log logr.Logger
+	// requests passes the sweeps started by ServeHTTP to Start
+	requests chan struct{}
+
+	mu     sync.Mutex
+	status *DNSSweepStatus
+	// stopped is set once Start returns, no sweep runs anymore
+	stopped bool
+}
+
+// DNSSweepResult is the outcome of the sweep for a DNS record
+type DNSSweepResult struct {
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+	Hostname  string `yaml:"hostname"`
+	// Result is created, corrected, unchanged, skipped or failed
+	Result string `yaml:"result"`
+	Error  string `yaml:"error,omitempty"`
+}
+
+// DNSSweepSummary is the outcome of a sweep
+type DNSSweepSummary struct {
+	Counts  map[string]int   `yaml:"counts"`
+	Records []DNSSweepResult `yaml:"records"`
+}
+
+// DNSSweepStatus is the progress of a sweep, with the records swept so far
+type DNSSweepStatus struct {
+	// State is pending until Start picks the sweep up, then running, done, interrupted or failed
+	State           string     `yaml:"state"`
+	Started         time.Time  `yaml:"started"`
+	Finished        *time.Time `yaml:"finished,omitempty"`
+	Error           string     `yaml:"error,omitempty"`
+	DNSSweepSummary `yaml:",inline"`
+}
+
+// ServeHTTP starts a sweep on POST, responding 202 Accepted, and writes the status of the sweep as YAML on GET
+func (s *DNSSweep) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		if s.status == nil {
+			http.Error(w, "no DNS sweep started, use POST to trigger one", http.StatusNotFound)
+			return
+		}
+		s.writeStatus(w, http.StatusOK)
+	case http.MethodPost:
+		if s.stopped {
+			http.Error(w, "the operator is stopping, no DNS sweep can be started", http.StatusServiceUnavailable)
+			return
+		}
+		if s.status != nil && (s.status.State == "pending" || s.status.State == "running") {
+			w.Header().Set("Location", DNSSweepPath)
+			http.Error(w, "a DNS sweep is already running, use GET to follow it", http.StatusConflict)
+			return
+		}
+		s.status = &DNSSweepStatus{State: "pending", Started: time.Now(), DNSSweepSummary: DNSSweepSummary{Counts: map[string]int{}}}
+		// Only one sweep is pending or running at a time, so this does not block
+		s.requests <- struct{}{}
+		w.Header().Set("Location", DNSSweepPath)
+		s.writeStatus(w, http.StatusAccepted)
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		http.Error(w, "use POST to trigger a DNS sweep and GET to follow it", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeStatus writes the status of the sweep as YAML, s.mu being held
+func (s *DNSSweep) writeStatus(w http.ResponseWriter, code int) {
+	out, err := yaml.Marshal(s.status)
+	if err != nil {
+		s.log.Error(err, "failed to marshal DNS sweep status")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(code)
+	if _, err := w.Write(out); err != nil {
+		s.log.Error(err, "failed to write DNS sweep status")
+	}
+}
+
+// Start runs the sweeps requested on ServeHTTP until the context is cancelled, which interrupts a running one
+// and a pending one
+func (s *DNSSweep) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			s.stop(ctx.Err())
+			return nil
+		case <-s.requests:
+			s.run(ctx)
+		}
+	}
+}
+
+// stop marks a sweep requested but not started as interrupted, and refuses new ones
+func (s *DNSSweep) stop(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	select {
+	case <-s.requests:
+		finished := time.Now()
+		s.status.State = "interrupted"
+		s.status.Finished = &finished
+		s.status.Error = fmt.Sprintf("DNS sweep interrupted before starting: %v", err)
+	default:
+	}
+}
+
+// NeedLeaderElection lets every replica run the sweeps requested on its own metrics endpoint.
+// Records are re-asserted the same way a reconcile does, so sweeps on several replicas are harmless.
+func (s *DNSSweep) NeedLeaderElection() bool {
+	return false
+}
+
+// run runs a sweep, recording its progress in the status
+func (s *DNSSweep) run(ctx context.Context) {
+	s.mu.Lock()
+	s.status.State = "running"
+	s.mu.Unlock()
+
+	_, err := s.Sweep(ctx, func(result DNSSweepResult) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.status.Counts[result.Result]++
+		s.status.Records = append(s.status.Records, result)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := time.Now()
+	s.status.Finished = &finished
+	switch {
+	case err == nil:
+		s.status.State = "done"
+	case ctx.Err() != nil:
+		s.status.State = "interrupted"
+		s.status.Error = err.Error()
+	default:
+		s.log.Error(err, "failed to sweep DNS records")
+		s.status.State = "failed"
+		s.status.Error = err.Error()
+	}
+}
+
+// Sweep re-asserts the DNS records of all TunnelBindings one at a time, calling progress with the result of each record.
+// Errors on a single record are reported in its result instead of stopping the sweep. If the context is cancelled,
+// the summary of the records swept so far is returned with the error.
+func (s *DNSSweep) Sweep(ctx context.Context, progress func(DNSSweepResult)) (*DNSSweepSummary, error) {
+	summary := &DNSSweepSummary{Counts: map[string]int{}}
+	bindingList := &networkingv1alpha1.TunnelBindingList{}
+	if err := s.Bindings.List(ctx, bindingList); err != nil {
+		return summary, fmt.Errorf("failed to list TunnelBindings: %w", err)
+	}
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultDNSSweepInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.log.Info("Starting DNS sweep", "tunnelBindings", len(bindingList.Items))
+	for i := range bindingList.Items {
+		for _, result := range s.sweepBinding(ctx, &bindingList.Items[i], ticker) {
+			summary.Counts[result.Result]++
+			summary.Records = append(summary.Records, result)
+			if progress != nil {
+				progress(result)
+			}
+		}
+		if ctx.Err() != nil {
+			s.log.Info("DNS sweep interrupted", "counts", summary.Counts)
+			return summary, fmt.Errorf("DNS sweep interrupted: %w", ctx.Err())
+		}
+	}
+	s.log.Info("DNS sweep done", "counts", summary.Counts)
+	return summary, nil
+}
+
+// sweepBinding re-asserts the DNS records of the TunnelBinding, waiting for the ticker before each record
+func (s *DNSSweep) sweepBinding(ctx context.Context, binding *networkingv1alpha1.TunnelBinding, ticker *time.Ticker) []DNSSweepResult {
+	results := make([]DNSSweepResult, 0, len(binding.Status.Services))
+	result := func(info networkingv1alpha1.ServiceInfo, outcome string, err error) DNSSweepResult {
+		r := DNSSweepResult{Namespace: binding.Namespace, Name: binding.Name, Hostname: recordNameForService(info), Result: outcome}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		return r
+	}
+	all := func(outcome string, err error) []DNSSweepResult {
+		for _, info := range binding.Status.Services {
+			results = append(results, result(info, outcome, err))
+		}
+		return results
+	}
+	skipAll := func(reason error) []DNSSweepResult {
+		return all("skipped", reason)
+	}
+
+	if binding.GetDeletionTimestamp() != nil || bindingExpired(binding) {
+		return skipAll(fmt.Errorf("TunnelBinding is being deleted or expired"))
+	}
+	if binding.TunnelRef.DisableDNSUpdates {
+		return skipAll(fmt.Errorf("DNS updates are disabled"))
+	}
+
+	// Same as a reconcile of the TunnelBinding, reporting the Events on it
+	r := *s.Bindings
+	r.log = s.log.WithValues("tunnelBinding", binding.Name, "namespace", binding.Namespace)
+	if err := r.initStruct(ctx, binding); err != nil {
+		return all("failed", fmt.Errorf("initialization failed: %w", err))
+	}
+	if r.annotationEnabled(tunnelDNSPausedAnnotation) {
+		return skipAll(fmt.Errorf("DNS updates are paused"))
+	}
+	if r.zoneCondition != nil && r.zoneCondition.Status == metav1.ConditionFalse {
+		return skipAll(fmt.Errorf("the tunnel domain is not a valid zone: %s", r.zoneCondition.Message))
+	}
+
+	for i, info := range binding.Status.Services {
+		if i >= len(binding.Subjects) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return results
+		case <-ticker.C:
+		}
+
+		hostname := recordNameForService(info)
+		if info.DNSName != "" && !r.cfAPI.InZone(info.DNSName) {
+			results = append(results, result(info, "failed", fmt.Errorf("dnsName %s is not in the zone %s", info.DNSName, r.cfAPI.Domain)))
+			continue
+		}
+		options := r.getDNSOptionsForSubject(binding.Subjects[i])
+		outcome := "unchanged"
+		if existing, err := r.cfAPI.GetDNSCNameRecord(hostname); err != nil || existing.ID == "" {
+			outcome = "created"
+		} else if !r.cfAPI.CNameInSync(existing, options) {
+			outcome = "corrected"
+		}
+		if err := r.createDNSLogic(hostname, options); err != nil {
+			results = append(results, result(info, "failed", err))
+			continue
+		}
+		results = append(results, result(info, outcome, nil))
+	}
+	return results
+}
+
+// SetupWithManager serves the sweep on the metrics server of the Manager, and runs it with the Manager.
+func (s *DNSSweep) SetupWithManager(mgr ctrl.Manager) error {
+	s.log = ctrl.Log.WithName("dns-sweep")
+	s.requests = make(chan struct{}, 1)
+	if err := mgr.AddMetricsExtraHandler(DNSSweepPath, s); err != nil {
+		return err
+	}
+	return mgr.Add(s)
+}
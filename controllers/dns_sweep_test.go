@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTestDNSSweep returns a sweep of TunnelBindings with DNS updates disabled, which are skipped without calling Cloudflare
+func newTestDNSSweep(t *testing.T, names ...string) *DNSSweep {
+	scheme := runtime.NewScheme()
+	if err := networkingv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	var objects []runtime.Object
+	for _, name := range names {
+		binding := &networkingv1alpha1.TunnelBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name}}
+		binding.TunnelRef = networkingv1alpha1.TunnelRef{Kind: "Tunnel", Name: "web", DisableDNSUpdates: true}
+		binding.Status.Services = []networkingv1alpha1.ServiceInfo{{Hostname: name + ".example.com"}}
+		objects = append(objects, binding)
+	}
+	return &DNSSweep{
+		Bindings: &TunnelBindingReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build()},
+		Interval: time.Millisecond,
+		log:      ctrl.Log.WithName("dns-sweep"),
+		requests: make(chan struct{}, 1),
+	}
+}
+
+func TestDNSSweepInterrupted(t *testing.T) {
+	sweep := newTestDNSSweep(t, "a", "b", "c")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	summary, err := sweep.Sweep(ctx, func(DNSSweepResult) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Sweep() error = %v, want context.Canceled", err)
+	}
+	if summary == nil || len(summary.Records) != 1 || summary.Counts["skipped"] != 1 {
+		t.Fatalf("Sweep() = %+v, want the record swept before the cancellation", summary)
+	}
+	if summary.Records[0].Hostname != "a.example.com" {
+		t.Errorf("Sweep() swept %s, want a.example.com", summary.Records[0].Hostname)
+	}
+}
+
+func TestDNSSweepServeHTTP(t *testing.T) {
+	sweep := newTestDNSSweep(t, "a", "b")
+	serve := func(method string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		sweep.ServeHTTP(recorder, httptest.NewRequest(method, DNSSweepPath, nil))
+		return recorder
+	}
+
+	if code := serve(http.MethodGet).Code; code != http.StatusNotFound {
+		t.Errorf("GET before a sweep = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := serve(http.MethodDelete).Code; code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE = %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+
+	// The sweep only runs once Start picks it up
+	started := serve(http.MethodPost)
+	if started.Code != http.StatusAccepted || !strings.Contains(started.Body.String(), "state: pending") {
+		t.Fatalf("POST = %d %q, want %d with a pending state", started.Code, started.Body.String(), http.StatusAccepted)
+	}
+	if code := serve(http.MethodPost).Code; code != http.StatusConflict {
+		t.Errorf("POST during a sweep = %d, want %d", code, http.StatusConflict)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sweep.Start(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var status *httptest.ResponseRecorder
+	for {
+		status = serve(http.MethodGet)
+		if body := status.Body.String(); !strings.Contains(body, "state: pending") && !strings.Contains(body, "state: running") || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	body := status.Body.String()
+	for _, want := range []string{"state: done", "skipped: 2", "hostname: a.example.com", "hostname: b.example.com", "finished:"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET after the sweep = %q, want it to contain %q", body, want)
+		}
+	}
+
+	// Another sweep can run once the last one is done
+	if code := serve(http.MethodPost).Code; code != http.StatusAccepted {
+		t.Errorf("POST after a sweep = %d, want %d", code, http.StatusAccepted)
+	}
+}
+
+func TestDNSSweepStoppedBeforeStart(t *testing.T) {
+	sweep := newTestDNSSweep(t, "a")
+	serve := func(method string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		sweep.ServeHTTP(recorder, httptest.NewRequest(method, DNSSweepPath, nil))
+		return recorder
+	}
+	if code := serve(http.MethodPost).Code; code != http.StatusAccepted {
+		t.Fatalf("POST = %d, want %d", code, http.StatusAccepted)
+	}
+
+	// The operator stops before the sweep is picked up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sweep.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if body := serve(http.MethodGet).Body.String(); !strings.Contains(body, "state: interrupted") || !strings.Contains(body, "finished:") {
+		t.Errorf("GET after stopping = %q, want an interrupted state", body)
+	}
+	if code := serve(http.MethodPost).Code; code != http.StatusServiceUnavailable {
+		t.Errorf("POST after stopping = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
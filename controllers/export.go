@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	yaml "gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ExportPath is the path on the metrics server serving the export of the managed resources
+const ExportPath = "/managed-resources"
+
+// TunnelConfigPath is the path on the metrics server serving the rendered configuration of a tunnel,
+// as TunnelConfigPath<namespace>/<name>/config. ClusterTunnels are in the cluster resource namespace.
+const TunnelConfigPath = "/tunnels/"
+
+// ManagedExport serves a YAML snapshot of all Tunnels and ClusterTunnels with their TunnelBindings,
+// rendered ingress rules and the DNS records managed on Cloudflare, for audits and disaster recovery.
+type ManagedExport struct {
+	client.Client
+	Namespace string
+	// Credentials reads the Cloudflare credentials of the tunnels, defaults to their Secret
+	Credentials CredentialProvider
+
+	log logr.Logger
+}
+
+// ExportedTunnel is the exported state of a Tunnel or ClusterTunnel
+type ExportedTunnel struct {
+	Kind       string                   `yaml:"kind"`
+	Namespace  string                   `yaml:"namespace"`
+	Name       string                   `yaml:"name"`
+	TunnelId   string                   `yaml:"tunnelId"`
+	Domain     string                   `yaml:"domain"`
+	Bindings   []ExportedBinding        `yaml:"bindings"`
+	Ingress    []UnvalidatedIngressRule `yaml:"ingress"`
+	DnsRecords []ExportedDnsRecord      `yaml:"dnsRecords"`
+	Errors     []string                 `yaml:"errors,omitempty"`
+}
+
+// ExportedBinding is the exported state of a TunnelBinding
+type ExportedBinding struct {
+	Namespace string                           `yaml:"namespace"`
+	Name      string                           `yaml:"name"`
+	Services  []networkingv1alpha1.ServiceInfo `yaml:"services"`
+}
+
+// ExportedDnsRecord is a DNS record managed by the operator
+type ExportedDnsRecord struct {
+	Id      string `yaml:"id"`
+	Type    string `yaml:"type"`
+	Name    string `yaml:"name"`
+	Content string `yaml:"content"`
+}
+
+//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnels,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=clustertunnels,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnelbindings,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+
+// ServeHTTP writes the export as YAML
+func (e *ManagedExport) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	tunnels, err := e.Export(req.Context())
+	if err != nil {
+		e.log.Error(err, "failed to export managed resources")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	out, err := yaml.Marshal(tunnels)
+	if err != nil {
+		e.log.Error(err, "failed to marshal managed resources")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := w.Write(out); err != nil {
+		e.log.Error(err, "failed to write managed resources")
+	}
+}
+
+// Export enumerates the Tunnels and ClusterTunnels with their TunnelBindings, ingress rules and managed DNS records.
+// Errors reading a single tunnel are reported in its Errors instead of failing the whole export.
+func (e *ManagedExport) Export(ctx context.Context) ([]ExportedTunnel, error) {
+	tunnels := []Tunnel{}
+
+	tunnelList := &networkingv1alpha1.TunnelList{}
+	if err := e.List(ctx, tunnelList); err != nil {
+		return nil, fmt.Errorf("failed to list Tunnels: %w", err)
+	}
+	for i := range tunnelList.Items {
+		tunnels = append(tunnels, TunnelAdapter{&tunnelList.Items[i]})
+	}
+
+	clusterTunnelList := &networkingv1alpha1.ClusterTunnelList{}
+	if err := e.List(ctx, clusterTunnelList); err != nil {
+		return nil, fmt.Errorf("failed to list ClusterTunnels: %w", err)
+	}
+	for i := range clusterTunnelList.Items {
+		tunnels = append(tunnels, ClusterTunnelAdapter{&clusterTunnelList.Items[i], e.Namespace})
+	}
+
+	bindingList := &networkingv1alpha1.TunnelBindingList{}
+	if err := e.List(ctx, bindingList); err != nil {
+		return nil, fmt.Errorf("failed to list TunnelBindings: %w", err)
+	}
+	bindings := map[tunnelKey][]ExportedBinding{}
+	for _, binding := range bindingList.Items {
+		key := tunnelKeyForBinding(&binding, e.Namespace)
+		bindings[key] = append(bindings[key], ExportedBinding{
+			Namespace: binding.Namespace,
+			Name:      binding.Name,
+			Services:  binding.Status.Services,
+		})
+	}
+
+	exported := make([]ExportedTunnel, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		key := tunnelKeyForTunnel(tunnel)
+		exportedTunnel := ExportedTunnel{
+			Kind:      key.Kind,
+			Namespace: tunnel.GetNamespace(),
+			Name:      tunnel.GetName(),
+			TunnelId:  tunnel.GetStatus().TunnelId,
+			Domain:    tunnel.GetSpec().Cloudflare.Domain,
+			Bindings:  bindings[key],
+		}
+
+		ingress, err := e.exportIngress(ctx, tunnel)
+		if err != nil {
+			exportedTunnel.Errors = append(exportedTunnel.Errors, err.Error())
+		}
+		exportedTunnel.Ingress = ingress
+
+		dnsRecords, err := e.exportDnsRecords(ctx, tunnel)
+		if err != nil {
+			exportedTunnel.Errors = append(exportedTunnel.Errors, err.Error())
+		}
+		exportedTunnel.DnsRecords = dnsRecords
+
+		exported = append(exported, exportedTunnel)
+	}
+	return exported, nil
+}
+
+// tunnelConfigHandler serves the configuration of a tunnel as rendered in its ConfigMap, for GitOps pipelines to diff it
+// against the expected one. Only the ConfigMaps of Tunnels and ClusterTunnels are served.
+type tunnelConfigHandler struct {
+	*ManagedExport
+}
+
+// ServeHTTP writes the config.yaml of the tunnel in the path
+func (h tunnelConfigHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "use GET to read a tunnel configuration", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, TunnelConfigPath), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] != "config" {
+		http.Error(w, "expected "+TunnelConfigPath+"<namespace>/<name>/config", http.StatusNotFound)
+		return
+	}
+
+	tunnel, err := h.getTunnel(req.Context(), parts[0], parts[1])
+	if apierrors.IsNotFound(err) {
+		http.Error(w, fmt.Sprintf("no Tunnel or ClusterTunnel %s/%s", parts[0], parts[1]), http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.log.Error(err, "failed to get tunnel", "namespace", parts[0], "name", parts[1])
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	configmap, err := h.getConfigMap(req.Context(), tunnel)
+	if err != nil {
+		h.log.Error(err, "failed to get tunnel configuration", "namespace", parts[0], "name", parts[1])
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	if _, err := w.Write([]byte(configmap.Data[configmapKey])); err != nil {
+		h.log.Error(err, "failed to write tunnel configuration")
+	}
+}
+
+// getTunnel returns the Tunnel, or the ClusterTunnel if the namespace is the cluster resource namespace
+func (e *ManagedExport) getTunnel(ctx context.Context, namespace, name string) (Tunnel, error) {
+	tunnel := &networkingv1alpha1.Tunnel{}
+	err := e.Get(ctx, apitypes.NamespacedName{Name: name, Namespace: namespace}, tunnel)
+	if err == nil {
+		return TunnelAdapter{tunnel}, nil
+	}
+	if !apierrors.IsNotFound(err) || namespace != e.Namespace {
+		return nil, err
+	}
+	clusterTunnel := &networkingv1alpha1.ClusterTunnel{}
+	if err := e.Get(ctx, apitypes.NamespacedName{Name: name}, clusterTunnel); err != nil {
+		return nil, err
+	}
+	return ClusterTunnelAdapter{clusterTunnel, e.Namespace}, nil
+}
+
+// getConfigMap returns the ConfigMap holding the configuration of the tunnel
+func (e *ManagedExport) getConfigMap(ctx context.Context, tunnel Tunnel) (*corev1.ConfigMap, error) {
+	configmap := &corev1.ConfigMap{}
+	if err := e.Get(ctx, apitypes.NamespacedName{Name: tunnel.GetName(), Namespace: tunnel.GetNamespace()}, configmap); err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", tunnel.GetNamespace(), tunnel.GetName(), err)
+	}
+	return configmap, nil
+}
+
+// exportIngress returns the ingress rules rendered in the ConfigMap of the tunnel
+func (e *ManagedExport) exportIngress(ctx context.Context, tunnel Tunnel) ([]UnvalidatedIngressRule, error) {
+	configmap, err := e.getConfigMap(ctx, tunnel)
+	if err != nil {
+		return nil, err
+	}
+	config := &Configuration{}
+	if err := yaml.Unmarshal([]byte(configmap.Data[configmapKey]), config); err != nil {
+		return nil, fmt.Errorf("failed to parse ConfigMap %s/%s as YAML: %w", tunnel.GetNamespace(), tunnel.GetName(), err)
+	}
+	return config.Ingress, nil
+}
+
+// exportDnsRecords returns the DNS records managed on Cloudflare for the tunnel
+func (e *ManagedExport) exportDnsRecords(ctx context.Context, tunnel Tunnel) ([]ExportedDnsRecord, error) {
+	cfAPI, _, err := getAPIDetails(ctx, credentialProviderOrDefault(e.Credentials, e.Client), e.log, tunnel.GetSpec(), tunnel.GetStatus(), tunnel.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	records, err := cfAPI.ListManagedDnsRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	exported := make([]ExportedDnsRecord, 0, len(records))
+	for _, record := range records {
+		exported = append(exported, ExportedDnsRecord{
+			Id:      record.ID,
+			Type:    record.Type,
+			Name:    record.Name,
+			Content: record.Content,
+		})
+	}
+	return exported, nil
+}
+
+// SetupWithManager serves the export and the tunnel configurations on the metrics server of the Manager.
+func (e *ManagedExport) SetupWithManager(mgr ctrl.Manager) error {
+	e.log = ctrl.Log.WithName("export")
+	if err := mgr.AddMetricsExtraHandler(ExportPath, e); err != nil {
+		return err
+	}
+	return mgr.AddMetricsExtraHandler(TunnelConfigPath, tunnelConfigHandler{e})
+}
+
+// tunnelKeyForTunnel returns the key of the Tunnel or ClusterTunnel
+func tunnelKeyForTunnel(tunnel Tunnel) tunnelKey {
+	key := tunnelKey{Kind: "tunnel", Namespace: tunnel.GetNamespace(), Name: tunnel.GetName()}
+	if _, ok := tunnel.(ClusterTunnelAdapter); ok {
+		key.Kind = "clustertunnel"
+	}
+	return key
+}
+
+// tunnelKeyForBinding returns the key of the Tunnel or ClusterTunnel referenced by the TunnelBinding,
+// ClusterTunnels having their resources in the cluster resource namespace
+func tunnelKeyForBinding(binding *networkingv1alpha1.TunnelBinding, clusterResourceNamespace string) tunnelKey {
+	key := tunnelKey{Kind: strings.ToLower(binding.TunnelRef.Kind), Namespace: binding.Namespace, Name: binding.TunnelRef.Name}
+	if key.Kind == "clustertunnel" {
+		key.Namespace = clusterResourceNamespace
+	}
+	return key
+}
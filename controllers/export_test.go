@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTunnelConfigHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := networkingv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	const clusterResourceNamespace = "cloudflare-operator-system"
+	objects := []runtime.Object{
+		&networkingv1alpha1.Tunnel{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web"}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web"},
+			Data:       map[string]string{configmapKey: "tunnel: web-id\n"},
+		},
+		&networkingv1alpha1.ClusterTunnel{ObjectMeta: metav1.ObjectMeta{Name: "shared"}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: clusterResourceNamespace, Name: "shared"},
+			Data:       map[string]string{configmapKey: "tunnel: shared-id\n"},
+		},
+		// A ConfigMap without a tunnel is not served
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "other"},
+			Data:       map[string]string{configmapKey: "secret: value\n"},
+		},
+	}
+	handler := tunnelConfigHandler{&ManagedExport{
+		Client:    fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build(),
+		Namespace: clusterResourceNamespace,
+		log:       ctrl.Log.WithName("export"),
+	}}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "tunnel", path: "/tunnels/apps/web/config", wantStatus: http.StatusOK, wantBody: "tunnel: web-id\n"},
+		{name: "cluster tunnel", path: "/tunnels/" + clusterResourceNamespace + "/shared/config", wantStatus: http.StatusOK, wantBody: "tunnel: shared-id\n"},
+		{name: "cluster tunnel outside the cluster resource namespace", path: "/tunnels/apps/shared/config", wantStatus: http.StatusNotFound},
+		{name: "not a tunnel", path: "/tunnels/apps/other/config", wantStatus: http.StatusNotFound},
+		{name: "missing name", path: "/tunnels/apps//config", wantStatus: http.StatusNotFound},
+		{name: "missing config suffix", path: "/tunnels/apps/web", wantStatus: http.StatusNotFound},
+		{name: "extra segment", path: "/tunnels/apps/web/config/extra", wantStatus: http.StatusNotFound},
+		{name: "other path", path: "/tunnels/apps/web/secret", wantStatus: http.StatusNotFound},
+		{name: "not a read", method: http.MethodPost, path: "/tunnels/apps/web/config", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(method, tt.path, nil))
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if tt.wantBody != "" && recorder.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", recorder.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
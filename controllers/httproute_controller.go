@@ -0,0 +1,352 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var (
+	// Gateway API HTTPRoutes are read as unstructured objects, the CRDs are only required when enabled
+	httpRouteGVK     = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+	httpRouteListGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRouteList"}
+)
+
+// httpRouteSpec is the subset of the Gateway API HTTPRoute spec used to generate ingress rules
+type httpRouteSpec struct {
+	ParentRefs []httpRouteParentRef `json:"parentRefs,omitempty"`
+	Hostnames  []string             `json:"hostnames,omitempty"`
+	Rules      []httpRouteRule      `json:"rules,omitempty"`
+}
+
+type httpRouteParentRef struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+type httpRouteRule struct {
+	Matches     []httpRouteMatch      `json:"matches,omitempty"`
+	BackendRefs []httpRouteBackendRef `json:"backendRefs,omitempty"`
+}
+
+type httpRouteMatch struct {
+	Path *httpRoutePathMatch `json:"path,omitempty"`
+}
+
+type httpRoutePathMatch struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+type httpRouteBackendRef struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Port      *int32 `json:"port,omitempty"`
+}
+
+// HTTPRouteReconciler configures the ingress rules of Gateway API HTTPRoutes whose parentRefs point to a Tunnel
+// or ClusterTunnel. Reconcile requests are per tunnel, ClusterTunnels having no namespace, and the configuration
+// is written through the TunnelBinding reconciler, which includes the HTTPRoutes of the tunnel.
+type HTTPRouteReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+	// Bindings is the TunnelBinding reconciler used to write the tunnel configuration
+	Bindings *TunnelBindingReconciler
+}
+
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnels,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=clustertunnels,verbs=get;list;watch
+
+// Reconcile regenerates the configuration of the tunnel in the request
+func (r *HTTPRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := drainContext(ctx, r.Bindings.DrainTimeout)
+	defer cancel()
+	log := ctrllog.FromContext(ctx)
+
+	var tunnel client.Object
+	tunnelRef := networkingv1alpha1.TunnelRef{Name: req.Name}
+	if req.Namespace == "" {
+		tunnelRef.Kind = "ClusterTunnel"
+		tunnel = &networkingv1alpha1.ClusterTunnel{}
+	} else {
+		tunnelRef.Kind = "Tunnel"
+		tunnel = &networkingv1alpha1.Tunnel{}
+	}
+	if err := r.Get(ctx, req.NamespacedName, tunnel); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("Tunnel of HTTPRoute not found, nothing to do")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "unable to fetch tunnel of HTTPRoute")
+		return ctrl.Result{}, fmt.Errorf("failed to fetch %s %s: %w", tunnelRef.Kind, req.NamespacedName, err)
+	}
+
+	// Write the configuration like an unnamed TunnelBinding without subjects would, reporting Events on the tunnel
+	binding := &networkingv1alpha1.TunnelBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: networkingv1alpha1.GroupVersion.String(), Kind: "TunnelBinding"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: req.Namespace},
+		TunnelRef:  tunnelRef,
+	}
+	bindings := *r.Bindings
+	bindings.log = log.WithValues("tunnel", tunnelRef.Name, "tunnelKind", tunnelRef.Kind)
+	bindings.Recorder = tunnelEventRecorder{EventRecorder: r.Recorder, tunnel: tunnel}
+	if err := bindings.initStruct(ctx, binding); errors.Is(err, errConfigMapNotFound) {
+		// Requeue with backoff without reporting an error
+		return ctrl.Result{Requeue: true}, nil
+	} else if err != nil {
+		log.Error(err, "initialization failed")
+		return ctrl.Result{}, err
+	}
+	if err := bindings.configureCloudflareDaemon(); err != nil {
+		log.Error(err, "unable to configure HTTPRoutes")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// tunnelEventRecorder records the Events of the configuration on the tunnel instead of the object they are emitted for
+type tunnelEventRecorder struct {
+	record.EventRecorder
+	tunnel runtime.Object
+}
+
+func (t tunnelEventRecorder) Event(_ runtime.Object, eventtype, reason, message string) {
+	t.EventRecorder.Event(t.tunnel, eventtype, reason, message)
+}
+
+func (t tunnelEventRecorder) Eventf(_ runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	t.EventRecorder.Eventf(t.tunnel, eventtype, reason, messageFmt, args...)
+}
+
+func (t tunnelEventRecorder) AnnotatedEventf(_ runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	t.EventRecorder.AnnotatedEventf(t.tunnel, annotations, eventtype, reason, messageFmt, args...)
+}
+
+// parseHTTPRoute reads the spec of the unstructured HTTPRoute
+func parseHTTPRoute(route *unstructured.Unstructured) (httpRouteSpec, error) {
+	spec := httpRouteSpec{}
+	specMap, _, err := unstructured.NestedMap(route.Object, "spec")
+	if err != nil {
+		return spec, fmt.Errorf("failed to read spec of HTTPRoute %s/%s: %w", route.GetNamespace(), route.GetName(), err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specMap, &spec); err != nil {
+		return spec, fmt.Errorf("failed to parse spec of HTTPRoute %s/%s: %w", route.GetNamespace(), route.GetName(), err)
+	}
+	return spec, nil
+}
+
+// httpRouteTunnelKeys returns the keys of the Tunnels and ClusterTunnels in the parentRefs of the HTTPRoute.
+// Tunnels in another namespace than the HTTPRoute are ignored, so that routes cannot add ingress rules to them.
+func httpRouteTunnelKeys(route *unstructured.Unstructured, spec httpRouteSpec, clusterResourceNamespace string) []tunnelKey {
+	var keys []tunnelKey
+	for _, parentRef := range spec.ParentRefs {
+		if parentRef.Group != networkingv1alpha1.GroupVersion.Group {
+			continue
+		}
+		switch strings.ToLower(parentRef.Kind) {
+		case "tunnel":
+			if parentRef.Namespace != "" && parentRef.Namespace != route.GetNamespace() {
+				continue
+			}
+			keys = append(keys, tunnelKey{Kind: "tunnel", Namespace: route.GetNamespace(), Name: parentRef.Name})
+		case "clustertunnel":
+			keys = append(keys, tunnelKey{Kind: "clustertunnel", Namespace: clusterResourceNamespace, Name: parentRef.Name})
+		}
+	}
+	return keys
+}
+
+// tunnelsForHTTPRoute returns reconcile requests for the tunnels in the parentRefs of the HTTPRoute.
+// This is called with both the old and new HTTPRoute on updates, so that removed parentRefs are reconfigured too.
+func (r *HTTPRouteReconciler) tunnelsForHTTPRoute(obj client.Object) []reconcile.Request {
+	route, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	spec, err := parseHTTPRoute(route)
+	if err != nil {
+		ctrllog.Log.Error(err, "unable to parse HTTPRoute")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, key := range httpRouteTunnelKeys(route, spec, r.Bindings.Namespace) {
+		// ClusterTunnels are cluster scoped
+		namespace := key.Namespace
+		if key.Kind == "clustertunnel" {
+			namespace = ""
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: apitypes.NamespacedName{Namespace: namespace, Name: key.Name}})
+	}
+	return requests
+}
+
+// getHTTPRouteIngresses returns the ingress rules of the HTTPRoutes of the tunnel, sorted by HTTPRoute for idempotent config generation
+func (r *TunnelBindingReconciler) getHTTPRouteIngresses() ([]UnvalidatedIngressRule, error) {
+	routeList := &unstructured.UnstructuredList{}
+	routeList.SetGroupVersionKind(httpRouteListGVK)
+	if err := r.List(r.ctx, routeList); err != nil {
+		r.log.Error(err, "failed to list HTTPRoutes")
+		return nil, fmt.Errorf("failed to list HTTPRoutes: %w", err)
+	}
+	routes := routeList.Items
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].GetNamespace() != routes[j].GetNamespace() {
+			return routes[i].GetNamespace() < routes[j].GetNamespace()
+		}
+		return routes[i].GetName() < routes[j].GetName()
+	})
+
+	key := tunnelKeyForBinding(r.binding, r.Namespace)
+	var ingresses []UnvalidatedIngressRule
+	for i := range routes {
+		route := &routes[i]
+		spec, err := parseHTTPRoute(route)
+		if err != nil {
+			r.log.Error(err, "unable to parse HTTPRoute, skipping", "httproute", route.GetName(), "namespace", route.GetNamespace())
+			continue
+		}
+		for _, routeKey := range httpRouteTunnelKeys(route, spec, r.Namespace) {
+			if routeKey == key {
+				ingresses = append(ingresses, r.ingressesForHTTPRoute(route, spec)...)
+				break
+			}
+		}
+	}
+	return ingresses, nil
+}
+
+// ingressesForHTTPRoute returns an ingress rule per hostname and path match of each HTTPRoute rule,
+// proxying to the first Service backendRef of the rule in the namespace of the HTTPRoute
+func (r *TunnelBindingReconciler) ingressesForHTTPRoute(route *unstructured.Unstructured, spec httpRouteSpec) []UnvalidatedIngressRule {
+	log := r.log.WithValues("httproute", route.GetName(), "namespace", route.GetNamespace())
+	if len(spec.Hostnames) == 0 {
+		log.Info("HTTPRoute has no hostnames, skipping as cloudflared rules need a hostname")
+		return nil
+	}
+
+	var ingresses []UnvalidatedIngressRule
+	for i, rule := range spec.Rules {
+		target, err := httpRouteTarget(r.tunnel, route.GetNamespace(), rule.BackendRefs)
+		if err != nil {
+			log.Info("Skipping HTTPRoute rule", "rule", i, "reason", err.Error())
+			continue
+		}
+		if len(rule.BackendRefs) > 1 {
+			log.Info("Multiple backendRefs are not supported, using the first Service", "rule", i, "target", target)
+		}
+
+		matches := rule.Matches
+		if len(matches) == 0 {
+			matches = []httpRouteMatch{{}}
+		}
+		for _, match := range matches {
+			path, err := httpRoutePath(match.Path)
+			if err != nil {
+				log.Info("Skipping HTTPRoute match", "rule", i, "reason", err.Error())
+				continue
+			}
+			for _, hostname := range spec.Hostnames {
+				ingresses = append(ingresses, UnvalidatedIngressRule{
+					Hostname:  hostname,
+					Path:      path,
+					Service:   target,
+					ManagedBy: fmt.Sprintf("httproute %s/%s", route.GetNamespace(), route.GetName()),
+				})
+			}
+		}
+	}
+	return ingresses
+}
+
+// httpRouteTarget returns the cloudflared service of the first Service backendRef, which must be in the HTTPRoute namespace
+func httpRouteTarget(tunnel Tunnel, namespace string, backendRefs []httpRouteBackendRef) (string, error) {
+	for _, backendRef := range backendRefs {
+		if (backendRef.Group != "" && backendRef.Group != "core") || (backendRef.Kind != "" && backendRef.Kind != "Service") {
+			continue
+		}
+		// Cross namespace references would need ReferenceGrants, which are not supported
+		if backendRef.Namespace != "" && backendRef.Namespace != namespace {
+			return "", fmt.Errorf("backendRef %s/%s is in another namespace", backendRef.Namespace, backendRef.Name)
+		}
+		if backendRef.Port == nil {
+			return "", fmt.Errorf("backendRef %s has no port", backendRef.Name)
+		}
+		target := fmt.Sprintf("%s://%s:%d", tunnelProtoHTTP, serviceHost(tunnel, backendRef.Name, namespace), *backendRef.Port)
+		if err := validateServiceURL(target); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+	return "", fmt.Errorf("no Service backendRef")
+}
+
+// httpRoutePath returns the cloudflared path regular expression of the HTTPRoute path match
+func httpRoutePath(match *httpRoutePathMatch) (string, error) {
+	if match == nil {
+		return "", nil
+	}
+	switch match.Type {
+	case "", "PathPrefix":
+		prefix := strings.TrimSuffix(match.Value, "/")
+		if prefix == "" {
+			return "", nil
+		}
+		// Prefixes match on path elements, /foo matches /foo and /foo/bar but not /foobar
+		return "^" + regexp.QuoteMeta(prefix) + "(/|$)", nil
+	case "Exact":
+		return "^" + regexp.QuoteMeta(match.Value) + "$", nil
+	case "RegularExpression":
+		if err := validatePath(match.Value); err != nil {
+			return "", err
+		}
+		return match.Value, nil
+	}
+	return "", fmt.Errorf("unsupported path match type %s", match.Type)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// The Gateway API HTTPRoute CRD must be installed.
+func (r *HTTPRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("cloudflare-operator")
+	c, err := controller.New("httproute", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+	if err := c.Watch(&source.Kind{Type: route}, handler.EnqueueRequestsFromMapFunc(r.tunnelsForHTTPRoute)); err != nil {
+		return err
+	}
+
+	// Configure HTTPRoutes created before their tunnel, requests of ClusterTunnels having no namespace already
+	if err := c.Watch(&source.Kind{Type: &networkingv1alpha1.Tunnel{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{}); err != nil {
+		return err
+	}
+	return c.Watch(&source.Kind{Type: &networkingv1alpha1.ClusterTunnel{}}, &handler.EnqueueRequestForObject{}, predicate.GenerationChangedPredicate{})
+}
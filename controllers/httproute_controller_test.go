@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestHTTPRouteTunnelKeys(t *testing.T) {
+	route := &unstructured.Unstructured{}
+	route.SetNamespace("apps")
+	route.SetName("web")
+	group := networkingv1alpha1.GroupVersion.Group
+	spec := httpRouteSpec{ParentRefs: []httpRouteParentRef{
+		{Group: group, Kind: "Tunnel", Name: "same"},
+		{Group: group, Kind: "Tunnel", Namespace: "apps", Name: "explicit"},
+		{Group: group, Kind: "Tunnel", Namespace: "other", Name: "cross-namespace"},
+		{Group: group, Kind: "ClusterTunnel", Name: "cluster"},
+		{Group: "gateway.networking.k8s.io", Kind: "Gateway", Name: "gateway"},
+	}}
+
+	got := httpRouteTunnelKeys(route, spec, "cloudflare-operator-system")
+	want := []tunnelKey{
+		{Kind: "tunnel", Namespace: "apps", Name: "same"},
+		{Kind: "tunnel", Namespace: "apps", Name: "explicit"},
+		{Kind: "clustertunnel", Namespace: "cloudflare-operator-system", Name: "cluster"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("httpRouteTunnelKeys() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// https://github.com/cloudflare/cloudflared/blob/master/ingress/ingress.go
+// validateIngress checks the ingress rules the way cloudflared does when loading its configuration,
+// cloudflared refuses to start on the first error, taking down all the services of the tunnel.
+// requireCatchAll is false when the catch-all rule is managed outside the operator.
+func validateIngress(rules []UnvalidatedIngressRule, requireCatchAll bool) error {
+	for i, rule := range rules {
+		if err := validateIngressHostname(rule.Hostname); err != nil {
+			return fmt.Errorf("rule #%d: %w", i+1, err)
+		}
+		if rule.Path != "" {
+			if _, err := regexp.Compile(rule.Path); err != nil {
+				return fmt.Errorf("rule #%d: path %s is not a valid regular expression: %w", i+1, rule.Path, err)
+			}
+		}
+		if err := validateIngressService(rule); err != nil {
+			return fmt.Errorf("rule #%d: %w", i+1, err)
+		}
+
+		matchesAll := (rule.Hostname == "" || rule.Hostname == "*") && rule.Path == ""
+		if matchesAll && i < len(rules)-1 {
+			return fmt.Errorf("rule #%d matches all requests but is not the last rule, the rules after it are unreachable", i+1)
+		}
+		if !matchesAll && i == len(rules)-1 && requireCatchAll {
+			return fmt.Errorf("the last rule #%d must match all requests, without hostname and path", i+1)
+		}
+	}
+	return nil
+}
+
+// validateIngressHostname checks the hostname has no port and a wildcard only as its first label
+func validateIngressHostname(hostname string) error {
+	if strings.Contains(hostname, ":") {
+		return fmt.Errorf("hostname %s cannot contain a port", hostname)
+	}
+	if strings.LastIndex(hostname, "*") > 0 {
+		return fmt.Errorf("hostname %s can only have a wildcard at the start", hostname)
+	}
+	return nil
+}
+
+// validateIngressService checks the service is one of the built-in cloudflared services, a unix socket or an origin URL
+func validateIngressService(rule UnvalidatedIngressRule) error {
+	service := rule.Service
+	switch {
+	case service == "":
+		return fmt.Errorf("rule for hostname %s has no service", rule.Hostname)
+	case service == "hello_world" || service == "hello-world" || service == "socks5" || service == bastionService:
+		return nil
+	case rule.OriginRequest.BastionMode != nil && *rule.OriginRequest.BastionMode:
+		return nil
+	case strings.HasPrefix(service, "http_status:"):
+		code, err := strconv.Atoi(strings.TrimPrefix(service, "http_status:"))
+		if err != nil || code < 100 || code > 999 {
+			return fmt.Errorf("service %s has an invalid HTTP status code", service)
+		}
+		return nil
+	case strings.HasPrefix(service, unixSocketPrefix) || strings.HasPrefix(service, "unix+tls:"):
+		if strings.TrimPrefix(strings.TrimPrefix(service, unixSocketPrefix), "unix+tls:") == "" {
+			return fmt.Errorf("service %s has no socket path", service)
+		}
+		return nil
+	}
+
+	parsed, err := url.Parse(service)
+	if err != nil {
+		return fmt.Errorf("service %s is not a valid URL: %w", service, err)
+	}
+	if parsed.Scheme == "" || parsed.Hostname() == "" {
+		return fmt.Errorf("service %s must have a scheme and a host", service)
+	}
+	if parsed.Path != "" && parsed.Path != "/" {
+		return fmt.Errorf("service %s cannot have a path, cloudflared does not proxy to a different path on the origin", service)
+	}
+	if port := parsed.Port(); port != "" {
+		if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+			return fmt.Errorf("service %s has an invalid port %s", service, port)
+		}
+	}
+	return nil
+}
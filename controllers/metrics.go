@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// tunnelOriginRequests is the total number of requests proxied to origins, as reported by cloudflared
+	tunnelOriginRequests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cloudflare_operator_tunnel_origin_requests",
+		Help: "Total number of requests cloudflared proxied to origins, summed over the tunnel pods",
+	}, []string{"kind", "namespace", "tunnel"})
+
+	// tunnelOriginRequestErrors is the total number of failed requests to origins, as reported by cloudflared
+	tunnelOriginRequestErrors = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cloudflare_operator_tunnel_origin_request_errors",
+		Help: "Total number of requests cloudflared failed to proxy to origins, summed over the tunnel pods",
+	}, []string{"kind", "namespace", "tunnel"})
+
+	// tunnelManagedServices is the number of TunnelBinding subjects configured on the tunnel
+	tunnelManagedServices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cloudflare_operator_managed_services",
+		Help: "Number of TunnelBinding subjects configured on the tunnel",
+	}, []string{"kind", "namespace", "tunnel"})
+
+	// tunnelIngressRules is the number of ingress rules in the tunnel configuration, including the catch-all
+	tunnelIngressRules = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cloudflare_operator_tunnel_ingress_rules",
+		Help: "Number of ingress rules in the tunnel configuration, including the catch-all rule",
+	}, []string{"kind", "namespace", "tunnel"})
+
+	// tunnelCircuitOpen is set while the circuit breaker of the tunnel backs off its Cloudflare API operations
+	tunnelCircuitOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cloudflare_operator_tunnel_circuit_open",
+		Help: "Whether the circuit breaker of the tunnel is open after consecutive Cloudflare API failures, 1 if open",
+	}, []string{"kind", "namespace", "tunnel"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(tunnelOriginRequests, tunnelOriginRequestErrors, tunnelManagedServices, tunnelIngressRules, tunnelCircuitOpen)
+}
+
+// deleteTunnelMetrics removes the series of a deleted tunnel, which would otherwise keep reporting its last configuration
+func deleteTunnelMetrics(key tunnelKey) {
+	tunnelManagedServices.DeleteLabelValues(key.Kind, key.Namespace, key.Name)
+	tunnelIngressRules.DeleteLabelValues(key.Kind, key.Namespace, key.Name)
+}
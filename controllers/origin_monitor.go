@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// cloudflared metrics used to detect origin errors
+	cloudflaredRequestsMetric      = "cloudflared_tunnel_total_requests"
+	cloudflaredRequestErrorsMetric = "cloudflared_tunnel_request_errors"
+)
+
+// tunnelKey identifies a Tunnel or ClusterTunnel, with the namespace its resources live in
+type tunnelKey struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// String returns the key as kind/namespace/name
+func (k tunnelKey) String() string {
+	return k.Kind + "/" + k.Namespace + "/" + k.Name
+}
+
+// OriginMonitor periodically scrapes the cloudflared metrics endpoint of every tunnel in use
+// and emits a Warning Event on its TunnelBindings when cloudflared reports new origin request errors.
+// cloudflared does not break these metrics down by hostname, so all TunnelBindings of the tunnel are notified.
+type OriginMonitor struct {
+	client.Client
+	Recorder  record.EventRecorder
+	Namespace string
+	Interval  time.Duration
+
+	log        logr.Logger
+	httpClient *http.Client
+	lastErrors map[tunnelKey]float64
+}
+
+//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnelbindings,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Start runs the monitor until the context is cancelled
+func (m *OriginMonitor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection makes sure only the leader emits Events
+func (m *OriginMonitor) NeedLeaderElection() bool {
+	return true
+}
+
+func (m *OriginMonitor) check(ctx context.Context) {
+	tunnelBindingList := &networkingv1alpha1.TunnelBindingList{}
+	if err := m.List(ctx, tunnelBindingList); err != nil {
+		m.log.Error(err, "failed to list TunnelBindings")
+		return
+	}
+
+	bindings := map[tunnelKey][]*networkingv1alpha1.TunnelBinding{}
+	for i := range tunnelBindingList.Items {
+		binding := &tunnelBindingList.Items[i]
+		key := tunnelKeyForBinding(binding, m.Namespace)
+		bindings[key] = append(bindings[key], binding)
+	}
+
+	// Forget tunnels that are not in use anymore
+	for key := range m.lastErrors {
+		if _, ok := bindings[key]; !ok {
+			delete(m.lastErrors, key)
+			tunnelOriginRequests.DeleteLabelValues(key.Kind, key.Namespace, key.Name)
+			tunnelOriginRequestErrors.DeleteLabelValues(key.Kind, key.Namespace, key.Name)
+		}
+	}
+
+	for key, tunnelBindings := range bindings {
+		requests, errors, err := m.scrapeTunnel(ctx, key)
+		if err != nil {
+			m.log.Error(err, "failed to scrape cloudflared metrics", "tunnel", key.Name, "namespace", key.Namespace)
+			continue
+		}
+		tunnelOriginRequests.WithLabelValues(key.Kind, key.Namespace, key.Name).Set(requests)
+		tunnelOriginRequestErrors.WithLabelValues(key.Kind, key.Namespace, key.Name).Set(errors)
+
+		last, seen := m.lastErrors[key]
+		m.lastErrors[key] = errors
+		// Counters reset when the pods restart, only report an increase
+		if !seen || errors <= last {
+			continue
+		}
+		m.log.Info("cloudflared reported origin errors", "tunnel", key.Name, "namespace", key.Namespace, "errors", errors-last)
+		for _, binding := range tunnelBindings {
+			m.Recorder.Event(binding, corev1.EventTypeWarning, "OriginErrors",
+				fmt.Sprintf("cloudflared reported %d new origin request errors, origin might be unreachable", int64(errors-last)))
+		}
+	}
+}
+
+// scrapeTunnel returns the total requests and request errors summed over the ready cloudflared pods of the tunnel
+func (m *OriginMonitor) scrapeTunnel(ctx context.Context, key tunnelKey) (float64, float64, error) {
+	pods := &corev1.PodList{}
+	listOpts := []client.ListOption{client.InNamespace(key.Namespace), client.MatchingLabels(map[string]string{
+		tunnelLabel:    key.Name,
+		tunnelAppLabel: "cloudflared",
+	})}
+	if err := m.List(ctx, pods, listOpts...); err != nil {
+		return 0, 0, fmt.Errorf("failed to list pods of tunnel %s/%s: %w", key.Namespace, key.Name, err)
+	}
+
+	var requests, errors float64
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		podRequests, podErrors, err := m.scrapePod(ctx, pod.Status.PodIP)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to scrape pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		requests += podRequests
+		errors += podErrors
+	}
+	return requests, errors, nil
+}
+
+func (m *OriginMonitor) scrapePod(ctx context.Context, podIP string) (float64, float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s:2000/metrics", podIP), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	sum := func(name string) float64 {
+		var total float64
+		if family, ok := families[name]; ok {
+			for _, metric := range family.GetMetric() {
+				total += metric.GetCounter().GetValue()
+			}
+		}
+		return total
+	}
+	return sum(cloudflaredRequestsMetric), sum(cloudflaredRequestErrorsMetric), nil
+}
+
+// SetupWithManager registers the monitor with the Manager.
+func (m *OriginMonitor) SetupWithManager(mgr ctrl.Manager) error {
+	m.Recorder = mgr.GetEventRecorderFor("cloudflare-operator")
+	m.log = ctrl.Log.WithName("origin-monitor")
+	m.httpClient = &http.Client{Timeout: 5 * time.Second}
+	m.lastErrors = map[tunnelKey]float64{}
+	return mgr.Add(m)
+}
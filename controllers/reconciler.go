@@ -2,7 +2,11 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
@@ -10,12 +14,15 @@ import (
 	yaml "gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -31,7 +38,7 @@ type GenericTunnelReconciler interface {
 	GetTunnel() Tunnel
 	GetCfAPI() *CloudflareAPI
 	SetCfAPI(*CloudflareAPI)
-	GetCfSecret() *corev1.Secret
+	GetCfCredentials() map[string][]byte
 	GetTunnelCreds() string
 	SetTunnelCreds(string)
 }
@@ -49,10 +56,357 @@ func labelsForTunnel(cf Tunnel) map[string]string {
 	}
 }
 
+// deploymentManaged checks if the operator manages the cloudflared Deployment of the tunnel
+func deploymentManaged(cf Tunnel) bool {
+	manage := cf.GetSpec().ManageDeployment
+	return manage == nil || *manage
+}
+
 func nodeSelectorsForTunnel(cf Tunnel) map[string]string {
 	return cf.GetSpec().NodeSelectors
 }
 
+// topologySpreadConstraintsForTunnel returns the topology spread constraints of the cloudflared pods,
+// selecting the pods of the tunnel when no labelSelector is given
+func topologySpreadConstraintsForTunnel(cf Tunnel) []corev1.TopologySpreadConstraint {
+	spec := cf.GetSpec()
+	podSelector := &metav1.LabelSelector{MatchLabels: map[string]string{
+		tunnelLabel:    cf.GetName(),
+		tunnelAppLabel: "cloudflared",
+	}}
+
+	if spec.SpreadAcrossZones {
+		return []corev1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelTopologyZone,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     podSelector,
+		}, {
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelHostname,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     podSelector,
+		}}
+	}
+
+	var constraints []corev1.TopologySpreadConstraint
+	for _, constraint := range spec.TopologySpreadConstraints {
+		constraint := *constraint.DeepCopy()
+		if constraint.LabelSelector == nil {
+			constraint.LabelSelector = podSelector.DeepCopy()
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints
+}
+
+// resourcesForTunnel returns the resource requirements of the cloudflared container
+func resourcesForTunnel(cf Tunnel) corev1.ResourceRequirements {
+	if resources := cf.GetSpec().Resources; resources != nil {
+		return *resources
+	}
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{"memory": resource.MustParse("30Mi"), "cpu": resource.MustParse("10m")},
+		Limits:   corev1.ResourceList{"memory": resource.MustParse("256Mi"), "cpu": resource.MustParse("500m")},
+	}
+}
+
+// livenessProbeForTunnel returns the liveness probe of the cloudflared container, with the fields the API server defaults set
+func livenessProbeForTunnel(cf Tunnel) *corev1.Probe {
+	if probe := cf.GetSpec().LivenessProbe; probe != nil {
+		return withProbeDefaults(probe.DeepCopy())
+	}
+	return withProbeDefaults(&corev1.Probe{
+		ProbeHandler:        readyProbeHandler(),
+		FailureThreshold:    1,
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       10,
+	})
+}
+
+// readinessProbeForTunnel returns the readiness probe of the cloudflared container, with the fields the API server defaults set
+func readinessProbeForTunnel(cf Tunnel) *corev1.Probe {
+	if probe := cf.GetSpec().ReadinessProbe; probe != nil {
+		return withProbeDefaults(probe.DeepCopy())
+	}
+	return withProbeDefaults(&corev1.Probe{
+		ProbeHandler:  readyProbeHandler(),
+		PeriodSeconds: 5,
+	})
+}
+
+// readyProbeHandler probes the /ready endpoint of cloudflared, answering 200 once it has a connection to the edge
+func readyProbeHandler() corev1.ProbeHandler {
+	return corev1.ProbeHandler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path: "/ready",
+			Port: intstr.IntOrString{IntVal: 2000},
+		},
+	}
+}
+
+// withProbeDefaults sets the fields of the probe the API server defaults, so that the Deployment is only updated on actual changes
+func withProbeDefaults(probe *corev1.Probe) *corev1.Probe {
+	if probe.TimeoutSeconds == 0 {
+		probe.TimeoutSeconds = 1
+	}
+	if probe.PeriodSeconds == 0 {
+		probe.PeriodSeconds = 10
+	}
+	if probe.SuccessThreshold == 0 {
+		probe.SuccessThreshold = 1
+	}
+	if probe.FailureThreshold == 0 {
+		probe.FailureThreshold = 3
+	}
+	if probe.HTTPGet != nil && probe.HTTPGet.Scheme == "" {
+		probe.HTTPGet.Scheme = corev1.URISchemeHTTP
+	}
+	return probe
+}
+
+// podSecurityContextForTunnel returns the security context of the cloudflared pods, empty like the API server defaults it if unset
+func podSecurityContextForTunnel(cf Tunnel) *corev1.PodSecurityContext {
+	if securityContext := cf.GetSpec().PodSecurityContext; securityContext != nil {
+		return securityContext.DeepCopy()
+	}
+	return &corev1.PodSecurityContext{}
+}
+
+// validateSecurityContext rejects security contexts the API server refuses for the pods, or the kubelet refuses to start
+func validateSecurityContext(spec networkingv1alpha1.TunnelSpec) error {
+	if sc := spec.SecurityContext; sc != nil {
+		noEscalation := sc.AllowPrivilegeEscalation != nil && !*sc.AllowPrivilegeEscalation
+		if noEscalation && sc.Privileged != nil && *sc.Privileged {
+			return fmt.Errorf("securityContext: privileged cannot be set with allowPrivilegeEscalation false")
+		}
+		if noEscalation && sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				if strings.TrimPrefix(strings.ToUpper(string(capability)), "CAP_") == "SYS_ADMIN" {
+					return fmt.Errorf("securityContext: capability %s cannot be added with allowPrivilegeEscalation false", capability)
+				}
+			}
+		}
+		if err := validateRunAs("securityContext", sc.RunAsNonRoot, sc.RunAsUser, sc.RunAsGroup); err != nil {
+			return err
+		}
+		if err := validateSeccompProfile("securityContext", sc.SeccompProfile); err != nil {
+			return err
+		}
+	}
+	if sc := spec.PodSecurityContext; sc != nil {
+		if err := validateRunAs("podSecurityContext", sc.RunAsNonRoot, sc.RunAsUser, sc.RunAsGroup); err != nil {
+			return err
+		}
+		if err := validateSeccompProfile("podSecurityContext", sc.SeccompProfile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRunAs rejects negative ids, and running as root along with runAsNonRoot
+func validateRunAs(field string, runAsNonRoot *bool, runAsUser, runAsGroup *int64) error {
+	if runAsUser != nil && *runAsUser < 0 {
+		return fmt.Errorf("%s: runAsUser cannot be negative", field)
+	}
+	if runAsGroup != nil && *runAsGroup < 0 {
+		return fmt.Errorf("%s: runAsGroup cannot be negative", field)
+	}
+	if runAsNonRoot != nil && *runAsNonRoot && runAsUser != nil && *runAsUser == 0 {
+		return fmt.Errorf("%s: runAsUser 0 cannot be set with runAsNonRoot", field)
+	}
+	return nil
+}
+
+// validateSeccompProfile checks a localhost profile is only and always set for the Localhost type
+func validateSeccompProfile(field string, profile *corev1.SeccompProfile) error {
+	if profile == nil {
+		return nil
+	}
+	localhost := profile.LocalhostProfile != nil && *profile.LocalhostProfile != ""
+	if profile.Type == corev1.SeccompProfileTypeLocalhost && !localhost {
+		return fmt.Errorf("%s: seccompProfile of type Localhost needs a localhostProfile", field)
+	}
+	if profile.Type != corev1.SeccompProfileTypeLocalhost && localhost {
+		return fmt.Errorf("%s: localhostProfile can only be set with the Localhost seccompProfile type", field)
+	}
+	return nil
+}
+
+// validateResources rejects resource requests above their limits, which the API server refuses for the pods
+func validateResources(spec networkingv1alpha1.TunnelSpec) error {
+	if spec.Resources == nil {
+		return nil
+	}
+	for name, request := range spec.Resources.Requests {
+		if limit, ok := spec.Resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			return fmt.Errorf("resources: %s request %s is above its limit %s", name, request.String(), limit.String())
+		}
+	}
+	return nil
+}
+
+// validateScheduling rejects nodeSelectors and tolerations the API server refuses for the pods
+func validateScheduling(spec networkingv1alpha1.TunnelSpec) error {
+	for key, value := range spec.NodeSelectors {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("nodeSelectors: invalid key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("nodeSelectors: invalid value %q for %s: %s", value, key, strings.Join(errs, ", "))
+		}
+	}
+	for i, toleration := range spec.Tolerations {
+		if toleration.Key != "" {
+			if errs := validation.IsQualifiedName(toleration.Key); len(errs) > 0 {
+				return fmt.Errorf("tolerations[%d]: invalid key %q: %s", i, toleration.Key, strings.Join(errs, ", "))
+			}
+		}
+		switch toleration.Operator {
+		case corev1.TolerationOpEqual, "":
+			if toleration.Key == "" {
+				return fmt.Errorf("tolerations[%d]: operator must be Exists when the key is empty", i)
+			}
+			if errs := validation.IsValidLabelValue(toleration.Value); len(errs) > 0 {
+				return fmt.Errorf("tolerations[%d]: invalid value %q: %s", i, toleration.Value, strings.Join(errs, ", "))
+			}
+		case corev1.TolerationOpExists:
+			if toleration.Value != "" {
+				return fmt.Errorf("tolerations[%d]: value must be empty when the operator is Exists", i)
+			}
+		default:
+			return fmt.Errorf("tolerations[%d]: unsupported operator %q", i, toleration.Operator)
+		}
+		switch toleration.Effect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("tolerations[%d]: unsupported effect %q", i, toleration.Effect)
+		}
+		if toleration.TolerationSeconds != nil && toleration.Effect != corev1.TaintEffectNoExecute {
+			return fmt.Errorf("tolerations[%d]: tolerationSeconds requires the NoExecute effect", i)
+		}
+	}
+	return nil
+}
+
+// caPoolPathForTunnel returns the default originRequest.caPool of the tunnel, empty if none
+func caPoolPathForTunnel(cf Tunnel) string {
+	spec := cf.GetSpec()
+	if spec.CaPool != nil {
+		return caPoolMountPath + "/" + caPoolFile
+	}
+	if spec.OriginCaPool != "" {
+		return "/etc/cloudflared/certs/tls.crt"
+	}
+	return ""
+}
+
+// caPoolVolumeForTunnel returns the volume of the CA bundle referenced by spec.caPool, nil if unset
+func caPoolVolumeForTunnel(cf Tunnel) *corev1.Volume {
+	caPool := cf.GetSpec().CaPool
+	if caPool == nil {
+		return nil
+	}
+	key := caPool.Key
+	if key == "" {
+		key = caPoolFile
+	}
+	items := []corev1.KeyToPath{{Key: key, Path: caPoolFile}}
+	volume := &corev1.Volume{Name: caPoolVolumeName}
+	if caPool.SecretName != "" {
+		volume.Secret = &corev1.SecretVolumeSource{SecretName: caPool.SecretName, Items: items}
+	} else {
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: caPool.ConfigMapName}, Items: items}
+	}
+	return volume
+}
+
+// sameCaPoolSource checks if both CA bundle volumes mount the same key of the same Secret or ConfigMap,
+// ignoring the fields defaulted by the API server
+func sameCaPoolSource(current, desired *corev1.Volume) bool {
+	if current == nil || desired == nil {
+		return current == desired
+	}
+	source := func(volume *corev1.Volume) (string, []corev1.KeyToPath) {
+		if volume.Secret != nil {
+			return "secret/" + volume.Secret.SecretName, volume.Secret.Items
+		}
+		if volume.ConfigMap != nil {
+			return "configmap/" + volume.ConfigMap.Name, volume.ConfigMap.Items
+		}
+		return "", nil
+	}
+	currentName, currentItems := source(current)
+	desiredName, desiredItems := source(desired)
+	return currentName == desiredName && reflect.DeepEqual(currentItems, desiredItems)
+}
+
+// validateCaPool checks spec.caPool references exactly one existing Secret or ConfigMap with the key
+func validateCaPool(r GenericTunnelReconciler) error {
+	caPool := r.GetTunnel().GetSpec().CaPool
+	if caPool == nil {
+		return nil
+	}
+	if (caPool.SecretName == "") == (caPool.ConfigMapName == "") {
+		return fmt.Errorf("caPool: exactly one of secretName and configMapName must be set")
+	}
+	key := caPool.Key
+	if key == "" {
+		key = caPoolFile
+	}
+
+	namespacedName := apitypes.NamespacedName{Namespace: r.GetTunnel().GetNamespace()}
+	found := false
+	if caPool.SecretName != "" {
+		namespacedName.Name = caPool.SecretName
+		secret := &corev1.Secret{}
+		if err := r.GetClient().Get(r.GetContext(), namespacedName, secret); err != nil {
+			return fmt.Errorf("caPool: failed to get Secret %s: %w", namespacedName, err)
+		}
+		_, found = secret.Data[key]
+	} else {
+		namespacedName.Name = caPool.ConfigMapName
+		configMap := &corev1.ConfigMap{}
+		if err := r.GetClient().Get(r.GetContext(), namespacedName, configMap); err != nil {
+			return fmt.Errorf("caPool: failed to get ConfigMap %s: %w", namespacedName, err)
+		}
+		_, found = configMap.Data[key]
+	}
+	if !found {
+		return fmt.Errorf("caPool: key %s not found in %s", key, namespacedName)
+	}
+	return nil
+}
+
+// validateTopologySpread rejects topology spread settings the scheduler would refuse
+func validateTopologySpread(spec networkingv1alpha1.TunnelSpec) error {
+	if spec.SpreadAcrossZones && len(spec.TopologySpreadConstraints) > 0 {
+		return fmt.Errorf("spreadAcrossZones and topologySpreadConstraints cannot be used together")
+	}
+	seen := map[string]bool{}
+	for i, constraint := range spec.TopologySpreadConstraints {
+		if constraint.MaxSkew < 1 {
+			return fmt.Errorf("topologySpreadConstraints[%d]: maxSkew must be at least 1", i)
+		}
+		if constraint.TopologyKey == "" {
+			return fmt.Errorf("topologySpreadConstraints[%d]: topologyKey is required", i)
+		}
+		if constraint.WhenUnsatisfiable != corev1.DoNotSchedule && constraint.WhenUnsatisfiable != corev1.ScheduleAnyway {
+			return fmt.Errorf("topologySpreadConstraints[%d]: whenUnsatisfiable must be %s or %s", i, corev1.DoNotSchedule, corev1.ScheduleAnyway)
+		}
+		if constraint.MinDomains != nil && (*constraint.MinDomains < 1 || constraint.WhenUnsatisfiable != corev1.DoNotSchedule) {
+			return fmt.Errorf("topologySpreadConstraints[%d]: minDomains must be at least 1 and requires whenUnsatisfiable %s", i, corev1.DoNotSchedule)
+		}
+		key := constraint.TopologyKey + "/" + string(constraint.WhenUnsatisfiable)
+		if seen[key] {
+			return fmt.Errorf("topologySpreadConstraints[%d]: duplicate topologyKey %s with whenUnsatisfiable %s", i, constraint.TopologyKey, constraint.WhenUnsatisfiable)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
 func setupTunnel(r GenericTunnelReconciler) (ctrl.Result, bool, error) {
 	okNewTunnel := r.GetTunnel().GetSpec().NewTunnel != networkingv1alpha1.NewTunnel{}
 	okExistingTunnel := r.GetTunnel().GetSpec().ExistingTunnel != networkingv1alpha1.ExistingTunnel{}
@@ -93,11 +447,11 @@ func setupExistingTunnel(r GenericTunnelReconciler) error {
 	r.SetCfAPI(cfAPI)
 
 	// Read secret for credentials file
-	cfCredFileB64, okCredFile := r.GetCfSecret().Data[r.GetTunnel().GetSpec().Cloudflare.CLOUDFLARE_TUNNEL_CREDENTIAL_FILE]
-	cfSecretB64, okSecret := r.GetCfSecret().Data[r.GetTunnel().GetSpec().Cloudflare.CLOUDFLARE_TUNNEL_CREDENTIAL_SECRET]
+	cfCredFileB64, okCredFile := r.GetCfCredentials()[r.GetTunnel().GetSpec().Cloudflare.CLOUDFLARE_TUNNEL_CREDENTIAL_FILE]
+	cfSecretB64, okSecret := r.GetCfCredentials()[r.GetTunnel().GetSpec().Cloudflare.CLOUDFLARE_TUNNEL_CREDENTIAL_SECRET]
 
 	if !okCredFile && !okSecret {
-		err := fmt.Errorf("neither key not found in secret")
+		err := fmt.Errorf("neither key %s nor %s found in secret %s", r.GetTunnel().GetSpec().Cloudflare.CLOUDFLARE_TUNNEL_CREDENTIAL_FILE, r.GetTunnel().GetSpec().Cloudflare.CLOUDFLARE_TUNNEL_CREDENTIAL_SECRET, r.GetTunnel().GetSpec().Cloudflare.Secret)
 		r.GetLog().Error(err, "neither key not found in secret", "secret", r.GetTunnel().GetSpec().Cloudflare.Secret, "key1", r.GetTunnel().GetSpec().Cloudflare.CLOUDFLARE_TUNNEL_CREDENTIAL_FILE, "key2", r.GetTunnel().GetSpec().Cloudflare.CLOUDFLARE_TUNNEL_CREDENTIAL_SECRET)
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "ErrSpecSecret", "Neither Key found in Secret")
 		return err
@@ -110,7 +464,7 @@ func setupExistingTunnel(r GenericTunnelReconciler) error {
 		if err != nil {
 			r.GetLog().Error(err, "error getting tunnel credentials from secret")
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "ErrSpecApi", "Error in getting Tunnel Credentials from Secret")
-			return err
+			return fmt.Errorf("failed to get credentials for tunnel %s: %w", r.GetTunnel().GetName(), err)
 		}
 		r.SetTunnelCreds(creds)
 	}
@@ -127,7 +481,7 @@ func setupNewTunnel(r GenericTunnelReconciler) error {
 		if err != nil {
 			r.GetLog().Error(err, "unable to create Tunnel")
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedCreate", "Unable to create Tunnel on Cloudflare")
-			return err
+			return fmt.Errorf("failed to create tunnel %s on Cloudflare: %w", r.GetTunnel().GetName(), err)
 		}
 		r.GetLog().Info("Tunnel created on Cloudflare")
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "Created", "Tunnel created successfully on Cloudflare")
@@ -139,7 +493,7 @@ func setupNewTunnel(r GenericTunnelReconciler) error {
 		controllerutil.AddFinalizer(r.GetTunnel().GetObject(), tunnelFinalizer)
 		if err := r.GetClient().Update(r.GetContext(), r.GetTunnel().GetObject()); err != nil {
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "FailedFinalizerSet", "Failed to add Tunnel Finalizer")
-			return err
+			return fmt.Errorf("failed to add finalizer to tunnel %s: %w", r.GetTunnel().GetName(), err)
 		}
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "FinalizerSet", "Tunnel Finalizer added")
 	}
@@ -155,7 +509,10 @@ func cleanupTunnel(r GenericTunnelReconciler) (ctrl.Result, bool, error) {
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "Deleting", "Starting Tunnel Deletion")
 		cfDeployment := &appsv1.Deployment{}
 		var bypass bool
-		if err := r.GetClient().Get(r.GetContext(), apitypes.NamespacedName{Name: r.GetTunnel().GetName(), Namespace: r.GetTunnel().GetNamespace()}, cfDeployment); err != nil {
+		if !deploymentManaged(r.GetTunnel()) {
+			// Stopping cloudflared is up to the controller managing its Deployment
+			bypass = true
+		} else if err := r.GetClient().Get(r.GetContext(), apitypes.NamespacedName{Name: r.GetTunnel().GetName(), Namespace: r.GetTunnel().GetNamespace()}, cfDeployment); err != nil {
 			r.GetLog().Error(err, "Error in getting deployments, might already be deleted?")
 			bypass = true
 		}
@@ -167,7 +524,7 @@ func cleanupTunnel(r GenericTunnelReconciler) (ctrl.Result, bool, error) {
 			if err := r.GetClient().Update(r.GetContext(), cfDeployment); err != nil {
 				r.GetLog().Error(err, "Failed to update Deployment", "Deployment.Namespace", cfDeployment.Namespace, "Deployment.Name", cfDeployment.Name)
 				r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedScaling", "Failed to scale down cloudflared")
-				return ctrl.Result{}, false, err
+				return ctrl.Result{}, false, fmt.Errorf("failed to scale down Deployment %s/%s: %w", cfDeployment.Namespace, cfDeployment.Name, err)
 			}
 			r.GetLog().Info("Scaling down successful", "size", r.GetTunnel().GetSpec().Size)
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "Scaled", "Scaling down cloudflared successful")
@@ -176,7 +533,7 @@ func cleanupTunnel(r GenericTunnelReconciler) (ctrl.Result, bool, error) {
 		if bypass || *cfDeployment.Spec.Replicas == 0 {
 			if err := r.GetCfAPI().DeleteCloudflareTunnel(); err != nil {
 				r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedDeleting", "Tunnel deletion failed")
-				return ctrl.Result{}, false, err
+				return ctrl.Result{}, false, fmt.Errorf("failed to delete tunnel %s on Cloudflare: %w", r.GetTunnel().GetName(), err)
 			}
 			r.GetLog().Info("Tunnel deleted", "tunnelID", r.GetTunnel().GetStatus().TunnelId)
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "Deleted", "Tunnel deletion successful")
@@ -188,15 +545,39 @@ func cleanupTunnel(r GenericTunnelReconciler) (ctrl.Result, bool, error) {
 			if err != nil {
 				r.GetLog().Error(err, "unable to continue with tunnel deletion")
 				r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedFinalizerUnset", "Unable to remove Tunnel Finalizer")
-				return ctrl.Result{}, false, err
+				return ctrl.Result{}, false, fmt.Errorf("failed to remove finalizer from tunnel %s: %w", r.GetTunnel().GetName(), err)
 			}
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "FinalizerUnset", "Tunnel Finalizer removed")
+
+			deleteTunnelMetrics(tunnelKeyForTunnel(r.GetTunnel()))
 			return ctrl.Result{}, true, nil
 		}
 	}
 	return ctrl.Result{}, true, nil
 }
 
+// setAuthFailedCondition sets the Authenticated condition of the tunnel to False with the error.
+// Failing to do so is only logged, the reconcile fails with the original error anyway.
+func setAuthFailedCondition(r GenericTunnelReconciler, reason string, authErr error) {
+	setFailedCondition(r, conditionAuthenticated, reason, authErr)
+}
+
+// setFailedCondition sets the condition of the tunnel to False with the error, only logging failures to do so
+func setFailedCondition(r GenericTunnelReconciler, conditionType string, reason string, condErr error) {
+	status := r.GetTunnel().GetStatus()
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            condErr.Error(),
+		ObservedGeneration: r.GetTunnel().GetObject().GetGeneration(),
+	})
+	r.GetTunnel().SetStatus(status)
+	if err := r.GetClient().Status().Update(r.GetContext(), r.GetTunnel().GetObject()); err != nil {
+		r.GetLog().Error(err, "Failed to set condition", "condition", conditionType, "Tunnel.Namespace", r.GetTunnel().GetNamespace(), "Tunnel.Name", r.GetTunnel().GetName())
+	}
+}
+
 func updateTunnelStatus(r GenericTunnelReconciler) error {
 	labels := r.GetTunnel().GetLabels()
 	if labels == nil {
@@ -207,29 +588,86 @@ func updateTunnelStatus(r GenericTunnelReconciler) error {
 	}
 	r.GetTunnel().SetLabels(labels)
 	if err := r.GetClient().Update(r.GetContext(), r.GetTunnel().GetObject()); err != nil {
-		return err
+		return fmt.Errorf("failed to set labels on tunnel %s: %w", r.GetTunnel().GetName(), err)
 	}
 
-	if err := r.GetCfAPI().ValidateAll(); err != nil {
+	domain := r.GetTunnel().GetSpec().Cloudflare.Domain
+	zoneCondition := metav1.Condition{
+		Type:               conditionZoneValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ZoneFound",
+		Message:            fmt.Sprintf("Domain %s is a zone of the account", domain),
+		ObservedGeneration: r.GetTunnel().GetObject().GetGeneration(),
+	}
+	if err := r.GetCfAPI().ValidateAll(); errors.Is(err, errInvalidZone) || errors.Is(err, errAmbiguousZone) {
+		// The credentials and tunnel work, only the DNS records cannot be created for the domain.
+		// The TunnelBindings skip them on the condition, the tunnel is still set up.
+		zoneCondition.Status, zoneCondition.Reason, zoneCondition.Message = metav1.ConditionFalse, "ZoneNotFound", err.Error()
+		if errors.Is(err, errAmbiguousZone) {
+			zoneCondition.Reason = "ZoneInSeveralAccounts"
+		}
+		r.GetLog().Error(err, "Domain is not a zone of the account, DNS records are not managed", "domain", domain, "reason", zoneCondition.Reason)
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "InvalidZone", fmt.Sprintf("Not managing DNS records of domain %s: %s", domain, err.Error()))
+	} else if err != nil {
 		r.GetLog().Error(err, "Failed to validate API credentials")
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "ErrSpecApi", "Error validating Cloudflare API credentials")
-		return err
+		setAuthFailedCondition(r, "InvalidCredentials", err)
+		return fmt.Errorf("failed to validate API credentials for tunnel %s: %w", r.GetTunnel().GetName(), err)
 	}
 	status := r.GetTunnel().GetStatus()
 	status.AccountId = r.GetCfAPI().ValidAccountId
 	status.TunnelId = r.GetCfAPI().ValidTunnelId
 	status.TunnelName = r.GetCfAPI().ValidTunnelName
 	status.ZoneId = r.GetCfAPI().ValidZoneId
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionAuthenticated,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Authenticated",
+		Message:            "Cloudflare API credentials validated",
+		ObservedGeneration: r.GetTunnel().GetObject().GetGeneration(),
+	})
+	meta.SetStatusCondition(&status.Conditions, zoneCondition)
 	r.GetTunnel().SetStatus(status)
 	if err := r.GetClient().Status().Update(r.GetContext(), r.GetTunnel().GetObject()); err != nil {
 		r.GetLog().Error(err, "Failed to update Tunnel status", "Tunnel.Namespace", r.GetTunnel().GetNamespace(), "Tunnel.Name", r.GetTunnel().GetName())
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedStatusSet", "Failed to set Tunnel status required for operation")
-		return err
+		return fmt.Errorf("failed to update status of tunnel %s: %w", r.GetTunnel().GetName(), err)
 	}
 	r.GetLog().Info("Tunnel status is set", "status", r.GetTunnel().GetStatus())
 	return nil
 }
 
+// syncArgoSmartRouting enables or disables Argo Smart Routing on the zone of the tunnel, if opted in to with spec.argoSmartRouting
+func syncArgoSmartRouting(r GenericTunnelReconciler) error {
+	desired := r.GetTunnel().GetSpec().ArgoSmartRouting
+	if desired == nil {
+		return nil
+	}
+	// The setting is on the zone, which the domain is not
+	if meta.IsStatusConditionFalse(r.GetTunnel().GetStatus().Conditions, conditionZoneValid) {
+		r.GetLog().Info("Domain is not a valid zone, not configuring Argo Smart Routing", "domain", r.GetCfAPI().Domain)
+		return nil
+	}
+
+	enabled, err := r.GetCfAPI().GetArgoSmartRouting()
+	if err != nil {
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedArgoSmartRouting", "Failed to read Argo Smart Routing setting of the zone")
+		return err
+	}
+	if enabled == *desired {
+		return nil
+	}
+
+	r.GetLog().Info("Changing Argo Smart Routing on the zone, this setting is zone-wide and billable", "domain", r.GetCfAPI().Domain, "current", enabled, "desired", *desired)
+	if err := r.GetCfAPI().SetArgoSmartRouting(*desired); err != nil {
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedArgoSmartRouting", "Failed to change Argo Smart Routing setting of the zone")
+		return err
+	}
+	r.GetLog().Info("Argo Smart Routing changed on the zone", "domain", r.GetCfAPI().Domain, "enabled", *desired)
+	r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "ArgoSmartRouting", fmt.Sprintf("Argo Smart Routing set to %t on zone %s", *desired, r.GetCfAPI().Domain))
+	return nil
+}
+
 func createManagedSecret(r GenericTunnelReconciler) error {
 	managedSecret := &corev1.Secret{}
 	if err := r.GetClient().Get(r.GetContext(), apitypes.NamespacedName{Name: r.GetTunnel().GetName(), Namespace: r.GetTunnel().GetNamespace()}, managedSecret); err != nil && apierrors.IsNotFound(err) {
@@ -241,14 +679,14 @@ func createManagedSecret(r GenericTunnelReconciler) error {
 		if err != nil {
 			r.GetLog().Error(err, "Failed to create new Secret", "Deployment.Namespace", sec.Namespace, "Deployment.Name", sec.Name)
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedCreatingSecret", "Creating Tunnel Secret failed")
-			return err
+			return fmt.Errorf("failed to create Secret %s/%s: %w", sec.Namespace, sec.Name, err)
 		}
 		r.GetLog().Info("Secret created", "Secret.Namespace", sec.Namespace, "Secret.Name", sec.Name)
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "CreatedSecret", "Created Tunnel Secret")
 	} else if err != nil {
 		r.GetLog().Error(err, "Failed to get Secret")
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedCreatedSecret", "Reading Tunnel Secret failed")
-		return err
+		return fmt.Errorf("failed to get Secret %s/%s: %w", r.GetTunnel().GetNamespace(), r.GetTunnel().GetName(), err)
 	}
 	return nil
 }
@@ -264,19 +702,60 @@ func createManagedConfigMap(r GenericTunnelReconciler) error {
 		if err != nil {
 			r.GetLog().Error(err, "Failed to create new ConfigMap", "Deployment.Namespace", cm.Namespace, "Deployment.Name", cm.Name)
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedConfiguring", "Creating Tunnel ConfigMap failed")
-			return err
+			return fmt.Errorf("failed to create ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
 		}
 		r.GetLog().Info("ConfigMap created", "ConfigMap.Namespace", cm.Namespace, "ConfigMap.Name", cm.Name)
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "Configured", "Created Tunnel ConfigMap")
 	} else if err != nil {
 		r.GetLog().Error(err, "Failed to get ConfigMap")
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedConfigured", "Reading Tunnel ConfigMap failed")
-		return err
+		return fmt.Errorf("failed to get ConfigMap %s/%s: %w", r.GetTunnel().GetNamespace(), r.GetTunnel().GetName(), err)
 	}
 	return nil
 }
 
 func createOrScaleManagedDeployment(r GenericTunnelReconciler) (ctrl.Result, bool, error) {
+	if err := validateTopologySpread(r.GetTunnel().GetSpec()); err != nil {
+		r.GetLog().Error(err, "Invalid topology spread")
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "InvalidTopologySpread", err.Error())
+		return ctrl.Result{}, false, err
+	}
+	if err := validateImage(r.GetTunnel().GetSpec().Image); err != nil {
+		r.GetLog().Error(err, "Invalid image")
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "InvalidImage", err.Error())
+		return ctrl.Result{}, false, err
+	}
+	if err := validateClusterDomain(r.GetTunnel().GetSpec().ClusterDomain); err != nil {
+		r.GetLog().Error(err, "Invalid clusterDomain")
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "InvalidClusterDomain", err.Error())
+		return ctrl.Result{}, false, err
+	}
+	if err := validateCaPool(r); err != nil {
+		r.GetLog().Error(err, "Invalid caPool")
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "InvalidCaPool", err.Error())
+		return ctrl.Result{}, false, err
+	}
+	if err := validateScheduling(r.GetTunnel().GetSpec()); err != nil {
+		r.GetLog().Error(err, "Invalid scheduling")
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "InvalidScheduling", err.Error())
+		return ctrl.Result{}, false, err
+	}
+	if err := validateSecurityContext(r.GetTunnel().GetSpec()); err != nil {
+		r.GetLog().Error(err, "Invalid securityContext")
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "InvalidSecurityContext", err.Error())
+		return ctrl.Result{}, false, err
+	}
+	if err := validateExtraArgs(r.GetTunnel().GetSpec()); err != nil {
+		r.GetLog().Error(err, "Invalid extraArgs")
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "InvalidExtraArgs", err.Error())
+		return ctrl.Result{}, false, err
+	}
+	if err := validateResources(r.GetTunnel().GetSpec()); err != nil {
+		r.GetLog().Error(err, "Invalid resources")
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "InvalidResources", err.Error())
+		return ctrl.Result{}, false, err
+	}
+
 	// Check if Deployment already exists, else create it
 	cfDeployment := &appsv1.Deployment{}
 	if res, err := createManagedDeployment(r, cfDeployment); err != nil || (res != ctrl.Result{}) {
@@ -288,6 +767,11 @@ func createOrScaleManagedDeployment(r GenericTunnelReconciler) (ctrl.Result, boo
 		return res, false, err
 	}
 
+	// Ensure the cloudflared arguments and pod placement are the same as the spec
+	if err := updateManagedDeploymentTemplate(r, cfDeployment); err != nil {
+		return ctrl.Result{}, false, err
+	}
+
 	return ctrl.Result{}, true, nil
 }
 
@@ -301,7 +785,7 @@ func createManagedDeployment(r GenericTunnelReconciler, cfDeployment *appsv1.Dep
 		if err != nil {
 			r.GetLog().Error(err, "Failed to create new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedDeploying", "Creating Tunnel Deployment failed")
-			return ctrl.Result{}, err
+			return ctrl.Result{}, fmt.Errorf("failed to create Deployment %s/%s: %w", dep.Namespace, dep.Name, err)
 		}
 		r.GetLog().Info("Deployment created", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "Deployed", "Created Tunnel Deployment")
@@ -309,7 +793,7 @@ func createManagedDeployment(r GenericTunnelReconciler, cfDeployment *appsv1.Dep
 	} else if err != nil {
 		r.GetLog().Error(err, "Failed to get Deployment")
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedDeployed", "Reading Tunnel Deployment failed")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, fmt.Errorf("failed to get Deployment %s/%s: %w", r.GetTunnel().GetNamespace(), r.GetTunnel().GetName(), err)
 	}
 	return ctrl.Result{}, nil
 }
@@ -323,7 +807,7 @@ func scaleManagedDeployment(r GenericTunnelReconciler, cfDeployment *appsv1.Depl
 		if err := r.GetClient().Update(r.GetContext(), cfDeployment); err != nil {
 			r.GetLog().Error(err, "Failed to update Deployment", "Deployment.Namespace", cfDeployment.Namespace, "Deployment.Name", cfDeployment.Name)
 			r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedScaling", "Failed to scale Tunnel Deployment")
-			return ctrl.Result{}, err
+			return ctrl.Result{}, fmt.Errorf("failed to scale Deployment %s/%s: %w", cfDeployment.Namespace, cfDeployment.Name, err)
 		}
 		r.GetLog().Info("Deployment updated")
 		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "Scaled", "Scaled Tunnel Deployment")
@@ -335,6 +819,165 @@ func scaleManagedDeployment(r GenericTunnelReconciler, cfDeployment *appsv1.Depl
 	return ctrl.Result{}, nil
 }
 
+// updateManagedDeploymentTemplate updates the cloudflared image, arguments and resources and the topology spread constraints
+// of the Deployment in a single update, so that a change only triggers a single rollout
+func updateManagedDeploymentTemplate(r GenericTunnelReconciler, cfDeployment *appsv1.Deployment) error {
+	podSpec := &cfDeployment.Spec.Template.Spec
+	changed := false
+
+	image := r.GetTunnel().GetSpec().Image
+	args := argsForTunnel(r.GetTunnel().GetSpec())
+	resources := resourcesForTunnel(r.GetTunnel())
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name != "cloudflared" {
+			continue
+		}
+		if image != "" && podSpec.Containers[i].Image != image {
+			r.GetLog().Info("Updating deployment image", "currentImage", podSpec.Containers[i].Image, "desiredImage", image)
+			podSpec.Containers[i].Image = image
+			changed = true
+		}
+		if !reflect.DeepEqual(podSpec.Containers[i].Args, args) {
+			r.GetLog().Info("Updating deployment arguments", "currentArgs", podSpec.Containers[i].Args, "desiredArgs", args)
+			podSpec.Containers[i].Args = args
+			changed = true
+		}
+		if !equality.Semantic.DeepEqual(podSpec.Containers[i].Resources, resources) {
+			r.GetLog().Info("Updating deployment resources", "currentResources", podSpec.Containers[i].Resources, "desiredResources", resources)
+			podSpec.Containers[i].Resources = resources
+			changed = true
+		}
+		if probe := livenessProbeForTunnel(r.GetTunnel()); !equality.Semantic.DeepEqual(podSpec.Containers[i].LivenessProbe, probe) {
+			r.GetLog().Info("Updating deployment liveness probe", "currentProbe", podSpec.Containers[i].LivenessProbe, "desiredProbe", probe)
+			podSpec.Containers[i].LivenessProbe = probe
+			changed = true
+		}
+		if probe := readinessProbeForTunnel(r.GetTunnel()); !equality.Semantic.DeepEqual(podSpec.Containers[i].ReadinessProbe, probe) {
+			r.GetLog().Info("Updating deployment readiness probe", "currentProbe", podSpec.Containers[i].ReadinessProbe, "desiredProbe", probe)
+			podSpec.Containers[i].ReadinessProbe = probe
+			changed = true
+		}
+		if securityContext := r.GetTunnel().GetSpec().SecurityContext; !equality.Semantic.DeepEqual(podSpec.Containers[i].SecurityContext, securityContext) {
+			r.GetLog().Info("Updating deployment container security context", "currentSecurityContext", podSpec.Containers[i].SecurityContext, "desiredSecurityContext", securityContext)
+			podSpec.Containers[i].SecurityContext = securityContext.DeepCopy()
+			changed = true
+		}
+	}
+
+	if updateCaPoolVolume(podSpec, caPoolVolumeForTunnel(r.GetTunnel())) {
+		r.GetLog().Info("Updating deployment caPool volume")
+		changed = true
+	}
+
+	nodeSelector := nodeSelectorsForTunnel(r.GetTunnel())
+	if !equality.Semantic.DeepEqual(podSpec.NodeSelector, nodeSelector) {
+		r.GetLog().Info("Updating deployment node selector", "currentNodeSelector", podSpec.NodeSelector, "desiredNodeSelector", nodeSelector)
+		podSpec.NodeSelector = nodeSelector
+		changed = true
+	}
+
+	tolerations := r.GetTunnel().GetSpec().Tolerations
+	if !equality.Semantic.DeepEqual(podSpec.Tolerations, tolerations) {
+		r.GetLog().Info("Updating deployment tolerations", "currentTolerations", podSpec.Tolerations, "desiredTolerations", tolerations)
+		podSpec.Tolerations = tolerations
+		changed = true
+	}
+
+	podSecurityContext := podSecurityContextForTunnel(r.GetTunnel())
+	if !equality.Semantic.DeepEqual(podSpec.SecurityContext, podSecurityContext) {
+		r.GetLog().Info("Updating deployment pod security context", "currentSecurityContext", podSpec.SecurityContext, "desiredSecurityContext", podSecurityContext)
+		podSpec.SecurityContext = podSecurityContext
+		changed = true
+	}
+
+	constraints := topologySpreadConstraintsForTunnel(r.GetTunnel())
+	if !equality.Semantic.DeepEqual(podSpec.TopologySpreadConstraints, constraints) {
+		r.GetLog().Info("Updating deployment topology spread constraints", "currentConstraints", podSpec.TopologySpreadConstraints, "desiredConstraints", constraints)
+		podSpec.TopologySpreadConstraints = constraints
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	if err := syncConfigChecksum(r, cfDeployment); err != nil {
+		return err
+	}
+	r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "Updating", "Updating Tunnel Deployment")
+	if err := r.GetClient().Update(r.GetContext(), cfDeployment); err != nil {
+		r.GetLog().Error(err, "Failed to update Deployment", "Deployment.Namespace", cfDeployment.Namespace, "Deployment.Name", cfDeployment.Name)
+		r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeWarning, "FailedUpdating", "Failed to update Tunnel Deployment")
+		return fmt.Errorf("failed to update Deployment %s/%s: %w", cfDeployment.Namespace, cfDeployment.Name, err)
+	}
+	r.GetLog().Info("Deployment updated")
+	r.GetRecorder().Event(r.GetTunnel().GetObject(), corev1.EventTypeNormal, "Updated", "Updated Tunnel Deployment")
+	return nil
+}
+
+// updateCaPoolVolume adds, replaces or removes the CA bundle volume and its cloudflared mount, returning whether it changed
+func updateCaPoolVolume(podSpec *corev1.PodSpec, desired *corev1.Volume) bool {
+	var volumes []corev1.Volume
+	var current *corev1.Volume
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == caPoolVolumeName {
+			current = &podSpec.Volumes[i]
+		} else {
+			volumes = append(volumes, podSpec.Volumes[i])
+		}
+	}
+	if sameCaPoolSource(current, desired) {
+		return false
+	}
+	if desired != nil {
+		volumes = append(volumes, *desired)
+	}
+	podSpec.Volumes = volumes
+
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name != "cloudflared" {
+			continue
+		}
+		var mounts []corev1.VolumeMount
+		for _, mount := range podSpec.Containers[i].VolumeMounts {
+			if mount.Name != caPoolVolumeName {
+				mounts = append(mounts, mount)
+			}
+		}
+		if desired != nil {
+			mounts = append(mounts, caPoolVolumeMount())
+		}
+		podSpec.Containers[i].VolumeMounts = mounts
+	}
+	return true
+}
+
+// caPoolVolumeMount returns the mount of the CA bundle volume into cloudflared
+func caPoolVolumeMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      caPoolVolumeName,
+		MountPath: caPoolMountPath,
+		ReadOnly:  true,
+	}
+}
+
+// syncConfigChecksum sets the checksum of the current tunnel configuration on the Deployment template being rolled out,
+// so that a configuration change not applied yet is part of the same rollout instead of causing another one
+func syncConfigChecksum(r GenericTunnelReconciler, cfDeployment *appsv1.Deployment) error {
+	if restart := r.GetTunnel().GetSpec().RestartOnConfigChange; restart != nil && !*restart {
+		return nil
+	}
+	cfConfigMap := &corev1.ConfigMap{}
+	if err := r.GetClient().Get(r.GetContext(), apitypes.NamespacedName{Name: r.GetTunnel().GetName(), Namespace: r.GetTunnel().GetNamespace()}, cfConfigMap); err != nil {
+		r.GetLog().Error(err, "Failed to get ConfigMap")
+		return fmt.Errorf("failed to get ConfigMap %s/%s: %w", r.GetTunnel().GetNamespace(), r.GetTunnel().GetName(), err)
+	}
+	if cfDeployment.Spec.Template.Annotations == nil {
+		cfDeployment.Spec.Template.Annotations = map[string]string{}
+	}
+	cfDeployment.Spec.Template.Annotations[tunnelConfigChecksum] = configChecksum(cfConfigMap.Data[configmapKey])
+	return nil
+}
+
 func createManagedResources(r GenericTunnelReconciler) (ctrl.Result, bool, error) {
 	// Check if Secret already exists, else create it
 	if err := createManagedSecret(r); err != nil {
@@ -346,6 +989,11 @@ func createManagedResources(r GenericTunnelReconciler) (ctrl.Result, bool, error
 		return ctrl.Result{}, false, err
 	}
 
+	// The Deployment is left to another controller
+	if !deploymentManaged(r.GetTunnel()) {
+		return ctrl.Result{}, true, nil
+	}
+
 	// Create Deployment if does not exist and scale it
 	if res, ok, err := createOrScaleManagedDeployment(r); !ok {
 		return res, false, err
@@ -354,27 +1002,54 @@ func createManagedResources(r GenericTunnelReconciler) (ctrl.Result, bool, error
 	return ctrl.Result{}, true, nil
 }
 
-// configMapForTunnel returns a tunnel ConfigMap object
-func configMapForTunnel(r GenericTunnelReconciler) *corev1.ConfigMap {
-	ls := labelsForTunnel(r.GetTunnel())
-	noTlsVerify := r.GetTunnel().GetSpec().NoTlsVerify
+// originRequestForTunnel returns the default origin request configuration for the tunnel
+func originRequestForTunnel(cf Tunnel) OriginRequestConfig {
+	spec := cf.GetSpec()
+	noTlsVerify := spec.NoTlsVerify
 	originRequest := OriginRequestConfig{
 		NoTLSVerify: &noTlsVerify,
 	}
-	if r.GetTunnel().GetSpec().OriginCaPool != "" {
-		defaultCaPool := "/etc/cloudflared/certs/tls.crt"
-		originRequest.CAPool = &defaultCaPool
+	if caPool := caPoolPathForTunnel(cf); caPool != "" {
+		originRequest.CAPool = &caPool
+	}
+	// Only set when enabled to avoid config churn
+	if spec.OriginRequest.NoHappyEyeballs {
+		originRequest.NoHappyEyeballs = ptr(true)
 	}
-	initialConfigBytes, _ := yaml.Marshal(Configuration{
-		TunnelId:      r.GetTunnel().GetStatus().TunnelId,
+	if spec.OriginRequest.Http2Origin {
+		originRequest.Http2Origin = ptr(true)
+	}
+	originRequest.DisableChunkedEncoding = spec.OriginRequest.DisableChunkedEncoding
+	return originRequest
+}
+
+// refreshOriginRequestDefaults sets the origin request defaults of the tunnel spec on the configuration read back from the
+// ConfigMap, which is only built from the spec when the ConfigMap is created
+func refreshOriginRequestDefaults(originRequest *OriginRequestConfig, cf Tunnel) {
+	defaults := originRequestForTunnel(cf)
+	originRequest.NoHappyEyeballs = defaults.NoHappyEyeballs
+	originRequest.Http2Origin = defaults.Http2Origin
+	originRequest.DisableChunkedEncoding = defaults.DisableChunkedEncoding
+}
+
+// initialConfigurationForTunnel returns the cloudflared configuration of the tunnel before any TunnelBinding is added
+func initialConfigurationForTunnel(cf Tunnel) Configuration {
+	return Configuration{
+		TunnelId:      cf.GetStatus().TunnelId,
 		SourceFile:    "/etc/cloudflared/creds/credentials.json",
 		Metrics:       "0.0.0.0:2000",
 		NoAutoUpdate:  true,
-		OriginRequest: originRequest,
+		OriginRequest: originRequestForTunnel(cf),
 		Ingress: []UnvalidatedIngressRule{{
-			Service: r.GetTunnel().GetSpec().FallbackTarget,
+			Service: cf.GetSpec().FallbackTarget,
 		}},
-	})
+	}
+}
+
+// configMapForTunnel returns a tunnel ConfigMap object
+func configMapForTunnel(r GenericTunnelReconciler) *corev1.ConfigMap {
+	ls := labelsForTunnel(r.GetTunnel())
+	initialConfigBytes, _ := yaml.Marshal(initialConfigurationForTunnel(r.GetTunnel()))
 
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -405,14 +1080,69 @@ func secretForTunnel(r GenericTunnelReconciler) *corev1.Secret {
 	return sec
 }
 
+// argsForTunnel returns the cloudflared arguments for the tunnel
+func argsForTunnel(spec networkingv1alpha1.TunnelSpec) []string {
+	args := []string{"tunnel", "--config", "/etc/cloudflared/config/config.yaml", "--metrics", "0.0.0.0:2000"}
+	if spec.Retries != nil {
+		args = append(args, "--retries", strconv.Itoa(int(*spec.Retries)))
+	}
+	if spec.HAConnections != nil {
+		args = append(args, "--ha-connections", strconv.Itoa(int(*spec.HAConnections)))
+	}
+	if spec.GracePeriod != "" {
+		args = append(args, "--grace-period", spec.GracePeriod)
+	}
+	if spec.EdgeIPVersion != "" {
+		args = append(args, "--edge-ip-version", spec.EdgeIPVersion)
+	}
+	// The flag overrides no-autoupdate of the configuration, only set it if specified to avoid a rollout
+	if spec.NoAutoupdate != nil {
+		args = append(args, "--no-autoupdate="+strconv.FormatBool(*spec.NoAutoupdate))
+	}
+	args = append(args, spec.ExtraArgs...)
+	return append(args, "run")
+}
+
+// operatorManagedArgs are the cloudflared flags set by the operator, which extraArgs cannot override
+var operatorManagedArgs = map[string]bool{
+	"config":               true,
+	"metrics":              true,
+	"token":                true,
+	"token-file":           true,
+	"credentials-file":     true,
+	"credentials-contents": true,
+	"cred-file":            true,
+	"origincert":           true,
+	"retries":              true,
+	"ha-connections":       true,
+	"grace-period":         true,
+	"edge-ip-version":      true,
+	"no-autoupdate":        true,
+}
+
+// validateExtraArgs rejects extraArgs overriding the flags set by the operator, with either the --flag value or --flag=value form
+func validateExtraArgs(spec networkingv1alpha1.TunnelSpec) error {
+	for _, arg := range spec.ExtraArgs {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if operatorManagedArgs[name] {
+			return fmt.Errorf("extraArgs: %s is managed by the operator and cannot be set", arg)
+		}
+	}
+	return nil
+}
+
 // deploymentForTunnel returns a tunnel Deployment object
 func deploymentForTunnel(r GenericTunnelReconciler) *appsv1.Deployment {
 	ls := labelsForTunnel(r.GetTunnel())
 	replicas := r.GetTunnel().GetSpec().Size
 	nodeSelector := nodeSelectorsForTunnel(r.GetTunnel())
 	tolerations := r.GetTunnel().GetSpec().Tolerations
+	topologySpreadConstraints := topologySpreadConstraintsForTunnel(r.GetTunnel())
 
-	args := []string{"tunnel", "--config", "/etc/cloudflared/config/config.yaml", "--metrics", "0.0.0.0:2000", "run"}
+	args := argsForTunnel(r.GetTunnel().GetSpec())
 	volumes := []corev1.Volume{{
 		Name: "creds",
 		VolumeSource: corev1.VolumeSource{
@@ -452,6 +1182,10 @@ func deploymentForTunnel(r GenericTunnelReconciler) *appsv1.Deployment {
 			},
 		})
 	}
+	if caPoolVolume := caPoolVolumeForTunnel(r.GetTunnel()); caPoolVolume != nil {
+		volumeMounts = append(volumeMounts, caPoolVolumeMount())
+		volumes = append(volumes, *caPoolVolume)
+	}
 
 	dep := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -470,20 +1204,11 @@ func deploymentForTunnel(r GenericTunnelReconciler) *appsv1.Deployment {
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{{
-						Image: r.GetTunnel().GetSpec().Image,
-						Name:  "cloudflared",
-						Args:  args,
-						LivenessProbe: &corev1.Probe{
-							ProbeHandler: corev1.ProbeHandler{
-								HTTPGet: &corev1.HTTPGetAction{
-									Path: "/ready",
-									Port: intstr.IntOrString{IntVal: 2000},
-								},
-							},
-							FailureThreshold:    1,
-							InitialDelaySeconds: 10,
-							PeriodSeconds:       10,
-						},
+						Image:          r.GetTunnel().GetSpec().Image,
+						Name:           "cloudflared",
+						Args:           args,
+						LivenessProbe:  livenessProbeForTunnel(r.GetTunnel()),
+						ReadinessProbe: readinessProbeForTunnel(r.GetTunnel()),
 						Ports: []corev1.ContainerPort{
 							{
 								Name:          "metrics",
@@ -491,15 +1216,15 @@ func deploymentForTunnel(r GenericTunnelReconciler) *appsv1.Deployment {
 								Protocol:      corev1.ProtocolTCP,
 							},
 						},
-						VolumeMounts: volumeMounts,
-						Resources: corev1.ResourceRequirements{
-							Requests: corev1.ResourceList{"memory": resource.MustParse("30Mi"), "cpu": resource.MustParse("10m")},
-							Limits:   corev1.ResourceList{"memory": resource.MustParse("256Mi"), "cpu": resource.MustParse("500m")},
-						},
+						VolumeMounts:    volumeMounts,
+						Resources:       resourcesForTunnel(r.GetTunnel()),
+						SecurityContext: r.GetTunnel().GetSpec().SecurityContext.DeepCopy(),
 					}},
-					Volumes:      volumes,
-					NodeSelector: nodeSelector,
-					Tolerations:  tolerations,
+					SecurityContext:           podSecurityContextForTunnel(r.GetTunnel()),
+					Volumes:                   volumes,
+					NodeSelector:              nodeSelector,
+					Tolerations:               tolerations,
+					TopologySpreadConstraints: topologySpreadConstraints,
 				},
 			},
 		},
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"testing"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRefreshOriginRequestDefaults(t *testing.T) {
+	tunnel := &networkingv1alpha1.Tunnel{}
+	tunnel.Spec.OriginRequest.NoHappyEyeballs = true
+	tunnel.Spec.OriginRequest.Http2Origin = true
+	tunnel.Spec.OriginRequest.DisableChunkedEncoding = ptr(true)
+
+	// A configuration written before the defaults were enabled
+	originRequest := OriginRequestConfig{NoTLSVerify: ptr(false)}
+	refreshOriginRequestDefaults(&originRequest, TunnelAdapter{tunnel})
+	if originRequest.NoHappyEyeballs == nil || !*originRequest.NoHappyEyeballs {
+		t.Errorf("noHappyEyeballs = %v, want true", originRequest.NoHappyEyeballs)
+	}
+	if originRequest.Http2Origin == nil || !*originRequest.Http2Origin {
+		t.Errorf("http2Origin = %v, want true", originRequest.Http2Origin)
+	}
+	if originRequest.DisableChunkedEncoding == nil || !*originRequest.DisableChunkedEncoding {
+		t.Errorf("disableChunkedEncoding = %v, want true", originRequest.DisableChunkedEncoding)
+	}
+
+	// Disabling them removes them from the configuration
+	tunnel.Spec.OriginRequest = networkingv1alpha1.OriginRequestSpec{}
+	refreshOriginRequestDefaults(&originRequest, TunnelAdapter{tunnel})
+	if originRequest.NoHappyEyeballs != nil {
+		t.Errorf("noHappyEyeballs = %v, want unset", *originRequest.NoHappyEyeballs)
+	}
+	if originRequest.Http2Origin != nil {
+		t.Errorf("http2Origin = %v, want unset", *originRequest.Http2Origin)
+	}
+	if originRequest.DisableChunkedEncoding != nil {
+		t.Errorf("disableChunkedEncoding = %v, want unset", *originRequest.DisableChunkedEncoding)
+	}
+}
+
+func TestValidateTopologySpread(t *testing.T) {
+	zone := corev1.TopologySpreadConstraint{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: corev1.DoNotSchedule}
+	tests := []struct {
+		name    string
+		spec    networkingv1alpha1.TunnelSpec
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "spreadAcrossZones", spec: networkingv1alpha1.TunnelSpec{SpreadAcrossZones: true}},
+		{name: "constraint", spec: networkingv1alpha1.TunnelSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zone}}},
+		{
+			name:    "both",
+			spec:    networkingv1alpha1.TunnelSpec{SpreadAcrossZones: true, TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zone}},
+			wantErr: true,
+		},
+		{
+			name: "same key with both whenUnsatisfiable",
+			spec: networkingv1alpha1.TunnelSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				zone, {MaxSkew: 2, TopologyKey: zone.TopologyKey, WhenUnsatisfiable: corev1.ScheduleAnyway},
+			}},
+		},
+		{name: "duplicate", spec: networkingv1alpha1.TunnelSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zone, zone}}, wantErr: true},
+		{
+			name:    "maxSkew 0",
+			spec:    networkingv1alpha1.TunnelSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{TopologyKey: zone.TopologyKey, WhenUnsatisfiable: corev1.DoNotSchedule}}},
+			wantErr: true,
+		},
+		{
+			name:    "no topologyKey",
+			spec:    networkingv1alpha1.TunnelSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{MaxSkew: 1, WhenUnsatisfiable: corev1.DoNotSchedule}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid whenUnsatisfiable",
+			spec:    networkingv1alpha1.TunnelSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{MaxSkew: 1, TopologyKey: zone.TopologyKey, WhenUnsatisfiable: "Sometimes"}}},
+			wantErr: true,
+		},
+		{
+			name: "minDomains with ScheduleAnyway",
+			spec: networkingv1alpha1.TunnelSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: zone.TopologyKey, WhenUnsatisfiable: corev1.ScheduleAnyway, MinDomains: ptr(int32(2))},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTopologySpread(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("validateTopologySpread() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	resources := func(request, limit string) *corev1.ResourceRequirements {
+		return &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(request)},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(limit)},
+		}
+	}
+	tests := []struct {
+		name      string
+		resources *corev1.ResourceRequirements
+		wantErr   bool
+	}{
+		{name: "none"},
+		{name: "request below limit", resources: resources("64Mi", "128Mi")},
+		{name: "request at limit", resources: resources("128Mi", "0.125Gi")},
+		{name: "request above limit", resources: resources("256Mi", "128Mi"), wantErr: true},
+		{
+			name: "limit of another resource",
+			resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateResources(networkingv1alpha1.TunnelSpec{Resources: tt.resources}); (err != nil) != tt.wantErr {
+				t.Errorf("validateResources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateScheduling(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    networkingv1alpha1.TunnelSpec
+		wantErr bool
+	}{
+		{name: "none"},
+		{
+			name: "valid",
+			spec: networkingv1alpha1.TunnelSpec{
+				NodeSelectors: map[string]string{"kubernetes.io/os": "linux"},
+				Tolerations: []corev1.Toleration{
+					{Key: "dedicated", Value: "tunnel", Effect: corev1.TaintEffectNoSchedule},
+					{Operator: corev1.TolerationOpExists},
+					{Key: "node.kubernetes.io/unreachable", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: ptr(int64(30))},
+				},
+			},
+		},
+		{name: "invalid nodeSelector key", spec: networkingv1alpha1.TunnelSpec{NodeSelectors: map[string]string{"bad key": "linux"}}, wantErr: true},
+		{name: "invalid nodeSelector value", spec: networkingv1alpha1.TunnelSpec{NodeSelectors: map[string]string{"kubernetes.io/os": "linux os"}}, wantErr: true},
+		{name: "invalid toleration key", spec: networkingv1alpha1.TunnelSpec{Tolerations: []corev1.Toleration{{Key: "bad key", Operator: corev1.TolerationOpExists}}}, wantErr: true},
+		{name: "Equal without key", spec: networkingv1alpha1.TunnelSpec{Tolerations: []corev1.Toleration{{Value: "tunnel"}}}, wantErr: true},
+		{name: "Exists with value", spec: networkingv1alpha1.TunnelSpec{Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists, Value: "tunnel"}}}, wantErr: true},
+		{name: "unsupported operator", spec: networkingv1alpha1.TunnelSpec{Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: "In"}}}, wantErr: true},
+		{name: "unsupported effect", spec: networkingv1alpha1.TunnelSpec{Tolerations: []corev1.Toleration{{Key: "dedicated", Effect: "NoRun"}}}, wantErr: true},
+		{
+			name:    "tolerationSeconds without NoExecute",
+			spec:    networkingv1alpha1.TunnelSpec{Tolerations: []corev1.Toleration{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule, TolerationSeconds: ptr(int64(30))}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateScheduling(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("validateScheduling() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateExtraArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "other flags", args: []string{"--loglevel", "debug", "--post-quantum=true"}},
+		{name: "value named like a managed flag", args: []string{"--loglevel", "metrics"}},
+		{name: "managed flag", args: []string{"--metrics", "0.0.0.0:9000"}, wantErr: true},
+		{name: "flag set from the spec", args: []string{"--edge-ip-version=6"}, wantErr: true},
+		{name: "managed flag with value", args: []string{"--config=/tmp/config.yaml"}, wantErr: true},
+		{name: "managed flag with single dash", args: []string{"-token", "secret"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateExtraArgs(networkingv1alpha1.TunnelSpec{ExtraArgs: tt.args}); (err != nil) != tt.wantErr {
+				t.Errorf("validateExtraArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSecurityContext(t *testing.T) {
+	localhost := "profiles/cloudflared.json"
+	tests := []struct {
+		name    string
+		spec    networkingv1alpha1.TunnelSpec
+		wantErr bool
+	}{
+		{name: "none"},
+		{
+			name: "restricted",
+			spec: networkingv1alpha1.TunnelSpec{
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: ptr(false),
+					Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}, Add: []corev1.Capability{"NET_BIND_SERVICE"}},
+					RunAsNonRoot:             ptr(true),
+					RunAsUser:                ptr(int64(65532)),
+				},
+				PodSecurityContext: &corev1.PodSecurityContext{
+					RunAsNonRoot:   ptr(true),
+					SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost, LocalhostProfile: &localhost},
+				},
+			},
+		},
+		{
+			name:    "privileged without escalation",
+			spec:    networkingv1alpha1.TunnelSpec{SecurityContext: &corev1.SecurityContext{AllowPrivilegeEscalation: ptr(false), Privileged: ptr(true)}},
+			wantErr: true,
+		},
+		{
+			name: "SYS_ADMIN without escalation",
+			spec: networkingv1alpha1.TunnelSpec{SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: ptr(false), Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"CAP_SYS_ADMIN"}},
+			}},
+			wantErr: true,
+		},
+		{name: "negative runAsUser", spec: networkingv1alpha1.TunnelSpec{SecurityContext: &corev1.SecurityContext{RunAsUser: ptr(int64(-1))}}, wantErr: true},
+		{name: "negative runAsGroup", spec: networkingv1alpha1.TunnelSpec{PodSecurityContext: &corev1.PodSecurityContext{RunAsGroup: ptr(int64(-1))}}, wantErr: true},
+		{
+			name:    "root with runAsNonRoot",
+			spec:    networkingv1alpha1.TunnelSpec{PodSecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: ptr(true), RunAsUser: ptr(int64(0))}},
+			wantErr: true,
+		},
+		{
+			name:    "Localhost seccompProfile without profile",
+			spec:    networkingv1alpha1.TunnelSpec{SecurityContext: &corev1.SecurityContext{SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost}}},
+			wantErr: true,
+		},
+		{
+			name: "localhostProfile with RuntimeDefault",
+			spec: networkingv1alpha1.TunnelSpec{PodSecurityContext: &corev1.PodSecurityContext{
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault, LocalhostProfile: &localhost},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSecurityContext(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("validateSecurityContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
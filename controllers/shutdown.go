@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+	"time"
+)
+
+// drainContext returns a context that outlives the cancellation of the parent by the drain timeout, keeping its values.
+// The manager cancels the context of in-flight reconciles on shutdown, this lets them finish writing the configuration
+// instead of leaving a stale ConfigMap behind. The returned cancel func must be called once the reconcile completes.
+func drainContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	ctx, cancel := context.WithCancel(detachedContext{parent})
+	go func() {
+		select {
+		case <-parent.Done():
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				cancel()
+			case <-ctx.Done():
+			}
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// detachedContext keeps the values of the parent context, but not its deadline and cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
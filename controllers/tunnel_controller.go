@@ -18,13 +18,19 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
 	"github.com/go-logr/logr"
@@ -37,15 +43,19 @@ type TunnelReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// Credentials reads the Cloudflare credentials of the tunnels, defaults to their Secret
+	Credentials CredentialProvider
+	// DeploymentCheckInterval requeues the tunnels to recreate their Deployment if deleted, disabled if 0
+	DeploymentCheckInterval time.Duration
 
 	// Custom data for ease of (re)use
 
-	ctx         context.Context
-	log         logr.Logger
-	tunnel      Tunnel
-	cfAPI       *CloudflareAPI
-	cfSecret    *corev1.Secret
-	tunnelCreds string
+	ctx           context.Context
+	log           logr.Logger
+	tunnel        Tunnel
+	cfAPI         *CloudflareAPI
+	cfCredentials map[string][]byte
+	tunnelCreds   string
 }
 
 func (r *TunnelReconciler) GetClient() client.Client {
@@ -80,8 +90,8 @@ func (r *TunnelReconciler) SetCfAPI(in *CloudflareAPI) {
 	r.cfAPI = in
 }
 
-func (r *TunnelReconciler) GetCfSecret() *corev1.Secret {
-	return r.cfSecret
+func (r *TunnelReconciler) GetCfCredentials() map[string][]byte {
+	return r.cfCredentials
 }
 
 func (r *TunnelReconciler) GetTunnelCreds() string {
@@ -98,9 +108,10 @@ func (r *TunnelReconciler) initStruct(ctx context.Context, tunnel Tunnel) error
 
 	var err error
 
-	if r.cfAPI, r.cfSecret, err = getAPIDetails(r.ctx, r.Client, r.log, r.tunnel.GetSpec(), r.tunnel.GetStatus(), r.tunnel.GetNamespace()); err != nil {
+	if r.cfAPI, r.cfCredentials, err = getAPIDetails(r.ctx, credentialProviderOrDefault(r.Credentials, r.Client), r.log, r.tunnel.GetSpec(), r.tunnel.GetStatus(), r.tunnel.GetNamespace()); err != nil {
 		r.log.Error(err, "unable to get API details")
 		r.Recorder.Event(r.tunnel.GetObject(), corev1.EventTypeWarning, "ErrSpecSecret", "Error reading Secret to configure API")
+		setAuthFailedCondition(r, "InvalidSecret", err)
 		return err
 	}
 
@@ -131,10 +142,11 @@ func (r *TunnelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
 			r.log.Info("Tunnel deleted, nothing to do")
+			deleteTunnelMetrics(tunnelKey{Kind: "tunnel", Namespace: req.Namespace, Name: req.Name})
 			return ctrl.Result{}, nil
 		}
 		r.log.Error(err, "unable to fetch Tunnel")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, fmt.Errorf("failed to fetch Tunnel %s: %w", req.NamespacedName, err)
 	}
 
 	if err := r.initStruct(ctx, TunnelAdapter{tunnel}); err != nil {
@@ -150,14 +162,41 @@ func (r *TunnelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
+	// Configure zone settings opted in to
+	if err := syncArgoSmartRouting(r); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Create necessary resources
 	if res, ok, err := createManagedResources(r); !ok {
 		return res, err
 	}
 
+	// Check the Deployment again later, in case its deletion was missed
+	if deploymentManaged(r.tunnel) {
+		return ctrl.Result{RequeueAfter: r.DeploymentCheckInterval}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
+// tunnelsForSecret returns reconcile requests for the Tunnels using the Cloudflare credentials in the Secret,
+// so that rotated credentials are picked up and verified right away.
+func (r *TunnelReconciler) tunnelsForSecret(secret client.Object) []reconcile.Request {
+	tunnelList := &networkingv1alpha1.TunnelList{}
+	if err := r.List(context.Background(), tunnelList, client.InNamespace(secret.GetNamespace())); err != nil {
+		ctrllog.Log.Error(err, "failed to list Tunnels for Secret", "secret", secret.GetName(), "namespace", secret.GetNamespace())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, tunnel := range tunnelList.Items {
+		if tunnel.Spec.Cloudflare.Secret == secret.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: apitypes.NamespacedName{Name: tunnel.Name, Namespace: tunnel.Namespace}})
+		}
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *TunnelReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.Recorder = mgr.GetEventRecorderFor("cloudflare-operator")
@@ -166,5 +205,6 @@ func (r *TunnelReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Secret{}).
 		Owns(&appsv1.Deployment{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.tunnelsForSecret)).
 		Complete(r)
 }
@@ -18,28 +18,43 @@ package controllers
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
 	"github.com/go-logr/logr"
 	yaml "gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/client-go/tools/record"
 )
 
+// errConfigMapNotFound is returned by initStruct when the ConfigMap of the tunnel is not created yet
+var errConfigMapNotFound = errors.New("tunnel ConfigMap not found")
+
 // TunnelBindingReconciler reconciles a TunnelBinding object
 type TunnelBindingReconciler struct {
 	client.Client
@@ -47,27 +62,75 @@ type TunnelBindingReconciler struct {
 	Recorder           record.EventRecorder
 	Namespace          string
 	OverwriteUnmanaged bool
+	WaitForTunnelReady bool
+	// AllowedServiceTypes restricts the types of the Services that can be tunneled, all are allowed if empty
+	AllowedServiceTypes []corev1.ServiceType
+	// MigrateLegacyServices removes the annotations, labels and finalizer set by pre v0.9 versions from the subject Services
+	MigrateLegacyServices bool
+	// GatewayAPI includes the ingress rules of the Gateway API HTTPRoutes of the tunnel in its configuration
+	GatewayAPI bool
+	// DrainTimeout is how long a reconcile in flight on shutdown can keep running to finish writing the configuration
+	DrainTimeout time.Duration
+	// MaxDNSDeleteAttempts removes the finalizer of a TunnelBinding being deleted after this many failed attempts
+	// to delete its DNS entries, leaving them orphaned. Retries forever if 0.
+	MaxDNSDeleteAttempts int
+	// ManageCacheRules sets the cache settings of the subjects as cache rules of the zone
+	ManageCacheRules bool
+	// ManageLoadBalancers splits the requests to the hostnames of weighted subjects with Cloudflare Load Balancers
+	ManageLoadBalancers bool
+	// ValidateIngress checks the ingress rules like cloudflared before writing the configuration, keeping the previous one if invalid
+	ValidateIngress bool
+	// CircuitBreakerThreshold backs off the DNS updates of a tunnel for CircuitBreakerCooldown after this many consecutive
+	// Cloudflare API failures. Disabled if 0.
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+	// ReportCertificateStatus reports whether the edge certificates of the hostnames are active in the CertificateReady condition
+	ReportCertificateStatus bool
+	// Credentials reads the Cloudflare credentials of the tunnels, defaults to their Secret
+	Credentials CredentialProvider
+
+	// Shared by the copies of the reconciler
+	breaker *circuitBreaker
 
 	// Custom data for ease of (re)use
 
-	ctx            context.Context
-	log            logr.Logger
-	binding        *networkingv1alpha1.TunnelBinding
-	configmap      *corev1.ConfigMap
-	fallbackTarget string
-	cfAPI          *CloudflareAPI
+	ctx              context.Context
+	log              logr.Logger
+	binding          *networkingv1alpha1.TunnelBinding
+	configmap        *corev1.ConfigMap
+	fallbackTarget   string
+	preserveFallback bool
+	manageCatchAll   bool
+	dnsDefaults      networkingv1alpha1.DNSSpec
+	restartOnConfig  bool
+	zoneCondition    *metav1.Condition
+	dnsCondition     *metav1.Condition
+	certCondition    *metav1.Condition
+	tunnel           Tunnel
+	cfAPI            *CloudflareAPI
 }
 
 // labelsForBinding returns the labels for selecting the Bindings served by a Tunnel.
-func (r TunnelBindingReconciler) labelsForBinding() map[string]string {
+func labelsForBinding(binding *networkingv1alpha1.TunnelBinding) map[string]string {
 	labels := map[string]string{
-		tunnelNameLabel: r.binding.TunnelRef.Name,
-		tunnelKindLabel: r.binding.Kind,
+		tunnelNameLabel:      binding.TunnelRef.Name,
+		tunnelKindLabel:      binding.Kind,
+		isClusterTunnelLabel: strconv.FormatBool(strings.ToLower(binding.TunnelRef.Kind) == "clustertunnel"),
 	}
 
 	return labels
 }
 
+// bindingLabelsStale checks if labels managed by the operator are missing or were changed on the binding
+func bindingLabelsStale(binding *networkingv1alpha1.TunnelBinding) bool {
+	for k, v := range labelsForBinding(binding) {
+		if current, ok := binding.Labels[k]; !ok || current != v {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *TunnelBindingReconciler) initStruct(ctx context.Context, tunnelBinding *networkingv1alpha1.TunnelBinding) error {
 	r.ctx = ctx
 	r.binding = tunnelBinding
@@ -83,15 +146,21 @@ func (r *TunnelBindingReconciler) initStruct(ctx context.Context, tunnelBinding
 		if err := r.Get(r.ctx, namespacedName, clusterTunnel); err != nil {
 			r.log.Error(err, "Failed to get ClusterTunnel", "namespacedName", namespacedName)
 			r.Recorder.Event(tunnelBinding, corev1.EventTypeWarning, "ErrTunnel", "Error getting ClusterTunnel")
-			return err
+			return fmt.Errorf("failed to get ClusterTunnel %s: %w", namespacedName.Name, err)
 		}
 
 		r.fallbackTarget = clusterTunnel.Spec.FallbackTarget
-
-		if r.cfAPI, _, err = getAPIDetails(r.ctx, r.Client, r.log, clusterTunnel.Spec, clusterTunnel.Status, r.Namespace); err != nil {
+		r.preserveFallback = clusterTunnel.Spec.PreserveFallbackTarget
+		r.manageCatchAll = clusterTunnel.Spec.ManageCatchAll == nil || *clusterTunnel.Spec.ManageCatchAll
+		r.dnsDefaults = clusterTunnel.Spec.DNS
+		r.restartOnConfig = clusterTunnel.Spec.RestartOnConfigChange == nil || *clusterTunnel.Spec.RestartOnConfigChange
+		r.zoneCondition = meta.FindStatusCondition(clusterTunnel.Status.Conditions, conditionZoneValid)
+		r.tunnel = ClusterTunnelAdapter{clusterTunnel, r.Namespace}
+
+		if r.cfAPI, _, err = getAPIDetails(r.ctx, credentialProviderOrDefault(r.Credentials, r.Client), r.log, clusterTunnel.Spec, clusterTunnel.Status, r.Namespace); err != nil {
 			r.log.Error(err, "unable to get API details")
 			r.Recorder.Event(tunnelBinding, corev1.EventTypeWarning, "ErrApiConfig", "Error getting API details")
-			return err
+			return fmt.Errorf("failed to get API details of ClusterTunnel %s: %w", namespacedName.Name, err)
 		}
 	case "tunnel":
 		namespacedName = apitypes.NamespacedName{Name: r.binding.TunnelRef.Name, Namespace: r.binding.Namespace}
@@ -99,28 +168,39 @@ func (r *TunnelBindingReconciler) initStruct(ctx context.Context, tunnelBinding
 		if err := r.Get(r.ctx, namespacedName, tunnel); err != nil {
 			r.log.Error(err, "Failed to get Tunnel", "namespacedName", namespacedName)
 			r.Recorder.Event(tunnelBinding, corev1.EventTypeWarning, "ErrTunnel", "Error getting Tunnel")
-			return err
+			return fmt.Errorf("failed to get Tunnel %s: %w", namespacedName, err)
 		}
 
 		r.fallbackTarget = tunnel.Spec.FallbackTarget
-
-		if r.cfAPI, _, err = getAPIDetails(r.ctx, r.Client, r.log, tunnel.Spec, tunnel.Status, r.binding.Namespace); err != nil {
+		r.preserveFallback = tunnel.Spec.PreserveFallbackTarget
+		r.manageCatchAll = tunnel.Spec.ManageCatchAll == nil || *tunnel.Spec.ManageCatchAll
+		r.dnsDefaults = tunnel.Spec.DNS
+		r.restartOnConfig = tunnel.Spec.RestartOnConfigChange == nil || *tunnel.Spec.RestartOnConfigChange
+		r.zoneCondition = meta.FindStatusCondition(tunnel.Status.Conditions, conditionZoneValid)
+		r.tunnel = TunnelAdapter{tunnel}
+
+		if r.cfAPI, _, err = getAPIDetails(r.ctx, credentialProviderOrDefault(r.Credentials, r.Client), r.log, tunnel.Spec, tunnel.Status, r.binding.Namespace); err != nil {
 			r.log.Error(err, "unable to get API details")
 			r.Recorder.Event(tunnelBinding, corev1.EventTypeWarning, "ErrApiConfig", "Error getting API details")
-			return err
+			return fmt.Errorf("failed to get API details of Tunnel %s: %w", namespacedName, err)
 		}
 	default:
-		err = fmt.Errorf("invalid kind")
+		err = fmt.Errorf("invalid tunnelRef kind %q", r.binding.TunnelRef.Kind)
 		r.log.Error(err, "unsupported tunnelRef Kind")
 		r.Recorder.Event(tunnelBinding, corev1.EventTypeWarning, "ErrTunnelKind", "Unsupported tunnel kind")
 		return err
 	}
 
 	r.configmap = &corev1.ConfigMap{}
-	if err := r.Get(r.ctx, namespacedName, r.configmap); err != nil {
+	if err := r.Get(r.ctx, namespacedName, r.configmap); err != nil && apierrors.IsNotFound(err) {
+		// The tunnel controller has not created it yet
+		r.log.Info("Tunnel ConfigMap not found, waiting for it", "namespacedName", namespacedName)
+		r.Recorder.Event(tunnelBinding, corev1.EventTypeNormal, "WaitingForConfigMap", "Waiting for Tunnel ConfigMap to be created")
+		return errConfigMapNotFound
+	} else if err != nil {
 		r.log.Error(err, "unable to get configmap for configuration")
 		r.Recorder.Event(tunnelBinding, corev1.EventTypeWarning, "ErrConfigMap", "Error finding ConfigMap for Tunnel referenced by TunnelBinding")
-		return err
+		return fmt.Errorf("failed to get ConfigMap %s: %w", namespacedName, err)
 	}
 
 	return nil
@@ -129,13 +209,14 @@ func (r *TunnelBindingReconciler) initStruct(ctx context.Context, tunnelBinding
 //+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnelbindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnelbindings/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnelbindings/finalizers,verbs=update
-//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnels,verbs=get
+//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnels,verbs=get;list;watch
 //+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=tunnels/status,verbs=get
-//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=clustertunnels,verbs=get
+//+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=clustertunnels,verbs=get;list;watch
 //+kubebuilder:rbac:groups=networking.cfargotunnel.com,resources=clustertunnels/status,verbs=get
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;update;patch
-//+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -144,6 +225,8 @@ func (r *TunnelBindingReconciler) initStruct(ctx context.Context, tunnelBinding
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.10.0/pkg/reconcile
 func (r *TunnelBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, cancel := drainContext(ctx, r.DrainTimeout)
+	defer cancel()
 	r.log = ctrllog.FromContext(ctx)
 
 	// Fetch TunnelBinding from API
@@ -157,11 +240,19 @@ func (r *TunnelBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			return ctrl.Result{}, nil
 		}
 		r.log.Error(err, "unable to fetch TunnelBinding")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, fmt.Errorf("failed to fetch TunnelBinding %s: %w", req.NamespacedName, err)
 	}
+	r.log = r.log.WithValues("tunnel", tunnelBinding.TunnelRef.Name, "tunnelKind", tunnelBinding.TunnelRef.Kind)
 
-	if err := r.initStruct(ctx, tunnelBinding); err != nil {
+	if err := r.initStruct(ctx, tunnelBinding); errors.Is(err, errConfigMapNotFound) && tunnelBinding.GetDeletionTimestamp() == nil {
+		// Requeue with backoff without reporting an error
+		return ctrl.Result{Requeue: true}, nil
+	} else if err != nil {
 		r.log.Error(err, "initialization failed")
+		// The tunnel might be gone with the namespace, do not block its deletion forever
+		if tunnelBinding.GetDeletionTimestamp() != nil && controllerutil.ContainsFinalizer(tunnelBinding, tunnelFinalizer) && r.dnsDeleteBackstop(err) {
+			return ctrl.Result{}, r.removeFinalizer()
+		}
 		return ctrl.Result{}, err
 	}
 
@@ -170,189 +261,1037 @@ func (r *TunnelBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, r.deletionLogic()
 	}
 
+	// Clean up the previous tunnel if the TunnelBinding was moved to another one
+	if err := r.cleanupPreviousTunnel(); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if r.MigrateLegacyServices {
+		r.migrateLegacyServices()
+	}
+
+	expiresAt, expires := r.expiresAt()
+	if expires && !time.Now().Before(expiresAt) {
+		return ctrl.Result{}, r.expiryLogic()
+	}
+
 	if err := r.setStatus(); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	// Configure ConfigMap
+	// The ingress rules are written before the DNS entries pointing to them, and removed after them,
+	// so that a failed step never leaves a DNS entry on the tunnel without a rule. Failures are retried on the next reconcile.
 	r.Recorder.Event(tunnelBinding, corev1.EventTypeNormal, "Configuring", "Configuring ConfigMap")
 	if err := r.configureCloudflareDaemon(); err != nil {
 		r.log.Error(err, "unable to configure ConfigMap", "key", configmapKey)
 		r.Recorder.Event(tunnelBinding, corev1.EventTypeWarning, "FailedConfigure", "Failed to configure ConfigMap")
+		r.reportConditions(err)
 		return ctrl.Result{}, err
 	}
 	r.Recorder.Event(tunnelBinding, corev1.EventTypeNormal, "Configured", "Configured Cloudflare Tunnel")
 
-	if err := r.creationLogic(); err != nil {
-		return ctrl.Result{}, err
+	r.dnsCondition, r.certCondition = nil, nil
+	result, err := r.creationLogic()
+	r.reportConditions(nil)
+	if err == nil && expires {
+		// Come back at expiry to clean up
+		if untilExpiry := time.Until(expiresAt); result.RequeueAfter == 0 || untilExpiry < result.RequeueAfter {
+			result.RequeueAfter = untilExpiry
+		}
+	}
+	return result, err
+}
+
+// expiresAt returns the expiry time of the TunnelBinding, if it has a valid expires-at annotation
+func (r *TunnelBindingReconciler) expiresAt() (time.Time, bool) {
+	expiresAt, ok, err := expiresAtForBinding(r.binding)
+	if err != nil {
+		r.log.Error(err, "Invalid value for annotation, ignoring", "annotation", tunnelExpiresAtAnnotation)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidExpiresAt", fmt.Sprintf("Ignoring %s, not an RFC3339 time", tunnelExpiresAtAnnotation))
+	}
+	return expiresAt, ok
+}
+
+// expiresAtForBinding parses the expires-at annotation of the TunnelBinding
+func expiresAtForBinding(binding *networkingv1alpha1.TunnelBinding) (time.Time, bool, error) {
+	value, ok := binding.Annotations[tunnelExpiresAtAnnotation]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return expiresAt, true, nil
+}
+
+// bindingExpired checks if the expires-at annotation of the TunnelBinding is in the past
+func bindingExpired(binding *networkingv1alpha1.TunnelBinding) bool {
+	expiresAt, ok, _ := expiresAtForBinding(binding)
+	return ok && !time.Now().Before(expiresAt)
+}
+
+// expiryLogic removes the ingress rules and DNS records of an expired TunnelBinding, and deletes it if requested.
+// The status is kept, so that the finalizer can still find the records if the cleanup failed.
+func (r *TunnelBindingReconciler) expiryLogic() error {
+	r.log.Info("TunnelBinding expired, removing its ingress rules and DNS entries")
+	for _, sub := range r.binding.Subjects {
+		r.setResolvedFqdnAnnotation(r.subjectServiceName(sub), nil)
+	}
+
+	// DNS entries are deleted before the ingress rules they point to, see Reconcile for the ordering
+	if !r.binding.TunnelRef.DisableDNSUpdates {
+		for _, info := range r.binding.Status.Services {
+			if err := r.deleteDNSLogic(recordNameForService(info)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Expired bindings are left out of the configuration
+	if err := r.configureCloudflareDaemon(); err != nil {
+		r.log.Error(err, "unable to configure ConfigMap", "key", configmapKey)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedConfigure", "Failed to configure ConfigMap")
+		return err
+	}
+	r.Recorder.Event(r.binding, corev1.EventTypeNormal, "Expired", fmt.Sprintf("Expired at %s, removed ingress rules and DNS entries", r.binding.Annotations[tunnelExpiresAtAnnotation]))
+
+	if r.annotationEnabled(tunnelDeleteOnExpiryAnnotation) {
+		if err := r.Delete(r.ctx, r.binding); err != nil && !apierrors.IsNotFound(err) {
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedDelete", "Failed to delete expired TunnelBinding")
+			return fmt.Errorf("failed to delete expired TunnelBinding %s/%s: %w", r.binding.Namespace, r.binding.Name, err)
+		}
+		r.log.Info("Deleted expired TunnelBinding")
 	}
-	return ctrl.Result{}, nil
+	return nil
 }
 
 func (r *TunnelBindingReconciler) setStatus() error {
 	status := make([]networkingv1alpha1.ServiceInfo, 0, len(r.binding.Subjects))
 	var hostnames string
+	serviceHostnames := map[apitypes.NamespacedName][]string{}
 	for _, sub := range r.binding.Subjects {
 		hostname, target, err := r.getConfigForSubject(sub)
 		if err != nil {
-			r.log.Error(err, "error getting config for service", "svc", sub.Name)
+			r.log.Error(err, "error getting config for service", "service", sub.Name)
 			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "ErrBuildConfig",
 				fmt.Sprintf("Error building TunnelBinding configuration, svc: %s", sub.Name))
+		} else if !isDirectTarget(target) {
+			key := r.subjectServiceName(sub)
+			serviceHostnames[key] = append(serviceHostnames[key], hostname)
 		}
-		status = append(status, networkingv1alpha1.ServiceInfo{Hostname: hostname, Target: target})
+		status = append(status, networkingv1alpha1.ServiceInfo{Hostname: hostname, Target: target, DNSName: sub.Spec.DNSName, Http2Origin: err == nil && r.h2cOrigin(sub, target)})
 		hostnames += hostname + ","
 	}
 
+	for serviceName, names := range serviceHostnames {
+		r.setResolvedFqdnAnnotation(serviceName, names)
+		r.reportSessionAffinity(serviceName, names)
+	}
+
 	r.binding.Status.Services = status
 	r.binding.Status.Hostnames = strings.TrimSuffix(hostnames, ",")
 
 	if err := r.Client.Status().Update(r.ctx, r.binding); err != nil {
 		r.log.Error(err, "Failed to update TunnelBinding status", "TunnelBinding.Namespace", r.binding.Namespace, "TunnelBinding.Name", r.binding.Name)
 		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedStatusSet", "Failed to set Tunnel status required for operation")
-		return err
+		return fmt.Errorf("failed to update TunnelBinding %s/%s status: %w", r.binding.Namespace, r.binding.Name, err)
 	}
 	r.log.Info("Tunnel status is set", "status", r.binding.Status)
 	return nil
 }
 
+// reportSessionAffinity emits an Event if the Service expects sticky sessions, which the tunnel does not preserve:
+// requests reach the Service from the cloudflared pods, so kube-proxy pins the cloudflared pods and not the clients.
+func (r *TunnelBindingReconciler) reportSessionAffinity(serviceName apitypes.NamespacedName, names []string) {
+	service := &corev1.Service{}
+	if err := r.Get(r.ctx, serviceName, service); err != nil {
+		return
+	}
+	if service.Spec.SessionAffinity != corev1.ServiceAffinityClientIP {
+		return
+	}
+	sort.Strings(names)
+	r.Recorder.Event(r.binding, corev1.EventTypeNormal, "SessionAffinityIgnored",
+		fmt.Sprintf("Service %s has sessionAffinity ClientIP, which is not preserved for %s: the client IP seen by kube-proxy is the one of the cloudflared pod", serviceName, strings.Join(names, ",")))
+}
+
+// setResolvedFqdnAnnotation sets the hostnames the Service is tunneled on as an annotation on it, removing it if empty.
+// The hostnames of the other TunnelBindings of the Service are included, like the ones of a second tunnel for failover.
+// This is only for visibility, so failures are logged and otherwise ignored.
+func (r *TunnelBindingReconciler) setResolvedFqdnAnnotation(serviceName apitypes.NamespacedName, names []string) {
+	service := &corev1.Service{}
+	if err := r.Get(r.ctx, serviceName, service); err != nil {
+		if !apierrors.IsNotFound(err) {
+			r.log.Error(err, "unable to get service to annotate", "service", serviceName)
+		}
+		return
+	}
+
+	otherNames, err := r.otherBindingHostnames(serviceName)
+	if err != nil {
+		r.log.Error(err, "unable to get hostnames of other TunnelBindings", "service", serviceName)
+		return
+	}
+	unique := map[string]bool{}
+	for _, name := range append(names, otherNames...) {
+		unique[name] = true
+	}
+	names = make([]string, 0, len(unique))
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hostnames := strings.Join(names, ",")
+
+	if service.Annotations[tunnelResolvedFqdnAnnotation] == hostnames {
+		return
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if hostnames == "" {
+		delete(service.Annotations, tunnelResolvedFqdnAnnotation)
+	} else {
+		if service.Annotations == nil {
+			service.Annotations = map[string]string{}
+		}
+		service.Annotations[tunnelResolvedFqdnAnnotation] = hostnames
+	}
+	if err := r.Patch(r.ctx, service, patch); err != nil {
+		r.log.Error(err, "unable to annotate service with resolved fqdn", "service", serviceName)
+	}
+}
+
+// migrateLegacyServices removes the keys set by the Service controller of pre v0.9 versions from the subject Services.
+// The legacy finalizer would otherwise block the deletion of the Services, as nothing removes it anymore.
+// Failures are logged and otherwise ignored, the migration is retried on the next reconcile.
+func (r *TunnelBindingReconciler) migrateLegacyServices() {
+	for _, subject := range r.binding.Subjects {
+		if subject.Spec.BastionMode || isDirectTarget(subject.Spec.Target) {
+			continue
+		}
+		serviceName := r.subjectServiceName(subject)
+		service := &corev1.Service{}
+		if err := r.Get(r.ctx, serviceName, service); err != nil {
+			if !apierrors.IsNotFound(err) {
+				r.log.Error(err, "unable to get service to migrate", "service", serviceName)
+			}
+			continue
+		}
+
+		patch := client.MergeFrom(service.DeepCopy())
+		var migrated []string
+		for _, annotation := range legacyServiceAnnotations {
+			if value, ok := service.Annotations[annotation]; ok {
+				r.log.Info("Removing legacy annotation, configure it on the TunnelBinding instead", "service", serviceName, "annotation", annotation, "value", value)
+				delete(service.Annotations, annotation)
+				migrated = append(migrated, annotation)
+			}
+		}
+		for _, label := range legacyServiceLabels {
+			if _, ok := service.Labels[label]; ok {
+				r.log.Info("Removing legacy label", "service", serviceName, "label", label)
+				delete(service.Labels, label)
+				migrated = append(migrated, label)
+			}
+		}
+		if controllerutil.RemoveFinalizer(service, tunnelFinalizer) {
+			r.log.Info("Removing legacy finalizer", "service", serviceName, "finalizer", tunnelFinalizer)
+			migrated = append(migrated, tunnelFinalizer)
+		}
+		if len(migrated) == 0 {
+			continue
+		}
+
+		if err := r.Patch(r.ctx, service, patch); err != nil {
+			r.log.Error(err, "unable to migrate legacy service keys", "service", serviceName)
+			continue
+		}
+		r.Recorder.Event(r.binding, corev1.EventTypeNormal, "MigratedService",
+			fmt.Sprintf("Removed legacy keys %s from Service %s", strings.Join(migrated, ","), serviceName))
+	}
+}
+
+// otherBindingHostnames returns the hostnames the Service is tunneled on by the TunnelBindings other than the current one,
+// leaving out the ones being deleted or expired
+func (r *TunnelBindingReconciler) otherBindingHostnames(serviceName apitypes.NamespacedName) ([]string, error) {
+	tunnelBindingList := &networkingv1alpha1.TunnelBindingList{}
+	if err := r.List(r.ctx, tunnelBindingList); err != nil {
+		return nil, fmt.Errorf("failed to list TunnelBindings: %w", err)
+	}
+	var names []string
+	for i := range tunnelBindingList.Items {
+		binding := &tunnelBindingList.Items[i]
+		if (binding.Namespace == r.binding.Namespace && binding.Name == r.binding.Name) || binding.GetDeletionTimestamp() != nil || bindingExpired(binding) {
+			continue
+		}
+		for j, subject := range binding.Subjects {
+			if j >= len(binding.Status.Services) || subjectServiceNameForBinding(binding, subject) != serviceName {
+				continue
+			}
+			if info := binding.Status.Services[j]; info.Hostname != "" && !isDirectTarget(info.Target) {
+				names = append(names, info.Hostname)
+			}
+		}
+	}
+	return names, nil
+}
+
+// subjectServiceName returns the name of the Service of the subject, in the TunnelBinding namespace unless set
+func (r *TunnelBindingReconciler) subjectServiceName(subject networkingv1alpha1.TunnelBindingSubject) apitypes.NamespacedName {
+	return subjectServiceNameForBinding(r.binding, subject)
+}
+
+// subjectServiceNameForBinding returns the name of the Service of the subject of the TunnelBinding
+func subjectServiceNameForBinding(binding *networkingv1alpha1.TunnelBinding, subject networkingv1alpha1.TunnelBindingSubject) apitypes.NamespacedName {
+	namespace := subject.Namespace
+	if namespace == "" {
+		namespace = binding.Namespace
+	}
+	return apitypes.NamespacedName{Name: subject.Name, Namespace: namespace}
+}
+
+// previousTunnelRef returns the tunnel the TunnelBinding was configured on, from the labels set in the last reconcile,
+// and whether it differs from the current tunnelRef
+func (r *TunnelBindingReconciler) previousTunnelRef() (networkingv1alpha1.TunnelRef, bool) {
+	previous := r.binding.TunnelRef
+	name, ok := r.binding.Labels[tunnelNameLabel]
+	if !ok {
+		// Not configured yet
+		return previous, false
+	}
+	previous.Name = name
+	// Bindings labeled before the kind was tracked are assumed to keep their kind
+	if isClusterTunnel, ok := r.binding.Labels[isClusterTunnelLabel]; ok {
+		if isClusterTunnel == "true" {
+			previous.Kind = "ClusterTunnel"
+		} else {
+			previous.Kind = "Tunnel"
+		}
+	}
+	return previous, previous.Name != r.binding.TunnelRef.Name || !strings.EqualFold(previous.Kind, r.binding.TunnelRef.Kind)
+}
+
+// cleanupPreviousTunnel removes the ingress rules and DNS entries of the TunnelBinding from the tunnel it was on before
+// its tunnelRef changed. If the previous tunnel does not exist anymore, there is nothing to clean up.
+func (r *TunnelBindingReconciler) cleanupPreviousTunnel() error {
+	previousRef, changed := r.previousTunnelRef()
+	if !changed {
+		return nil
+	}
+	r.log.Info("TunnelBinding moved to another tunnel, cleaning up the previous one", "previousTunnel", previousRef.Name, "previousKind", previousRef.Kind)
+	r.Recorder.Event(r.binding, corev1.EventTypeNormal, "TunnelChanged", fmt.Sprintf("Moved from %s %s, cleaning it up", previousRef.Kind, previousRef.Name))
+
+	// Reconcile the previous tunnel with a copy of the binding pointing to it
+	previousBinding := r.binding.DeepCopy()
+	previousBinding.TunnelRef = previousRef
+	previous := *r
+	if err := previous.initStruct(r.ctx, previousBinding); err != nil {
+		r.log.Info("Unable to initialize previous tunnel, skipping its cleanup", "previousTunnel", previousRef.Name, "error", err.Error())
+		return nil
+	}
+
+	// DNS entries are deleted before the ingress rules they point to, see Reconcile for the ordering
+	if !previousRef.DisableDNSUpdates {
+		for _, info := range r.binding.Status.Services {
+			if err := previous.deleteDNSLogic(recordNameForService(info)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The tunnelRef of the binding excludes it from the configuration of the previous tunnel
+	if err := previous.configureCloudflareDaemon(); err != nil {
+		r.log.Error(err, "unable to remove ingress rules from previous tunnel", "previousTunnel", previousRef.Name)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedConfigure", "Failed to remove ingress rules from the previous tunnel")
+		return err
+	}
+	return nil
+}
+
 func (r *TunnelBindingReconciler) deletionLogic() error {
+	// Services are not tunneled anymore
+	for _, sub := range r.binding.Subjects {
+		r.setResolvedFqdnAnnotation(r.subjectServiceName(sub), nil)
+	}
+
 	if controllerutil.ContainsFinalizer(r.binding, tunnelFinalizer) {
 		// Run finalization logic. If the finalization logic fails,
 		// don't remove the finalizer so that we can retry during the next reconciliation.
 
-		errors := false
+		// Keep the first failure, a later successful deletion does not clear it
 		var err error
 		for _, info := range r.binding.Status.Services {
-			if err = r.deleteDNSLogic(info.Hostname); err != nil {
-				errors = true
+			if derr := r.deleteDNSLogic(recordNameForService(info)); derr != nil && err == nil {
+				err = derr
 			}
 		}
-		if errors {
+		if err != nil && !r.dnsDeleteBackstop(err) {
 			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FinalizerNotUnset", "Not removing Finalizer due to errors")
 			return err
 		}
 
-		// Remove tunnelFinalizer. Once all finalizers have been
-		// removed, the object will be deleted.
-		controllerutil.RemoveFinalizer(r.binding, tunnelFinalizer)
-		err = r.Update(r.ctx, r.binding)
-		if err != nil {
-			r.log.Error(err, "unable to delete Finalizer")
-			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedFinalizerUnset", "Failed to remove Finalizer")
+		// Remove the ingress rules once no DNS entry points to them anymore, keeping the finalizer to retry on failure
+		if err := r.configureCloudflareDaemon(); err != nil {
+			r.log.Error(err, "unable to remove ingress rules", "key", configmapKey)
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FinalizerNotUnset", "Not removing Finalizer, failed to remove ingress rules from ConfigMap")
 			return err
 		}
-		r.Recorder.Event(r.binding, corev1.EventTypeNormal, "FinalizerUnset", "Finalizer removed")
+
+		return r.removeFinalizer()
 	}
 	// Already removed our finalizer, all good.
 	return nil
 }
 
-func (r *TunnelBindingReconciler) creationLogic() error {
+// removeFinalizer removes tunnelFinalizer. Once all finalizers have been removed, the object will be deleted.
+func (r *TunnelBindingReconciler) removeFinalizer() error {
+	controllerutil.RemoveFinalizer(r.binding, tunnelFinalizer)
+	if err := r.Update(r.ctx, r.binding); err != nil {
+		r.log.Error(err, "unable to delete Finalizer")
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedFinalizerUnset", "Failed to remove Finalizer")
+		return fmt.Errorf("failed to remove finalizer from TunnelBinding %s/%s: %w", r.binding.Namespace, r.binding.Name, err)
+	}
+	r.Recorder.Event(r.binding, corev1.EventTypeNormal, "FinalizerUnset", "Finalizer removed")
+	return nil
+}
+
+// dnsDeleteBackstop counts a failed attempt to delete the DNS entries of the TunnelBinding being deleted, and returns true
+// once MaxDNSDeleteAttempts is reached to remove the finalizer anyway. The orphaned entries keep their TXT ownership record
+// naming the tunnel for a later cleanup.
+func (r *TunnelBindingReconciler) dnsDeleteBackstop(cause error) bool {
+	if r.MaxDNSDeleteAttempts <= 0 {
+		return false
+	}
+	r.binding.Status.DNSDeleteFailures++
+	if r.binding.Status.DNSDeleteFailures < r.MaxDNSDeleteAttempts {
+		if err := r.Status().Update(r.ctx, r.binding); err != nil {
+			r.log.Error(err, "unable to record failed DNS deletion", "failures", r.binding.Status.DNSDeleteFailures)
+		}
+		return false
+	}
+
+	hostnames := make([]string, 0, len(r.binding.Status.Services))
+	for _, info := range r.binding.Status.Services {
+		hostnames = append(hostnames, recordNameForService(info))
+	}
+	r.log.Error(cause, "Giving up deleting DNS entries, leaving them orphaned", "failures", r.binding.Status.DNSDeleteFailures, "hostnames", hostnames)
+	r.Recorder.Event(r.binding, corev1.EventTypeWarning, "OrphanedDns",
+		fmt.Sprintf("Failed to delete DNS entries %d times, removing Finalizer and leaving %s on Cloudflare", r.binding.Status.DNSDeleteFailures, strings.Join(hostnames, ",")))
+	return true
+}
+
+func (r *TunnelBindingReconciler) creationLogic() (ctrl.Result, error) {
 
 	// Add labels for TunnelBinding
 	if r.binding.Labels == nil {
 		r.binding.Labels = make(map[string]string)
 	}
-	for k, v := range r.labelsForBinding() {
+	for k, v := range labelsForBinding(r.binding) {
 		r.binding.Labels[k] = v
 	}
 
 	// Update TunnelBinding resource
 	if err := r.Update(r.ctx, r.binding); err != nil {
 		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedMetaSet", "Failed to set Labels")
-		return err
+		return ctrl.Result{}, fmt.Errorf("failed to set labels on TunnelBinding %s/%s: %w", r.binding.Namespace, r.binding.Name, err)
 	}
 
 	// Add finalizer for TunnelBinding if DNS updates are not disabled
 	if r.binding.TunnelRef.DisableDNSUpdates {
-		return nil
+		r.setDNSCondition(metav1.ConditionTrue, "DNSUpdatesDisabled", "DNS entries are managed outside the operator")
+		return ctrl.Result{}, nil
 	}
 
-	if !controllerutil.ContainsFinalizer(r.binding, tunnelFinalizer) {
+	if r.annotationEnabled(tunnelNoFinalizerAnnotation) {
+		// Opted out of the finalizer, remove it if it was added before
+		if controllerutil.RemoveFinalizer(r.binding, tunnelFinalizer) {
+			if err := r.Update(r.ctx, r.binding); err != nil {
+				r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedFinalizerUnset", "Failed to remove Finalizer")
+				return ctrl.Result{}, fmt.Errorf("failed to remove finalizer from TunnelBinding %s/%s: %w", r.binding.Namespace, r.binding.Name, err)
+			}
+			r.Recorder.Event(r.binding, corev1.EventTypeNormal, "FinalizerUnset", "Finalizer removed, DNS entries are not cleaned up on deletion")
+		}
+	} else if !controllerutil.ContainsFinalizer(r.binding, tunnelFinalizer) {
 		if !controllerutil.AddFinalizer(r.binding, tunnelFinalizer) {
 			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedMetaSet", "Failed to set Finalizer")
-			return fmt.Errorf("failed to set finalizer, trying again")
+			return ctrl.Result{}, fmt.Errorf("failed to set finalizer, trying again")
 		}
 		// Update TunnelBinding resource
 		if err := r.Update(r.ctx, r.binding); err != nil {
 			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedMetaSet", "Failed to set Finalizer")
-			return err
+			return ctrl.Result{}, fmt.Errorf("failed to set finalizer on TunnelBinding %s/%s: %w", r.binding.Namespace, r.binding.Name, err)
 		}
 	}
 
 	r.Recorder.Event(r.binding, corev1.EventTypeNormal, "MetaSet", "TunnelBinding Finalizer and Labels added")
 
+	if r.annotationEnabled(tunnelDNSPausedAnnotation) {
+		r.log.Info("DNS updates paused, not creating DNS entries")
+		r.Recorder.Event(r.binding, corev1.EventTypeNormal, "DnsPaused", "DNS updates paused, not creating DNS entries")
+		r.setDNSCondition(metav1.ConditionFalse, "DNSPaused", "DNS updates paused, not creating DNS entries")
+		return ctrl.Result{}, nil
+	}
+
+	// Records cannot be created in a zone the tunnel controller found to be invalid
+	if r.zoneCondition != nil && r.zoneCondition.Status == metav1.ConditionFalse {
+		r.log.Info("Tunnel domain is not a zone of the account, not creating DNS entries", "reason", r.zoneCondition.Reason, "message", r.zoneCondition.Message)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidZone", fmt.Sprintf("Not creating DNS entries, the tunnel domain is not a valid zone: %s", r.zoneCondition.Message))
+		r.setDNSCondition(metav1.ConditionFalse, "InvalidZone", fmt.Sprintf("The tunnel domain is not a valid zone: %s", r.zoneCondition.Message))
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Defer DNS updates until cloudflared is running to serve them, else warn about it
+	ready, err := r.tunnelReady()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !ready && r.WaitForTunnelReady {
+		r.log.Info("Tunnel Deployment has no ready replicas, waiting before creating DNS entries")
+		r.Recorder.Event(r.binding, corev1.EventTypeNormal, "WaitingForTunnel", "Waiting for Tunnel Deployment to be ready before creating DNS entries")
+		r.setDNSCondition(metav1.ConditionFalse, "WaitingForTunnel", "Waiting for Tunnel Deployment to be ready before creating DNS entries")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	} else if !ready {
+		r.log.Info("Tunnel Deployment has no ready replicas, DNS entries will point to an unavailable tunnel")
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "TunnelUnavailable", "Tunnel Deployment has no ready replicas, DNS entries will point to an unavailable tunnel")
+	}
+
+	// Back off a tunnel whose Cloudflare API operations keep failing, instead of retrying for each of its TunnelBindings
+	key := tunnelKeyForBinding(r.binding, r.Namespace)
+	if ok, retryIn := r.breaker.allow(key); !ok {
+		r.log.Info("Circuit breaker of the tunnel is open, not creating DNS entries", "retryIn", retryIn)
+		r.setDNSCondition(metav1.ConditionFalse, "CircuitOpen",
+			fmt.Sprintf("Cloudflare API operations of the tunnel failed %d times in a row, backing off for %s", r.CircuitBreakerThreshold, r.CircuitBreakerCooldown))
+		return ctrl.Result{RequeueAfter: retryIn}, nil
+	}
+
 	errors := false
-	var err error
+	// Only failed Cloudflare API operations count towards the circuit breaker, not invalid specs
+	apiErrors := false
+	// Subjects sharing a hostname with different paths share its record, set from the first of them
+	recordsDone := map[string]bool{}
+	var upserts []CNameUpsert
+	// Subjects whose records are upserted, by index
+	var prepared []int
+	// err keeps the last failure to be returned, a later success does not reset it
 	// Create DNS entries
-	for _, info := range r.binding.Status.Services {
-		err = r.createDNSLogic(info.Hostname)
-		if err != nil {
+	for i, info := range r.binding.Status.Services {
+		if info.DNSName != "" && !r.cfAPI.InZone(info.DNSName) {
+			err = fmt.Errorf("dnsName %s of %s is not in the zone %s", info.DNSName, info.Hostname, r.cfAPI.Domain)
+			r.log.Error(err, "Invalid dnsName", "service", r.binding.Subjects[i].Name)
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidDnsName", err.Error())
 			errors = true
+			continue
+		}
+		if name := strings.ToLower(recordNameForService(info)); recordsDone[name] {
+			continue
+		} else {
+			recordsDone[name] = true
+		}
+		upsert, prepareErr := r.prepareDNSLogic(recordNameForService(info), r.getDNSOptionsForSubject(r.binding.Subjects[i]))
+		if prepareErr != nil {
+			errors, apiErrors, err = true, true, prepareErr
+			continue
+		}
+		upserts = append(upserts, upsert)
+		prepared = append(prepared, i)
+	}
+	// The records of all the hostnames are upserted together, saving API calls when onboarding many subjects
+	if upsertErr := r.upsertDNSLogic(upserts); upsertErr != nil {
+		errors, apiErrors, err = true, true, upsertErr
+		prepared = nil
+	}
+	for _, i := range prepared {
+		info := r.binding.Status.Services[i]
+		if srv := r.binding.Subjects[i].Spec.SRV; srv != nil {
+			if srvErr := r.createSRVLogic(recordNameForService(info), info.Target, srv); srvErr != nil {
+				errors, apiErrors, err = true, true, srvErr
+			}
+		}
+		if regionKey := r.binding.Subjects[i].Spec.RegionKey; regionKey != "" {
+			if regionErr := r.createRegionalHostnameLogic(recordNameForService(info), regionKey); regionErr != nil {
+				errors, apiErrors, err = true, true, regionErr
+			}
+		}
+		if cache := r.binding.Subjects[i].Spec.Cache; cache != nil {
+			if cacheErr := r.createCacheRuleLogic(recordNameForService(info), cache); cacheErr != nil {
+				errors, apiErrors, err = true, true, cacheErr
+			}
 		}
 	}
+	if lbErr := r.createLoadBalancerLogic(); lbErr != nil {
+		errors, apiErrors, err = true, true, lbErr
+	}
+	if !apiErrors {
+		r.breaker.success(key)
+	} else if r.breaker.failure(key) {
+		r.log.Info("Cloudflare API operations of the tunnel keep failing, opening its circuit breaker", "cooldown", r.CircuitBreakerCooldown)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "CircuitOpened",
+			fmt.Sprintf("Cloudflare API operations of tunnel %s failed %d times in a row, backing off its DNS updates for %s", r.binding.TunnelRef.Name, r.CircuitBreakerThreshold, r.CircuitBreakerCooldown))
+	}
 	if errors {
 		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedDNSCreatePartial", "Some DNS entries failed to create")
+		message := "Some DNS entries failed to create"
+		if err != nil {
+			message = fmt.Sprintf("%s: %v", message, err)
+		}
+		r.setDNSCondition(metav1.ConditionFalse, "DNSFailed", message)
+		return ctrl.Result{}, err
+	}
+	r.setDNSCondition(metav1.ConditionTrue, "Created", fmt.Sprintf("DNS entries point to tunnel %s", r.binding.TunnelRef.Name))
+	return r.certificateLogic(), nil
+}
+
+// certificateLogic reports whether the edge certificates of the hostnames are active, as HTTPS fails until they are.
+// New hostnames are checked again every minute until their certificates are active.
+func (r *TunnelBindingReconciler) certificateLogic() ctrl.Result {
+	if !r.ReportCertificateStatus || len(r.binding.Status.Services) == 0 {
+		return ctrl.Result{}
+	}
+	hostnames := make([]string, 0, len(r.binding.Status.Services))
+	for _, info := range r.binding.Status.Services {
+		hostnames = append(hostnames, recordNameForService(info))
+	}
+
+	statuses, err := r.cfAPI.GetCertificateStatuses(hostnames)
+	if err != nil {
+		r.log.Error(err, "unable to read the edge certificate status", "hostnames", hostnames)
+		r.certCondition = &metav1.Condition{Type: conditionCertificateReady, Status: metav1.ConditionUnknown, Reason: "CheckFailed", Message: err.Error()}
+		return ctrl.Result{RequeueAfter: time.Minute}
+	}
+
+	var pending []string
+	for _, hostname := range hostnames {
+		if status := statuses[hostname]; status != certificateStatusActive {
+			pending = append(pending, fmt.Sprintf("%s (%s)", hostname, status))
+		}
+	}
+	if len(pending) > 0 {
+		r.log.Info("Edge certificates are not active yet, HTTPS fails until they are", "pending", pending)
+		r.certCondition = &metav1.Condition{Type: conditionCertificateReady, Status: metav1.ConditionFalse, Reason: "Pending",
+			Message: fmt.Sprintf("Edge certificates are not active for %s", strings.Join(pending, ", "))}
+		return ctrl.Result{RequeueAfter: time.Minute}
+	}
+
+	if previous := meta.FindStatusCondition(r.binding.Status.Conditions, conditionCertificateReady); previous == nil || previous.Status != metav1.ConditionTrue {
+		r.Recorder.Event(r.binding, corev1.EventTypeNormal, "CertificateActive", fmt.Sprintf("Edge certificates are active, serving HTTPS for %s", strings.Join(hostnames, ",")))
+	}
+	r.certCondition = &metav1.Condition{Type: conditionCertificateReady, Status: metav1.ConditionTrue, Reason: "Active", Message: "Edge certificates are active"}
+	return ctrl.Result{}
+}
+
+// setDNSCondition records the DNSReady condition reported at the end of the reconcile
+func (r *TunnelBindingReconciler) setDNSCondition(status metav1.ConditionStatus, reason, message string) {
+	r.dnsCondition = &metav1.Condition{Type: conditionDNSReady, Status: status, Reason: reason, Message: message}
+}
+
+// reportConditions sets the ConfigReady, DNSReady and CertificateReady conditions of the TunnelBinding, from configErr and
+// the outcome of creationLogic. DNSReady and CertificateReady are kept as is if creationLogic did not get to them.
+// The status is only written if a condition changed, to not add a write to every reconcile.
+func (r *TunnelBindingReconciler) reportConditions(configErr error) {
+	previous := make([]metav1.Condition, len(r.binding.Status.Conditions))
+	copy(previous, r.binding.Status.Conditions)
+
+	config := metav1.Condition{Type: conditionConfigReady, Status: metav1.ConditionTrue, Reason: "Configured", Message: "Ingress rules are in the tunnel configuration"}
+	if configErr != nil {
+		config.Status, config.Reason, config.Message = metav1.ConditionFalse, "ConfigFailed", configErr.Error()
+	}
+	conditions := []metav1.Condition{config}
+	if configErr == nil && r.dnsCondition != nil {
+		conditions = append(conditions, *r.dnsCondition)
+	}
+	if configErr == nil && r.certCondition != nil {
+		conditions = append(conditions, *r.certCondition)
+	}
+	for _, condition := range conditions {
+		condition.ObservedGeneration = r.binding.Generation
+		meta.SetStatusCondition(&r.binding.Status.Conditions, condition)
+	}
+
+	if equality.Semantic.DeepEqual(previous, r.binding.Status.Conditions) {
+		return
+	}
+	if err := r.Status().Update(r.ctx, r.binding); err != nil {
+		r.log.Error(err, "unable to update TunnelBinding conditions")
+	}
+}
+
+// recordNameForService returns the name of the DNS record of the service, the hostname unless a dnsName is set
+func recordNameForService(info networkingv1alpha1.ServiceInfo) string {
+	if info.DNSName != "" {
+		return info.DNSName
+	}
+	return info.Hostname
+}
+
+// annotationEnabled checks if the boolean annotation is set to true on the TunnelBinding
+func (r *TunnelBindingReconciler) annotationEnabled(annotation string) bool {
+	value, ok := r.binding.Annotations[annotation]
+	if !ok {
+		return false
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		r.log.Info("Invalid value for annotation, ignoring", "annotation", annotation, "value", value)
+		return false
+	}
+	return enabled
+}
+
+// tunnelReady checks if the cloudflared Deployment for the tunnel has at least one ready replica
+func (r *TunnelBindingReconciler) tunnelReady() (bool, error) {
+	// The Deployment managed by another controller might not be named after the tunnel
+	if !deploymentManaged(r.tunnel) {
+		return true, nil
+	}
+	cfDeployment := &appsv1.Deployment{}
+	if err := r.Get(r.ctx, apitypes.NamespacedName{Name: r.configmap.Name, Namespace: r.configmap.Namespace}, cfDeployment); err != nil {
+		r.log.Error(err, "Error in getting deployment, cannot check readiness")
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedReadiness", "Failed to get Deployment")
+		return false, fmt.Errorf("failed to get Deployment %s/%s: %w", r.configmap.Namespace, r.configmap.Name, err)
+	}
+	return cfDeployment.Status.ReadyReplicas > 0, nil
+}
+
+// getDNSOptionsForSubject returns the options of the DNS record of the subject, falling back to the tunnel defaults
+func (r *TunnelBindingReconciler) getDNSOptionsForSubject(subject networkingv1alpha1.TunnelBindingSubject) DNSRecordOptions {
+	options := DNSRecordOptions{Proxied: true, TTL: 1}
+	if r.dnsDefaults.DefaultProxied != nil {
+		options.Proxied = *r.dnsDefaults.DefaultProxied
+	}
+	if r.dnsDefaults.DefaultTTL != 0 {
+		options.TTL = r.dnsDefaults.DefaultTTL
+	}
+	if subject.Spec.Proxied != nil {
+		options.Proxied = *subject.Spec.Proxied
+	}
+	if subject.Spec.TTL != 0 {
+		options.TTL = subject.Spec.TTL
+	}
+
+	// Cloudflare only allows the automatic TTL on proxied records
+	if options.Proxied && options.TTL != 1 {
+		r.log.Info("Proxied DNS entries always use the automatic TTL, ignoring TTL", "service", subject.Name, "ttl", options.TTL)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "IgnoredDnsTTL", fmt.Sprintf("TTL %d ignored for proxied DNS entry, svc: %s", options.TTL, subject.Name))
+		options.TTL = 1
+	}
+	return options
+}
+
+// createDNSLogic points the hostname to the tunnel, with a TXT record naming the tunnel as its owner
+func (r *TunnelBindingReconciler) createDNSLogic(hostname string, options DNSRecordOptions) error {
+	upsert, err := r.prepareDNSLogic(hostname, options)
+	if err != nil {
 		return err
 	}
-	return nil
+	return r.upsertDNSLogic([]CNameUpsert{upsert})
 }
 
-func (r *TunnelBindingReconciler) createDNSLogic(hostname string) error {
+// prepareDNSLogic checks that the hostname can be pointed to the tunnel, and returns the records to upsert for it
+func (r *TunnelBindingReconciler) prepareDNSLogic(hostname string, options DNSRecordOptions) (CNameUpsert, error) {
+	if err := validateHostname(hostname); err != nil {
+		r.log.Error(err, "Invalid hostname", "hostname", hostname)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidHostname", err.Error())
+		return CNameUpsert{}, err
+	}
+
 	txtId, dnsTxtResponse, canUseDns, err := r.cfAPI.GetManagedDnsTxt(hostname)
 	if err != nil {
 		// We should not use this entry
 		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedReadingTxt", "Failed to read existing TXT DNS entry")
-		return err
+		return CNameUpsert{}, fmt.Errorf("failed to read TXT entry for %s: %w", hostname, err)
 	}
 	if !canUseDns {
 		// We cannot use this entry
 		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedReadingTxt", fmt.Sprintf("FQDN already managed by Tunnel Name: %s, Id: %s", dnsTxtResponse.TunnelName, dnsTxtResponse.TunnelId))
-		return err
+		return CNameUpsert{}, fmt.Errorf("FQDN %s already managed by tunnel %s (%s)", hostname, dnsTxtResponse.TunnelName, dnsTxtResponse.TunnelId)
+	}
+	// Apex records need CNAME flattening on the zone
+	if r.cfAPI.IsApex(hostname) {
+		if err := r.cfAPI.ValidateApexCName(hostname); err != nil {
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedApexDns", fmt.Sprintf("Cannot route apex domain: %s", err.Error()))
+			return CNameUpsert{}, err
+		}
 	}
-	existingId, err := r.cfAPI.GetDNSCNameId(hostname)
+	existing, err := r.cfAPI.GetDNSCNameRecord(hostname)
+	existingId := existing.ID
 	// Check if a DNS record exists
 	if err == nil || existingId != "" {
 		// without a managed TXT record when we are not supposed to overwrite it
 		if !r.OverwriteUnmanaged && txtId == "" {
-			err := fmt.Errorf("unmanaged FQDN present")
+			err := fmt.Errorf("unmanaged FQDN %s present", hostname)
 			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedReadingTxt", "FQDN present but unmanaged by Tunnel")
-			return err
+			return CNameUpsert{}, err
+		}
+		if !r.cfAPI.CNameInSync(existing, options) {
+			r.log.Info("DNS entry drifted from tunnel, correcting", "hostname", hostname, "content", existing.Content)
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "DriftedDns", fmt.Sprintf("DNS entry for %s points to %s, correcting", hostname, existing.Content))
 		}
 		// To overwrite
 		dnsTxtResponse.DnsId = existingId
 	}
 
-	newDnsId, err := r.cfAPI.InsertOrUpdateCName(hostname, dnsTxtResponse.DnsId)
-	if err != nil {
-		r.log.Error(err, "Failed to insert/update DNS entry", "Hostname", hostname)
-		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedCreatingDns", fmt.Sprintf("Failed to insert/update DNS entry: %s", err.Error()))
-		return err
+	return CNameUpsert{FQDN: hostname, DnsId: dnsTxtResponse.DnsId, TxtId: txtId, Options: options}, nil
+}
+
+// upsertDNSLogic upserts the records of the hostnames, in batches when there are several of them
+func (r *TunnelBindingReconciler) upsertDNSLogic(upserts []CNameUpsert) error {
+	if len(upserts) == 0 {
+		return nil
 	}
-	if err := r.cfAPI.InsertOrUpdateTXT(hostname, txtId, newDnsId); err != nil {
-		r.log.Error(err, "Failed to insert/update TXT entry", "Hostname", hostname)
-		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedCreatingTxt", fmt.Sprintf("Failed to insert/update TXT entry: %s", err.Error()))
-		if err := r.cfAPI.DeleteDNSId(hostname, newDnsId, dnsTxtResponse.DnsId != ""); err != nil {
-			r.log.Info("Failed to delete DNS entry, left in broken state", "Hostname", hostname)
-			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedDeletingDns", "Failed to delete DNS entry, left in broken state")
-			return err
-		}
-		if dnsTxtResponse.DnsId != "" {
-			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "DeletedDns", "Deleted DNS entry, retrying")
-			r.log.Info("Deleted DNS entry", "Hostname", hostname)
-		} else {
-			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "PreventDeleteDns", "Prevented DNS entry deletion, retrying")
-			r.log.Info("Did not delete DNS entry", "Hostname", hostname)
-		}
-		return err
+	if err := r.cfAPI.InsertOrUpdateCNames(upserts); err != nil {
+		r.log.Error(err, "Failed to insert/update DNS entries", "count", len(upserts))
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedCreatingDns", fmt.Sprintf("Failed to insert/update DNS entries: %s", err.Error()))
+		return fmt.Errorf("failed to insert/update DNS entries: %w", err)
 	}
 
-	r.log.Info("Inserted/Updated DNS/TXT entry")
-	r.Recorder.Event(r.binding, corev1.EventTypeNormal, "CreatedDns", "Inserted/Updated DNS/TXT entry")
+	r.log.Info("Inserted/Updated DNS/TXT entries", "count", len(upserts))
+	r.Recorder.Event(r.binding, corev1.EventTypeNormal, "CreatedDns", fmt.Sprintf("Inserted/Updated %d DNS/TXT entries", len(upserts)))
+
+	// Verify the records on a forced sync
+	if _, ok := r.binding.Annotations[tunnelForceDNSSyncAnnotation]; !ok {
+		return nil
+	}
+	for _, upsert := range upserts {
+		record, err := r.cfAPI.GetDNSCNameRecord(upsert.FQDN)
+		if err != nil {
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedVerifyingDns", "Failed to read back DNS entry")
+			return fmt.Errorf("failed to verify DNS entry for %s: %w", upsert.FQDN, err)
+		}
+		if !r.cfAPI.CNameInSync(record, upsert.Options) {
+			err := fmt.Errorf("DNS entry for %s points to %s instead of %s", upsert.FQDN, record.Content, r.cfAPI.TunnelCName())
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedVerifyingDns", "DNS entry does not point to the Tunnel")
+			return err
+		}
+		r.log.Info("Verified DNS entry", "hostname", upsert.FQDN)
+		r.Recorder.Event(r.binding, corev1.EventTypeNormal, "VerifiedDns", "Verified DNS entry points to the Tunnel")
+	}
+	return nil
+}
+
+// createSRVLogic creates the SRV record of a tcp or udp service pointing to its hostname
+func (r *TunnelBindingReconciler) createSRVLogic(hostname, target string, srv *networkingv1alpha1.SRVSpec) error {
+	if !strings.HasPrefix(target, tunnelProtoTCP+"://") && !strings.HasPrefix(target, tunnelProtoUDP+"://") {
+		r.log.Info("SRV records are only supported for tcp and udp targets, ignoring", "hostname", hostname, "target", target)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "IgnoredSrv", fmt.Sprintf("SRV record ignored for %s, only supported for tcp and udp targets", hostname))
+		return nil
+	}
+	proto := srv.Proto
+	if proto == "" {
+		proto = tunnelProtoTCP
+	}
+	if err := r.cfAPI.SyncSRV(hostname, &SRVRecordOptions{
+		Service:  srv.Service,
+		Proto:    proto,
+		Priority: srv.Priority,
+		Weight:   srv.Weight,
+		Port:     srv.Port,
+	}); err != nil {
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedCreatingSrv", fmt.Sprintf("Failed to set SRV record for %s: %s", hostname, err.Error()))
+		return fmt.Errorf("failed to set SRV record for %s: %w", hostname, err)
+	}
 	return nil
 }
 
+// canaryGroups returns the subjects of the TunnelBinding split by weight by hostname, by their index.
+// Hostnames with invalid weights are left out and returned as errors.
+func canaryGroups(binding *networkingv1alpha1.TunnelBinding) (map[string][]int, []error) {
+	groups := map[string][]int{}
+	unweighted := map[string]bool{}
+	withPath := map[string]bool{}
+	for i, subject := range binding.Subjects {
+		if i >= len(binding.Status.Services) {
+			break
+		}
+		hostname := strings.ToLower(binding.Status.Services[i].Hostname)
+		if subject.Spec.Weight == nil {
+			unweighted[hostname] = true
+			continue
+		}
+		groups[hostname] = append(groups[hostname], i)
+		if subject.Spec.Path != "" {
+			withPath[hostname] = true
+		}
+	}
+
+	hostnames := make([]string, 0, len(groups))
+	for hostname := range groups {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	var errs []error
+	for _, hostname := range hostnames {
+		total := 0
+		for _, i := range groups[hostname] {
+			total += *binding.Subjects[i].Spec.Weight
+		}
+		var err error
+		switch {
+		case strings.HasPrefix(hostname, "*"):
+			err = fmt.Errorf("weights are not supported on the wildcard hostname %s", hostname)
+		case unweighted[hostname]:
+			err = fmt.Errorf("all the subjects with the hostname %s need a weight", hostname)
+		case withPath[hostname]:
+			err = fmt.Errorf("weighted subjects of %s cannot have a path, the Load Balancer splits all its requests", hostname)
+		case total != 100:
+			err = fmt.Errorf("the weights of %s sum to %d instead of 100", hostname, total)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			delete(groups, hostname)
+		}
+	}
+	return groups, errs
+}
+
+// canaryOriginHostname returns the hostname of the ingress rule of a weighted subject, which its Load Balancer origin sends as Host header
+func canaryOriginHostname(hostname string, subject int) string {
+	return fmt.Sprintf("canary-%d.%s", subject, strings.ToLower(hostname))
+}
+
+// canaryOrigins returns the hostnames of the ingress rules of the subjects balanced by a Load Balancer, by subject index
+func (r *TunnelBindingReconciler) canaryOrigins(binding *networkingv1alpha1.TunnelBinding) map[int]string {
+	if !r.ManageLoadBalancers {
+		return nil
+	}
+	groups, _ := canaryGroups(binding)
+	origins := map[int]string{}
+	for _, subjects := range groups {
+		for _, i := range subjects {
+			if i >= len(binding.Status.Services) {
+				break
+			}
+			origins[i] = canaryOriginHostname(binding.Status.Services[i].Hostname, i)
+		}
+	}
+	return origins
+}
+
+// createLoadBalancerLogic sets the Load Balancers splitting the requests to the hostnames of the weighted subjects,
+// and removes the ones of the hostnames not weighted anymore. It is skipped unless Load Balancers are managed.
+func (r *TunnelBindingReconciler) createLoadBalancerLogic() error {
+	groups, invalid := canaryGroups(r.binding)
+	if !r.ManageLoadBalancers {
+		if len(groups) > 0 || len(invalid) > 0 {
+			r.log.Info("Load Balancers are not managed, ignoring weights")
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "IgnoredWeight", "Ignoring subject weights, the operator does not manage Load Balancers")
+		}
+		return nil
+	}
+	for _, err := range invalid {
+		r.log.Error(err, "Invalid weights, not load balancing the hostname")
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidWeight", err.Error())
+	}
+
+	owner := r.binding.Namespace + "/" + r.binding.Name
+	managed, err := r.cfAPI.ManagedLoadBalancers(owner)
+	if err != nil {
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedLoadBalancer", fmt.Sprintf("Failed to list Load Balancers: %s", err.Error()))
+		return fmt.Errorf("failed to list Load Balancers of TunnelBinding %s: %w", owner, err)
+	}
+	var lastErr error
+	for _, hostname := range managed {
+		if _, ok := groups[strings.ToLower(hostname)]; ok {
+			continue
+		}
+		if err := r.cfAPI.SyncLoadBalancer(hostname, owner, nil); err != nil {
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedLoadBalancer", fmt.Sprintf("Failed to delete Load Balancer for %s: %s", hostname, err.Error()))
+			lastErr = fmt.Errorf("failed to delete Load Balancer for %s: %w", hostname, err)
+		}
+	}
+	for hostname, subjects := range groups {
+		origins := make([]LoadBalancerOrigin, 0, len(subjects))
+		for _, i := range subjects {
+			origins = append(origins, LoadBalancerOrigin{Hostname: canaryOriginHostname(hostname, i), Weight: *r.binding.Subjects[i].Spec.Weight})
+		}
+		if err := r.cfAPI.SyncLoadBalancer(hostname, owner, origins); err != nil {
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedLoadBalancer", fmt.Sprintf("Failed to set Load Balancer for %s: %s", hostname, err.Error()))
+			lastErr = fmt.Errorf("failed to set Load Balancer for %s: %w", hostname, err)
+		}
+	}
+	return lastErr
+}
+
+// createCacheRuleLogic sets the cache rule of the hostname, skipping it unless cache rules are managed
+func (r *TunnelBindingReconciler) createCacheRuleLogic(hostname string, cache *networkingv1alpha1.CacheSpec) error {
+	if !r.ManageCacheRules {
+		r.log.Info("Cache rules are not managed, ignoring cache settings", "hostname", hostname)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "IgnoredCache", fmt.Sprintf("Ignoring cache settings for %s, the operator does not manage cache rules", hostname))
+		return nil
+	}
+	if err := r.cfAPI.SyncCacheRule(hostname, &CacheRuleOptions{
+		Bypass:     cache.Bypass,
+		EdgeTTL:    uint(cache.EdgeTTL),
+		BrowserTTL: uint(cache.BrowserTTL),
+	}); err != nil {
+		r.log.Error(err, "Failed to set cache rule", "hostname", hostname)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedCacheRule", fmt.Sprintf("Failed to set cache rule for %s: %s", hostname, err.Error()))
+		return fmt.Errorf("failed to set cache rule for %s: %w", hostname, err)
+	}
+	return nil
+}
+
+// createRegionalHostnameLogic sets the Data Localization region of the hostname, skipping it if the account is not entitled
+func (r *TunnelBindingReconciler) createRegionalHostnameLogic(hostname, regionKey string) error {
+	err := r.cfAPI.SyncRegionalHostname(hostname, regionKey)
+	if errors.Is(err, errNotEntitled) {
+		r.log.Info("Account is not entitled to Regional Services, skipping regionKey", "hostname", hostname, "error", err.Error())
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "NotEntitled", fmt.Sprintf("Skipping regionKey %s for %s, the account is not entitled to Regional Services", regionKey, hostname))
+		return nil
+	} else if err != nil {
+		r.log.Error(err, "Failed to set regional hostname", "hostname", hostname, "regionKey", regionKey)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedRegionalHostname", fmt.Sprintf("Failed to set regionKey %s for %s: %s", regionKey, hostname, err.Error()))
+		return fmt.Errorf("failed to set regionKey %s for %s: %w", regionKey, hostname, err)
+	}
+	return nil
+}
+
+// dnsRecordUsers returns the other TunnelBindings of the tunnel with a subject using the DNS record, like another path
+// of the same hostname. Bindings being deleted, expired or not managing their DNS records are not counted.
+func (r *TunnelBindingReconciler) dnsRecordUsers(name string) ([]string, error) {
+	bindings, err := r.getRelevantTunnelBindings()
+	if err != nil {
+		return nil, err
+	}
+	var users []string
+	for _, binding := range bindings {
+		if binding.Namespace == r.binding.Namespace && binding.Name == r.binding.Name {
+			continue
+		}
+		if binding.GetDeletionTimestamp() != nil || binding.TunnelRef.DisableDNSUpdates || bindingExpired(&binding) {
+			continue
+		}
+		for _, info := range binding.Status.Services {
+			if strings.EqualFold(recordNameForService(info), name) {
+				users = append(users, binding.Namespace+"/"+binding.Name)
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
 func (r *TunnelBindingReconciler) deleteDNSLogic(hostname string) error {
+	// The Load Balancer of weighted subjects is owned by the TunnelBinding alone
+	if r.ManageLoadBalancers {
+		if err := r.cfAPI.SyncLoadBalancer(hostname, r.binding.Namespace+"/"+r.binding.Name, nil); err != nil {
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedLoadBalancer", fmt.Sprintf("Failed to delete Load Balancer: %s", err.Error()))
+			return fmt.Errorf("failed to delete load balancer for %s: %w", hostname, err)
+		}
+	}
+
+	// The record stays while other TunnelBindings of the tunnel use it
+	users, err := r.dnsRecordUsers(hostname)
+	if err != nil {
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedDeletingDns", "Failed to check if other TunnelBindings use the DNS entry")
+		return err
+	}
+	if len(users) > 0 {
+		r.log.Info("DNS entry still used by other TunnelBindings, not deleting", "hostname", hostname, "bindings", users)
+		r.Recorder.Event(r.binding, corev1.EventTypeNormal, "KeptDns", fmt.Sprintf("Keeping DNS entry %s, still used by %s", hostname, strings.Join(users, ",")))
+		return nil
+	}
+
 	// Delete DNS entry
 	txtId, dnsTxtResponse, canUseDns, err := r.cfAPI.GetManagedDnsTxt(hostname)
 	if err != nil {
@@ -361,27 +1300,45 @@ func (r *TunnelBindingReconciler) deleteDNSLogic(hostname string) error {
 	} else if !canUseDns {
 		// We cannot use this entry. This should be happen if all controllers are using DNS management with the same prefix.
 		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedReadingTxt", fmt.Sprintf("FQDN already managed by Tunnel Name: %s, Id: %s, not cleaning up", dnsTxtResponse.TunnelName, dnsTxtResponse.TunnelId))
+	} else if txtId == "" {
+		// No TXT entry, the DNS entry was never created by us, for example while DNS updates were paused
+		r.log.Info("No managed DNS entry found, nothing to clean up", "hostname", hostname)
 	} else {
 		if id, err := r.cfAPI.GetDNSCNameId(hostname); err != nil {
-			r.log.Error(err, "Error fetching DNS record", "Hostname", hostname)
+			r.log.Error(err, "Error fetching DNS record", "hostname", hostname)
 			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedDeletingDns", "Error fetching DNS record")
 		} else if id != dnsTxtResponse.DnsId {
 			err := fmt.Errorf("DNS ID from TXT and real DNS record does not match")
-			r.log.Error(err, "DNS ID from TXT and real DNS record does not match", "Hostname", hostname)
+			r.log.Error(err, "DNS ID from TXT and real DNS record does not match", "hostname", hostname)
 			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedDeletingDns", "DNS/TXT ID Mismatch")
 		} else {
+			if err := r.cfAPI.SyncSRV(hostname, nil); err != nil {
+				r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedDeletingSrv", fmt.Sprintf("Failed to delete SRV records: %s", err.Error()))
+				return fmt.Errorf("failed to delete SRV records for %s: %w", hostname, err)
+			}
+			// Accounts without Regional Services have no regional hostname to delete
+			if err := r.cfAPI.SyncRegionalHostname(hostname, ""); err != nil && !errors.Is(err, errNotEntitled) {
+				r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedRegionalHostname", fmt.Sprintf("Failed to delete regional hostname: %s", err.Error()))
+				return fmt.Errorf("failed to delete regional hostname %s: %w", hostname, err)
+			}
+			if r.ManageCacheRules {
+				if err := r.cfAPI.SyncCacheRule(hostname, nil); err != nil {
+					r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedCacheRule", fmt.Sprintf("Failed to delete cache rule: %s", err.Error()))
+					return fmt.Errorf("failed to delete cache rule for %s: %w", hostname, err)
+				}
+			}
 			if err := r.cfAPI.DeleteDNSId(hostname, dnsTxtResponse.DnsId, true); err != nil {
-				r.log.Info("Failed to delete DNS entry", "Hostname", hostname)
+				r.log.Info("Failed to delete DNS entry", "hostname", hostname)
 				r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedDeletingDns", fmt.Sprintf("Failed to delete DNS entry: %s", err.Error()))
-				return err
+				return fmt.Errorf("failed to delete DNS entry for %s: %w", hostname, err)
 			}
-			r.log.Info("Deleted DNS entry", "Hostname", hostname)
+			r.log.Info("Deleted DNS entry", "hostname", hostname)
 			r.Recorder.Event(r.binding, corev1.EventTypeNormal, "DeletedDns", "Deleted DNS entry")
 			if err := r.cfAPI.DeleteDNSId(hostname, txtId, true); err != nil {
 				r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedDeletingTxt", fmt.Sprintf("Failed to delete TXT entry: %s", err.Error()))
-				return err
+				return fmt.Errorf("failed to delete TXT entry for %s: %w", hostname, err)
 			}
-			r.log.Info("Deleted DNS TXT entry", "Hostname", hostname)
+			r.log.Info("Deleted DNS TXT entry", "hostname", hostname)
 			r.Recorder.Event(r.binding, corev1.EventTypeNormal, "DeletedTxt", "Deleted DNS TXT entry")
 		}
 	}
@@ -389,17 +1346,15 @@ func (r *TunnelBindingReconciler) deleteDNSLogic(hostname string) error {
 }
 
 func (r *TunnelBindingReconciler) getRelevantTunnelBindings() ([]networkingv1alpha1.TunnelBinding, error) {
-	// Fetch TunnelBindings from API
-	listOpts := []client.ListOption{client.MatchingLabels(map[string]string{
-		tunnelNameLabel: r.binding.TunnelRef.Name,
-		tunnelKindLabel: r.binding.Kind,
-	})}
+	// Fetch TunnelBindings from the cache index on their tunnelRef, which also skips bindings moved to another tunnel
+	listOpts := []client.ListOption{client.MatchingFields{
+		tunnelRefIndex: tunnelKeyForBinding(r.binding, r.Namespace).String(),
+	}}
 	tunnelBindingList := &networkingv1alpha1.TunnelBindingList{}
 	if err := r.List(r.ctx, tunnelBindingList, listOpts...); err != nil {
 		r.log.Error(err, "failed to list Tunnel Bindings", "listOpts", listOpts)
-		return tunnelBindingList.Items, err
+		return tunnelBindingList.Items, fmt.Errorf("failed to list TunnelBindings for %s %s: %w", r.binding.TunnelRef.Kind, r.binding.TunnelRef.Name, err)
 	}
-
 	bindings := tunnelBindingList.Items
 
 	if len(bindings) == 0 {
@@ -407,8 +1362,12 @@ func (r *TunnelBindingReconciler) getRelevantTunnelBindings() ([]networkingv1alp
 		r.log.Info("No tunnelBindings found, tunnel not in use")
 	}
 
-	// Sort by binding name for idempotent config generation
+	// Sort by group then binding name for idempotent config generation, ungrouped bindings first
 	sort.Slice(bindings, func(i, j int) bool {
+		groupI, groupJ := bindings[i].Annotations[tunnelGroupAnnotation], bindings[j].Annotations[tunnelGroupAnnotation]
+		if groupI != groupJ {
+			return groupI < groupJ
+		}
 		return bindings[i].Name < bindings[j].Name
 	})
 
@@ -427,45 +1386,236 @@ func (r TunnelBindingReconciler) getConfigForSubject(subject networkingv1alpha1.
 		r.log.Info("using default domain value", "domain", r.cfAPI.Domain)
 	}
 
+	if err := validatePath(subject.Spec.Path); err != nil {
+		r.log.Error(err, "invalid path", "service", subject.Name)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidPath", fmt.Sprintf("Invalid path %q, svc: %s", subject.Spec.Path, subject.Name))
+		return hostname, target, err
+	}
+
+	if _, err := validateAccess(subject.Spec.Access); err != nil {
+		r.log.Error(err, "invalid access", "service", subject.Name)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidAccess", fmt.Sprintf("Invalid access config, svc: %s: %s", subject.Name, err.Error()))
+		return hostname, target, err
+	}
+
+	// Bastion mode lets the client pick the destination, the Service is not used
+	if subject.Spec.BastionMode {
+		if err := validateBastionSubject(subject); err != nil {
+			r.log.Error(err, "invalid bastion mode subject", "service", subject.Name)
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidBastion", err.Error())
+			return hostname, target, err
+		}
+		r.log.Info("generated cloudflare config", "hostname", hostname, "target", bastionService)
+		return hostname, bastionService, nil
+	}
+
+	// Unix sockets are shared with cloudflared directly, the Service is not used
+	if strings.HasPrefix(subject.Spec.Target, unixSocketPrefix) {
+		socketTarget, err := normalizeUnixSocketTarget(subject.Spec.Target)
+		if err != nil {
+			r.log.Error(err, "invalid unix socket target", "service", subject.Name)
+			return hostname, target, err
+		}
+		r.log.Info("generated cloudflare config", "hostname", hostname, "target", socketTarget)
+		return hostname, socketTarget, nil
+	}
+
+	// IP literal targets point to the backend directly, like a VIP, the Service is not used
+	if scheme, host, port, ok, err := parseIPTarget(subject.Spec.Target); ok {
+		if err != nil {
+			r.log.Error(err, "invalid IP target", "service", subject.Name)
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidTarget", fmt.Sprintf("Invalid target for svc %s: %s", subject.Name, err.Error()))
+			return hostname, target, err
+		}
+		if scheme == "" {
+			scheme = r.getServiceProto(subject.Spec.Protocol, corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: port})
+		}
+		ipTarget := fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(int(port))))
+		r.log.Info("generated cloudflare config", "hostname", hostname, "target", ipTarget)
+		return hostname, ipTarget, nil
+	}
+
 	service := &corev1.Service{}
-	if err := r.Get(r.ctx, apitypes.NamespacedName{Name: subject.Name, Namespace: r.binding.Namespace}, service); err != nil {
+	serviceName := r.subjectServiceName(subject)
+	if err := r.Get(r.ctx, serviceName, service); err != nil {
 		r.log.Error(err, "Error getting referenced service")
 		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedService", "Failed to get Service")
+		return hostname, target, fmt.Errorf("failed to get Service %s: %w", serviceName, err)
+	}
+
+	if !r.serviceTypeAllowed(service.Spec.Type) {
+		err := fmt.Errorf("type %s of service %s/%s is not allowed to be tunneled", service.Spec.Type, service.Namespace, service.Name)
+		r.log.Error(err, "service type not allowed", "service", service.Name, "type", service.Spec.Type)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "DisallowedServiceType", fmt.Sprintf("Service %s of type %s is not allowed to be tunneled", service.Name, service.Spec.Type))
 		return hostname, target, err
 	}
 
+	if service.Spec.Type == corev1.ServiceTypeExternalName {
+		return r.getConfigForExternalName(subject, service, hostname)
+	}
+
 	if len(service.Spec.Ports) == 0 {
-		err := fmt.Errorf("no ports found in service spec, cannot proceed")
-		r.log.Error(err, "unable to read service ports", "svc", service.Name)
+		err := fmt.Errorf("no ports found in service %s/%s spec, cannot proceed", service.Namespace, service.Name)
+		r.log.Error(err, "unable to read service ports", "service", service.Name)
 		return hostname, target, err
 	} else if len(service.Spec.Ports) > 1 {
-		r.log.Info("Multiple ports definition found, picking the first in the list", "svc", service.Name)
+		r.log.Info("Multiple ports definition found, picking the first in the list", "service", service.Name)
 	}
 
 	servicePort := service.Spec.Ports[0]
-	tunnelProto := subject.Spec.Protocol
-	validProto := tunnelValidProtoMap[tunnelProto]
+	serviceProto := r.getServiceProto(subject.Spec.Protocol, servicePort)
 
-	serviceProto := r.getServiceProto(tunnelProto, validProto, servicePort)
+	r.log.Info("Selected protocol", "protocol", serviceProto)
+
+	port := servicePort.Port
+	if subject.Spec.DirectPods {
+		port = r.directPodsPort(service, servicePort)
+	}
+	target = fmt.Sprintf("%s://%s:%d", serviceProto, serviceHost(r.tunnel, service.Name, service.Namespace), port)
+	if err := validateServiceURL(target); err != nil {
+		r.log.Error(err, "invalid generated target", "service", service.Name)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidTarget", fmt.Sprintf("Invalid target generated for Service %s: %s", service.Name, err.Error()))
+		return hostname, "http_status:404", err
+	}
+
+	r.log.Info("generated cloudflare config", "hostname", hostname, "target", target)
+
+	return hostname, target, nil
+}
+
+// directPodsPort returns the port to reach the pods of a headless Service on, skipping kube-proxy. It falls back to the port
+// of the Service with a Warning Event if the Service has a ClusterIP, or a named targetPort that only the Endpoints resolve.
+func (r *TunnelBindingReconciler) directPodsPort(service *corev1.Service, servicePort corev1.ServicePort) int32 {
+	if service.Spec.ClusterIP != corev1.ClusterIPNone {
+		r.log.Info("directPods is only supported for headless Services, ignoring", "service", service.Name)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "IgnoredDirectPods", fmt.Sprintf("Service %s is not headless, requests go through its ClusterIP", service.Name))
+		return servicePort.Port
+	}
+	switch {
+	case servicePort.TargetPort.Type == intstr.String:
+		r.log.Info("Named targetPort cannot be used with directPods, ignoring", "service", service.Name, "targetPort", servicePort.TargetPort.StrVal)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "IgnoredDirectPods",
+			fmt.Sprintf("Named targetPort %s of Service %s cannot be used with directPods, set a numeric targetPort", servicePort.TargetPort.StrVal, service.Name))
+		return servicePort.Port
+	case servicePort.TargetPort.IntVal == 0:
+		// The targetPort defaults to the port
+		return servicePort.Port
+	}
+	return servicePort.TargetPort.IntVal
+}
+
+// h2cOrigin checks if the target of the subject is the first port of its Service, with an h2c appProtocol and served over http
+func (r TunnelBindingReconciler) h2cOrigin(subject networkingv1alpha1.TunnelBindingSubject, target string) bool {
+	if !strings.HasPrefix(target, tunnelProtoHTTP+"://") || isDirectTarget(subject.Spec.Target) {
+		return false
+	}
+	service := &corev1.Service{}
+	if err := r.Get(r.ctx, r.subjectServiceName(subject), service); err != nil {
+		return false
+	}
+	if service.Spec.Type == corev1.ServiceTypeExternalName || len(service.Spec.Ports) == 0 {
+		return false
+	}
+	appProtocol := service.Spec.Ports[0].AppProtocol
+	if appProtocol == nil || !isH2CAppProtocol(*appProtocol) {
+		return false
+	}
+	r.log.Info("Using http2Origin for the h2c appProtocol of the port", "service", service.Name, "appProtocol", *appProtocol)
+	return true
+}
+
+// getConfigForExternalName returns the target for an ExternalName Service, pointing to the external name directly
+// on the port of the subject, or on the first port of the Service
+func (r *TunnelBindingReconciler) getConfigForExternalName(subject networkingv1alpha1.TunnelBindingSubject, service *corev1.Service, hostname string) (string, string, error) {
+	target := "http_status:404"
+	if service.Spec.ExternalName == "" {
+		err := fmt.Errorf("ExternalName service %s/%s has no externalName", service.Namespace, service.Name)
+		r.log.Error(err, "unable to read external name", "service", service.Name)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidExternalName", fmt.Sprintf("Service %s has no externalName", service.Name))
+		return hostname, target, err
+	}
+
+	servicePort := corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: subject.Spec.Port}
+	if servicePort.Port == 0 {
+		if len(service.Spec.Ports) == 0 {
+			err := fmt.Errorf("no port set for ExternalName service %s/%s, set it on the subject", service.Namespace, service.Name)
+			r.log.Error(err, "unable to find external port", "service", service.Name)
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidExternalName", fmt.Sprintf("No port set for ExternalName Service %s, set it on the subject", service.Name))
+			return hostname, target, err
+		}
+		servicePort = service.Spec.Ports[0]
+	}
+	serviceProto := r.getServiceProto(subject.Spec.Protocol, servicePort)
 
 	r.log.Info("Selected protocol", "protocol", serviceProto)
 
-	target = fmt.Sprintf("%s://%s.%s.svc:%d", serviceProto, service.Name, service.Namespace, servicePort.Port)
+	target = fmt.Sprintf("%s://%s:%d", serviceProto, service.Spec.ExternalName, servicePort.Port)
+	if err := validateServiceURL(target); err != nil {
+		r.log.Error(err, "invalid generated target", "service", service.Name)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidTarget", fmt.Sprintf("Invalid target generated for Service %s: %s", service.Name, err.Error()))
+		return hostname, "http_status:404", err
+	}
 
 	r.log.Info("generated cloudflare config", "hostname", hostname, "target", target)
 
 	return hostname, target, nil
 }
 
-// getServiceProto returns the service protocol to be used
-func (r *TunnelBindingReconciler) getServiceProto(tunnelProto string, validProto bool, servicePort corev1.ServicePort) string {
-	var serviceProto string
-	if tunnelProto != "" && !validProto {
-		r.log.Info("Invalid Protocol provided, following default protocol logic")
+// serviceTypeAllowed checks if Services of the type can be tunneled
+func (r *TunnelBindingReconciler) serviceTypeAllowed(serviceType corev1.ServiceType) bool {
+	if len(r.AllowedServiceTypes) == 0 {
+		return true
+	}
+	// An empty type defaults to ClusterIP
+	if serviceType == "" {
+		serviceType = corev1.ServiceTypeClusterIP
+	}
+	for _, allowed := range r.AllowedServiceTypes {
+		if allowed == serviceType {
+			return true
+		}
+	}
+	return false
+}
+
+// getServiceProto returns the service protocol to be used.
+// tunnelProto can be a comma separated preference list like "https,http". The protocol the port defaults to
+// is picked if it is in the list, else the first valid protocol in the list.
+func (r *TunnelBindingReconciler) getServiceProto(tunnelProto string, servicePort corev1.ServicePort) string {
+	defaultProto := r.getDefaultServiceProto(tunnelProto, servicePort)
+	if tunnelProto == "" {
+		return defaultProto
 	}
 
-	if tunnelProto != "" && validProto {
-		serviceProto = tunnelProto
+	var preferred []string
+	for _, proto := range strings.Split(tunnelProto, ",") {
+		proto = strings.TrimSpace(proto)
+		if !tunnelValidProtoMap[proto] {
+			r.log.Info("Invalid Protocol provided, ignoring", "protocol", proto)
+			continue
+		}
+		if proto == defaultProto {
+			r.log.Info("Protocol preference matches the port default", "protocols", tunnelProto, "protocol", proto, "port", servicePort.Port)
+			return proto
+		}
+		preferred = append(preferred, proto)
+	}
+
+	if len(preferred) == 0 {
+		r.log.Info("No valid Protocol provided, following default protocol logic", "protocols", tunnelProto)
+		return defaultProto
+	}
+	r.log.Info("Picked first valid Protocol from preference", "protocols", tunnelProto, "protocol", preferred[0], "port", servicePort.Port)
+	return preferred[0]
+}
+
+// getDefaultServiceProto returns the service protocol to be used based on the port
+func (r *TunnelBindingReconciler) getDefaultServiceProto(tunnelProto string, servicePort corev1.ServicePort) string {
+	var serviceProto string
+	if servicePort.Protocol == corev1.ProtocolTCP && servicePort.AppProtocol != nil && protoForAppProtocol(*servicePort.AppProtocol) != "" {
+		// The appProtocol of the port is a stronger signal than its number
+		serviceProto = protoForAppProtocol(*servicePort.AppProtocol)
+		r.log.Info("Using the appProtocol of the port", "appProtocol", *servicePort.AppProtocol, "protocol", serviceProto)
 	} else if servicePort.Protocol == corev1.ProtocolTCP {
 		// Default protocol selection logic
 		switch servicePort.Port {
@@ -493,54 +1643,164 @@ func (r *TunnelBindingReconciler) getConfigMapConfiguration() (*Configuration, e
 	// Read ConfigMap YAML
 	configStr, ok := r.configmap.Data[configmapKey]
 	if !ok {
-		err := fmt.Errorf("unable to find key `%s` in ConfigMap", configmapKey)
+		err := fmt.Errorf("unable to find key `%s` in ConfigMap %s/%s", configmapKey, r.configmap.Namespace, r.configmap.Name)
 		r.log.Error(err, "unable to find key in ConfigMap", "key", configmapKey)
 		return &Configuration{}, err
 	}
 
 	config := &Configuration{}
 	if err := yaml.Unmarshal([]byte(configStr), config); err != nil {
-		r.log.Error(err, "unable to read config as YAML")
-		return &Configuration{}, err
+		// Failing would wedge every TunnelBinding of the tunnel, the ingress rules are rebuilt from all of them anyway
+		r.log.Error(err, "unable to read config as YAML, backing it up and rebuilding it", "backupKey", configmapCorruptKey)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "CorruptConfig",
+			fmt.Sprintf("ConfigMap %s/%s has an unparseable %s, backed up to %s and rebuilt", r.configmap.Namespace, r.configmap.Name, configmapKey, configmapCorruptKey))
+		r.configmap.Data[configmapCorruptKey] = configStr
+		initial := initialConfigurationForTunnel(r.tunnel)
+		return &initial, nil
 	}
 	return config, nil
 }
 
+// templateConfiguration returns the configuration template of the tunnel with the tunnel, credentials and ingress rules of the configuration
+func (r *TunnelBindingReconciler) templateConfiguration(template *networkingv1alpha1.ConfigTemplateSpec, configStr string) (string, error) {
+	key := template.Key
+	if key == "" {
+		key = configmapKey
+	}
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(r.ctx, apitypes.NamespacedName{Name: template.ConfigMapName, Namespace: r.configmap.Namespace}, cm); err != nil {
+		return "", fmt.Errorf("failed to get configuration template ConfigMap %s: %w", template.ConfigMapName, err)
+	}
+	templateStr, ok := cm.Data[key]
+	if !ok {
+		return "", fmt.Errorf("unable to find key `%s` in configuration template ConfigMap %s", key, template.ConfigMapName)
+	}
+	templated, err := applyConfigTemplate([]byte(templateStr), []byte(configStr))
+	if err != nil {
+		return "", fmt.Errorf("%s in ConfigMap %s: %w", key, template.ConfigMapName, err)
+	}
+	return string(templated), nil
+}
+
+// configMapDataSize returns the size of the data of the ConfigMap as counted by the API server against its limit
+func configMapDataSize(cm *corev1.ConfigMap) int {
+	size := 0
+	for _, value := range cm.Data {
+		size += len(value)
+	}
+	for _, value := range cm.BinaryData {
+		size += len(value)
+	}
+	return size
+}
+
 func (r *TunnelBindingReconciler) setConfigMapConfiguration(config *Configuration) error {
 	// Push updated changes
 	var configStr string
-	if configBytes, err := yaml.Marshal(config); err == nil {
+	if configBytes, err := marshalConfiguration(config); err == nil {
 		configStr = string(configBytes)
 	} else {
 		r.log.Error(err, "unable to marshal config to ConfigMap", "key", configmapKey)
-		return err
+		return fmt.Errorf("failed to marshal config for ConfigMap %s/%s: %w", r.configmap.Namespace, r.configmap.Name, err)
 	}
+	if template := r.tunnel.GetSpec().ConfigTemplate; template != nil {
+		templated, err := r.templateConfiguration(template, configStr)
+		if err != nil {
+			r.log.Error(err, "unable to apply configuration template, not updating ConfigMap", "configMap", template.ConfigMapName)
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidConfigTemplate", fmt.Sprintf("Not updating ConfigMap: %s", err.Error()))
+			return fmt.Errorf("failed to apply configuration template for ConfigMap %s/%s: %w", r.configmap.Namespace, r.configmap.Name, err)
+		}
+		configStr = templated
+	}
+	// cloudflared would refuse to start with the configuration, keep the one running
+	if r.ValidateIngress {
+		if err := validateIngress(config.Ingress, r.manageCatchAll); err != nil {
+			r.log.Error(err, "invalid ingress rules, not updating ConfigMap", "key", configmapKey)
+			r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidConfig", fmt.Sprintf("Not updating ConfigMap, cloudflared would reject it: %s", err.Error()))
+			return fmt.Errorf("invalid configuration for ConfigMap %s/%s: %w", r.configmap.Namespace, r.configmap.Name, err)
+		}
+	}
+	previous := r.configmap.Data[configmapKey]
 	r.configmap.Data[configmapKey] = configStr
+	// The API server refuses ConfigMaps over the limit with an opaque size error, and cloudflared cannot merge several configurations
+	if size := configMapDataSize(r.configmap); size > configMapMaxSize {
+		err := fmt.Errorf("configuration of %d bytes exceeds the ConfigMap limit of %d bytes with %d ingress rules, spread the TunnelBindings over several tunnels or use wildcard hostnames", size, configMapMaxSize, len(config.Ingress))
+		r.log.Error(err, "configuration too large, not updating ConfigMap", "key", configmapKey)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "ConfigTooLarge", fmt.Sprintf("Not updating ConfigMap: %s", err.Error()))
+		return fmt.Errorf("invalid configuration for ConfigMap %s/%s: %w", r.configmap.Namespace, r.configmap.Name, err)
+	} else if size*100 > configMapMaxSize*configMapWarnPercent {
+		r.log.Info("Configuration nearly exceeds the ConfigMap size limit", "size", size, "limit", configMapMaxSize)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "ConfigNearSizeLimit", fmt.Sprintf("Configuration of %d bytes is close to the ConfigMap limit of %d bytes, spread the TunnelBindings over several tunnels before it is reached", size, configMapMaxSize))
+	}
+	// Log what changes for review, the ConfigMap might be unparseable if it is being rebuilt
+	current := &Configuration{}
+	if err := yaml.Unmarshal([]byte(previous), current); err == nil {
+		if diff, err := configDiff(current, config); err != nil {
+			r.log.Error(err, "unable to diff configuration", "key", configmapKey)
+		} else if diff != "" {
+			r.log.Info("Updating tunnel configuration", "configMap", r.configmap.Name, "diff", diff)
+		}
+	}
+	// A Deployment managed by another controller reloads on the checksum of the ConfigMap, like through a pod template annotation
+	unmanaged := !deploymentManaged(r.tunnel)
+	if unmanaged && r.restartOnConfig {
+		if r.configmap.Annotations == nil {
+			r.configmap.Annotations = map[string]string{}
+		}
+		r.configmap.Annotations[tunnelConfigChecksum] = configChecksum(configStr)
+	}
 	if err := r.Update(r.ctx, r.configmap); err != nil {
 		r.log.Error(err, "unable to marshal config to ConfigMap", "key", configmapKey)
-		return err
+		return fmt.Errorf("failed to update ConfigMap %s/%s: %w", r.configmap.Namespace, r.configmap.Name, err)
+	}
+
+	// cloudflared might take its configuration from elsewhere, like a remote-managed tunnel
+	if !r.restartOnConfig {
+		r.log.Info("Restart on config change disabled, not restarting")
+		return nil
+	}
+	if unmanaged {
+		r.log.Info("Deployment not managed by the operator, signalled the configuration change on the ConfigMap", "annotation", tunnelConfigChecksum)
+		return nil
 	}
 
 	// Set checksum as annotation on Deployment, causing a restart of the Pods to take config
 	cfDeployment := &appsv1.Deployment{}
-	if err := r.Get(r.ctx, apitypes.NamespacedName{Name: r.configmap.Name, Namespace: r.configmap.Namespace}, cfDeployment); err != nil {
+	if err := r.Get(r.ctx, apitypes.NamespacedName{Name: r.configmap.Name, Namespace: r.configmap.Namespace}, cfDeployment); err != nil && apierrors.IsNotFound(err) {
+		// Deleted out-of-band, the tunnel controller recreates it with the updated ConfigMap
+		r.log.Info("Deployment not found, not restarting", "deployment", r.configmap.Name)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "DeploymentMissing", "Tunnel Deployment not found, configuration applies once it is recreated")
+		return nil
+	} else if err != nil {
 		r.log.Error(err, "Error in getting deployment, failed to restart")
 		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedConfigure", "Failed to get Deployment")
-		return err
+		return fmt.Errorf("failed to get Deployment %s/%s: %w", r.configmap.Namespace, r.configmap.Name, err)
+	}
+	// Nothing to restart if cloudflared is scaled to zero, new pods read the updated ConfigMap when scaled up
+	if cfDeployment.Spec.Replicas != nil && *cfDeployment.Spec.Replicas == 0 {
+		r.log.Info("Deployment scaled to zero, not restarting")
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "TunnelScaledDown", "Tunnel Deployment is scaled to zero, configuration applies once it is scaled up")
+		return nil
+	}
+
+	checksum := configChecksum(configStr)
+	// The tunnel controller might have rolled out this configuration already, along with a Deployment change
+	if cfDeployment.Spec.Template.Annotations[tunnelConfigChecksum] == checksum {
+		r.log.Info("Deployment already runs this configuration, not restarting")
+		return nil
 	}
-	hash := md5.Sum([]byte(configStr))
 	// Restart pods
 	r.Recorder.Event(r.binding, corev1.EventTypeNormal, "ApplyingConfig", "Applying ConfigMap to Deployment")
 	r.Recorder.Event(cfDeployment, corev1.EventTypeNormal, "ApplyingConfig", "Applying ConfigMap to Deployment")
 	if cfDeployment.Spec.Template.Annotations == nil {
 		cfDeployment.Spec.Template.Annotations = map[string]string{}
 	}
-	cfDeployment.Spec.Template.Annotations[tunnelConfigChecksum] = hex.EncodeToString(hash[:])
+	cfDeployment.Spec.Template.Annotations[tunnelConfigChecksum] = checksum
 	if err := r.Update(r.ctx, cfDeployment); err != nil {
 		r.log.Error(err, "Failed to update Deployment for restart")
 		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FailedApplyingConfig", "Failed to apply ConfigMap to Deployment")
 		r.Recorder.Event(cfDeployment, corev1.EventTypeWarning, "FailedApplyingConfig", "Failed to apply ConfigMap to Deployment")
-		return err
+		return fmt.Errorf("failed to update Deployment %s/%s for restart: %w", cfDeployment.Namespace, cfDeployment.Name, err)
 	}
 	r.log.Info("Restarted deployment")
 	r.Recorder.Event(r.binding, corev1.EventTypeNormal, "AppliedConfig", "ConfigMap applied to Deployment")
@@ -548,10 +1808,31 @@ func (r *TunnelBindingReconciler) setConfigMapConfiguration(config *Configuratio
 	return nil
 }
 
+// tunnelConfigLocks holds a *sync.Mutex per tunnel ConfigMap namespace/name,
+// serializing the read-modify-write of the tunnel configuration across reconciles
+var tunnelConfigLocks sync.Map
+
+// lockTunnelConfig locks the configuration of the tunnel served by the ConfigMap and returns the unlock function
+func lockTunnelConfig(configmap *corev1.ConfigMap) func() {
+	lock, _ := tunnelConfigLocks.LoadOrStore(configmap.Namespace+"/"+configmap.Name, &sync.Mutex{})
+	mutex := lock.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
 func (r *TunnelBindingReconciler) configureCloudflareDaemon() error {
 	var config *Configuration
 	var err error
 
+	unlock := lockTunnelConfig(r.configmap)
+	defer unlock()
+
+	// Re-read the ConfigMap under the lock, a previous reconcile might have updated it since initStruct
+	if err := r.Get(r.ctx, apitypes.NamespacedName{Name: r.configmap.Name, Namespace: r.configmap.Namespace}, r.configmap); err != nil {
+		r.log.Error(err, "unable to get configmap for configuration")
+		return fmt.Errorf("failed to get ConfigMap %s/%s: %w", r.configmap.Namespace, r.configmap.Name, err)
+	}
+
 	if config, err = r.getConfigMapConfiguration(); err != nil {
 		r.log.Error(err, "unable to get ConfigMap")
 		return err
@@ -563,50 +1844,370 @@ func (r *TunnelBindingReconciler) configureCloudflareDaemon() error {
 		return err
 	}
 
+	// The CA bundle of the tunnel spec.caPool is (un)mounted by the tunnel controller, keep the default pointing to it
+	if r.tunnel.GetSpec().CaPool != nil {
+		caPool := caPoolPathForTunnel(r.tunnel)
+		config.OriginRequest.CAPool = &caPool
+	} else if current := config.OriginRequest.CAPool; current != nil && *current == caPoolMountPath+"/"+caPoolFile {
+		config.OriginRequest.CAPool = nil
+	}
+	refreshOriginRequestDefaults(&config.OriginRequest, r.tunnel)
+
 	// Total number of ingresses is the number of services + 1 for the catchall ingress
 	// Set to 16 initially
 	finalIngresses := make([]UnvalidatedIngressRule, 0, 16)
+	var nonHTTPHostnames []string
+	// The TunnelBinding being deleted removes its own rules once its DNS entries are deleted,
+	// other ones being deleted keep theirs until they do the same
+	skipBinding := func(binding *networkingv1alpha1.TunnelBinding) bool {
+		removing := binding.GetDeletionTimestamp() != nil && binding.Namespace == r.binding.Namespace && binding.Name == r.binding.Name
+		return bindingExpired(binding) || removing
+	}
+	owners := ruleOwners(bindings, skipBinding, r.canaryOrigins)
+	catchAllElsewhere := false
 	for _, binding := range bindings {
+		if skipBinding(&binding) {
+			continue
+		}
+		if noCatchAll, err := strconv.ParseBool(binding.Annotations[tunnelNoCatchAllAnnotation]); err == nil && noCatchAll {
+			catchAllElsewhere = true
+		}
+		// Weighted subjects get a rule for the Host header of their Load Balancer origin instead of the hostname
+		canary := r.canaryOrigins(&binding)
 		for i, subject := range binding.Subjects {
+			// The status of subjects added since the last reconcile of the TunnelBinding is not set yet
+			if i >= len(binding.Status.Services) {
+				break
+			}
 			targetService := ""
-			if subject.Spec.Target != "" {
+			// Bastion, unix socket and IP targets are validated into the status while generating the config
+			if subject.Spec.Target != "" && !subject.Spec.BastionMode && !isDirectTarget(subject.Spec.Target) {
 				targetService = subject.Spec.Target
 			} else {
 				targetService = binding.Status.Services[i].Target
 			}
-			originRequest := OriginRequestConfig{}
-			originRequest.NoTLSVerify = &subject.Spec.NoTlsVerify
-			originRequest.ProxyAddress = &subject.Spec.ProxyAddress
-			originRequest.ProxyPort = &subject.Spec.ProxyPort
-			originRequest.ProxyType = &subject.Spec.ProxyType
-			if caPool := subject.Spec.CaPool; caPool != "" {
-				caPath := fmt.Sprintf("/etc/cloudflared/certs/%s", caPool)
-				originRequest.CAPool = &caPath
+			// An invalid path would stop cloudflared from starting, the rule falls back to the status target without it
+			rulePath := subject.Spec.Path
+			if validatePath(rulePath) != nil {
+				rulePath = ""
+				targetService = binding.Status.Services[i].Target
 			}
-
-			finalIngresses = append(finalIngresses, UnvalidatedIngressRule{
-				Hostname:      binding.Status.Services[i].Hostname,
+			// Likewise for a partial access config, without exposing the origin unprotected
+			if _, err := validateAccess(subject.Spec.Access); err != nil {
+				targetService = binding.Status.Services[i].Target
+			}
+			ruleHostname := binding.Status.Services[i].Hostname
+			if origin, ok := canary[i]; ok {
+				ruleHostname = origin
+			}
+			// Only one of the subjects claiming the same hostname and path gets the rule, the other ones would never match
+			if owner := owners[ruleKey(ruleHostname, rulePath)]; !owner.is(&binding, i) {
+				r.reportRuleConflict(owner, &binding, i)
+				continue
+			}
+			rule := UnvalidatedIngressRule{
+				Hostname:      ruleHostname,
 				Service:       targetService,
-				Path:          subject.Spec.Path,
-				OriginRequest: originRequest,
-			})
+				Path:          rulePath,
+				OriginRequest: r.getOriginRequestForSubject(&binding, subject, targetService),
+				ManagedBy:     fmt.Sprintf("%s/%s, subject: %s", binding.Namespace, binding.Name, subject.Name),
+				Group:         binding.Annotations[tunnelGroupAnnotation],
+				Before:        subject.Spec.Before,
+				After:         subject.Spec.After,
+			}
+			// An h2c Service port needs HTTP/2 to the origin, unless the subject sets http2Origin or its own target
+			if rule.OriginRequest.Http2Origin == nil && binding.Status.Services[i].Http2Origin && targetService == binding.Status.Services[i].Target {
+				rule.OriginRequest.Http2Origin = ptr(true)
+			}
+			isCurrentBinding := binding.Namespace == r.binding.Namespace && binding.Name == r.binding.Name
+			// The status keeps the real target, so that leaving maintenance restores it
+			if subject.Spec.Maintenance {
+				rule.Service = maintenanceService
+				rule.OriginRequest = OriginRequestConfig{}
+				if isCurrentBinding {
+					r.Recorder.Event(r.binding, corev1.EventTypeNormal, "Maintenance", fmt.Sprintf("Serving %s for %s in maintenance", maintenanceService, rule.Hostname))
+				}
+			}
+			finalIngresses = append(finalIngresses, rule)
+			if isCurrentBinding && !isHTTPService(targetService) {
+				nonHTTPHostnames = append(nonHTTPHostnames, binding.Status.Services[i].Hostname)
+			}
 		}
 	}
 
+	managedServices := len(finalIngresses)
+	if r.GatewayAPI {
+		routeIngresses, err := r.getHTTPRouteIngresses()
+		if err != nil {
+			return err
+		}
+		finalIngresses = append(finalIngresses, routeIngresses...)
+	}
+	sortIngressByPrecedence(finalIngresses)
+	if err := orderIngressByConstraints(finalIngresses); err != nil {
+		r.log.Error(err, "invalid ingress rule order, keeping the default order")
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "InvalidIngressOrder", fmt.Sprintf("Ignoring before and after, %s", err.Error()))
+	}
+
 	// Catchall ingress
-	finalIngresses = append(finalIngresses, UnvalidatedIngressRule{
-		Service: r.fallbackTarget,
-	})
+	fallbackIngress, ok := r.getFallbackIngress(config, r.preserveFallback || catchAllElsewhere)
+	if ok {
+		finalIngresses = append(finalIngresses, fallbackIngress)
+	} else {
+		// cloudflared refuses to start without a catch-all, unless it is added before cloudflared reads the config
+		r.log.Info("Catch-all not managed and missing from the configuration, cloudflared requires one")
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "MissingCatchAll", "manageCatchAll is false and the configuration has no catch-all rule, cloudflared requires one")
+	}
+
+	// cloudflared has a single catch-all for all protocols, an HTTP status is not meaningful to tcp/udp clients
+	if ok && len(nonHTTPHostnames) > 0 && strings.HasPrefix(fallbackIngress.Service, "http_status:") {
+		r.log.Info("Non HTTP services share the HTTP status catch-all, set the tunnel fallbackTarget to override it", "hostnames", nonHTTPHostnames, "fallbackTarget", fallbackIngress.Service)
+		r.Recorder.Event(r.binding, corev1.EventTypeWarning, "FallbackMismatch",
+			fmt.Sprintf("Non HTTP services %s use the %s catch-all, set the tunnel fallbackTarget to override it", strings.Join(nonHTTPHostnames, ","), fallbackIngress.Service))
+	}
 
 	config.Ingress = finalIngresses
 
-	return r.setConfigMapConfiguration(config)
+	if err := r.setConfigMapConfiguration(config); err != nil {
+		return err
+	}
+
+	// Gauges are set from the whole configuration, so removed subjects are accounted for
+	key := tunnelKeyForBinding(r.binding, r.Namespace)
+	tunnelManagedServices.WithLabelValues(key.Kind, key.Namespace, key.Name).Set(float64(managedServices))
+	tunnelIngressRules.WithLabelValues(key.Kind, key.Namespace, key.Name).Set(float64(len(finalIngresses)))
+	return nil
+}
+
+// ruleOwner is the TunnelBinding subject keeping the ingress rule of a hostname and path claimed by several subjects
+type ruleOwner struct {
+	binding *networkingv1alpha1.TunnelBinding
+	subject int
+}
+
+// is checks if the owner is the subject of the TunnelBinding
+func (o ruleOwner) is(binding *networkingv1alpha1.TunnelBinding, subject int) bool {
+	return o.binding != nil && o.binding.Namespace == binding.Namespace && o.binding.Name == binding.Name && o.subject == subject
+}
+
+// precedes orders the subjects claiming the same rule: the oldest TunnelBinding first, then by namespace and name, then the first subject
+func (o ruleOwner) precedes(other ruleOwner) bool {
+	if created, otherCreated := o.binding.CreationTimestamp, other.binding.CreationTimestamp; !created.Equal(&otherCreated) {
+		return created.Before(&otherCreated)
+	}
+	if o.binding.Namespace != other.binding.Namespace {
+		return o.binding.Namespace < other.binding.Namespace
+	}
+	if o.binding.Name != other.binding.Name {
+		return o.binding.Name < other.binding.Name
+	}
+	return o.subject < other.subject
+}
+
+// ruleKey identifies the requests an ingress rule matches, cloudflared matches hostnames case insensitively
+func ruleKey(hostname, path string) string {
+	return strings.ToLower(hostname) + " " + path
+}
+
+// ruleOwners returns the owner of each hostname and path claimed by the subjects of the TunnelBindings
+func ruleOwners(bindings []networkingv1alpha1.TunnelBinding, skip func(*networkingv1alpha1.TunnelBinding) bool, origins func(*networkingv1alpha1.TunnelBinding) map[int]string) map[string]ruleOwner {
+	owners := map[string]ruleOwner{}
+	for b := range bindings {
+		binding := &bindings[b]
+		if skip(binding) {
+			continue
+		}
+		canary := origins(binding)
+		for i, subject := range binding.Subjects {
+			if i >= len(binding.Status.Services) {
+				break
+			}
+			// Same as the rule, which drops an invalid path
+			path := subject.Spec.Path
+			if validatePath(path) != nil {
+				path = ""
+			}
+			hostname := binding.Status.Services[i].Hostname
+			if origin, ok := canary[i]; ok {
+				hostname = origin
+			}
+			key := ruleKey(hostname, path)
+			candidate := ruleOwner{binding: binding, subject: i}
+			if owner, ok := owners[key]; !ok || candidate.precedes(owner) {
+				owners[key] = candidate
+			}
+		}
+	}
+	return owners
+}
+
+// reportRuleConflict warns on both TunnelBindings when the current one is involved in a conflict, the other reconciles would be repeating it
+func (r *TunnelBindingReconciler) reportRuleConflict(owner ruleOwner, binding *networkingv1alpha1.TunnelBinding, subject int) {
+	ownerIsCurrent := owner.binding.Namespace == r.binding.Namespace && owner.binding.Name == r.binding.Name
+	if !ownerIsCurrent && (binding.Namespace != r.binding.Namespace || binding.Name != r.binding.Name) {
+		return
+	}
+	hostname, path := binding.Status.Services[subject].Hostname, binding.Subjects[subject].Spec.Path
+	ownerSubject := fmt.Sprintf("%s/%s subject %s", owner.binding.Namespace, owner.binding.Name, owner.binding.Subjects[owner.subject].Name)
+	ignoredSubject := fmt.Sprintf("%s/%s subject %s", binding.Namespace, binding.Name, binding.Subjects[subject].Name)
+	r.log.Info("Hostname and path claimed by several subjects, keeping the oldest", "hostname", hostname, "path", path, "kept", ownerSubject, "ignored", ignoredSubject)
+	r.Recorder.Event(binding, corev1.EventTypeWarning, "HostnameConflict",
+		fmt.Sprintf("Ignoring %s for %s%s, already claimed by %s", ignoredSubject, hostname, path, ownerSubject))
+	r.Recorder.Event(owner.binding, corev1.EventTypeWarning, "HostnameConflict",
+		fmt.Sprintf("%s for %s%s is also claimed by %s, which is ignored", ownerSubject, hostname, path, ignoredSubject))
+}
+
+// getOriginRequestForSubject returns the origin request configuration for the ingress rule of the subject
+func (r *TunnelBindingReconciler) getOriginRequestForSubject(binding *networkingv1alpha1.TunnelBinding, subject networkingv1alpha1.TunnelBindingSubject, targetService string) OriginRequestConfig {
+	originRequest := OriginRequestConfig{}
+	originRequest.NoTLSVerify = &subject.Spec.NoTlsVerify
+	originRequest.ProxyAddress = &subject.Spec.ProxyAddress
+	originRequest.ProxyPort = &subject.Spec.ProxyPort
+	originRequest.ProxyType = &subject.Spec.ProxyType
+	// Unset pointers inherit the tunnel defaults, explicit values override them
+	originRequest.NoHappyEyeballs = subject.Spec.NoHappyEyeballs
+	originRequest.Http2Origin = subject.Spec.Http2Origin
+	originRequest.DisableChunkedEncoding = subject.Spec.DisableChunkedEncoding
+	if access, err := validateAccess(subject.Spec.Access); err == nil {
+		originRequest.Access = access
+	}
+	if subject.Spec.HttpHostHeader != "" {
+		originRequest.HTTPHostHeader = &subject.Spec.HttpHostHeader
+	}
+	if subject.Spec.BastionMode {
+		originRequest.BastionMode = ptr(true)
+	}
+	if caPool := subject.Spec.CaPool; caPool != "" {
+		caPath := fmt.Sprintf("/etc/cloudflared/certs/%s", caPool)
+		originRequest.CAPool = &caPath
+	}
+
+	// TCP keepalive only applies to raw TCP/UDP rules
+	if keepAlive := subject.Spec.TCPKeepAlive; keepAlive != "" {
+		if duration, err := time.ParseDuration(keepAlive); err != nil {
+			r.log.Error(err, "invalid tcpKeepAlive duration, ignoring", "service", subject.Name, "tcpKeepAlive", keepAlive)
+			r.Recorder.Event(binding, corev1.EventTypeWarning, "ErrBuildConfig", fmt.Sprintf("Invalid tcpKeepAlive duration %q, svc: %s", keepAlive, subject.Name))
+		} else if strings.HasPrefix(targetService, tunnelProtoTCP+"://") || strings.HasPrefix(targetService, tunnelProtoUDP+"://") {
+			originRequest.TCPKeepAlive = &duration
+		} else {
+			r.log.Info("tcpKeepAlive is only supported for tcp and udp targets, ignoring", "service", subject.Name, "target", targetService)
+		}
+	}
+
+	// The TLS handshake timeout only applies to HTTPS rules
+	if tlsTimeout := subject.Spec.TLSTimeout; tlsTimeout != "" {
+		if duration, err := time.ParseDuration(tlsTimeout); err != nil {
+			r.log.Error(err, "invalid tlsTimeout duration, ignoring", "service", subject.Name, "tlsTimeout", tlsTimeout)
+			r.Recorder.Event(binding, corev1.EventTypeWarning, "ErrBuildConfig", fmt.Sprintf("Invalid tlsTimeout duration %q, svc: %s", tlsTimeout, subject.Name))
+		} else if strings.HasPrefix(targetService, tunnelProtoHTTPS+"://") {
+			originRequest.TLSTimeout = &duration
+		} else {
+			r.log.Info("tlsTimeout is only supported for https targets, ignoring", "service", subject.Name, "target", targetService)
+		}
+	}
+	return originRequest
+}
+
+// getFallbackIngress returns the catch-all ingress rule, keeping the existing one if it should be preserved.
+// Without a managed catch-all, it returns false if the configuration has none.
+func (r *TunnelBindingReconciler) getFallbackIngress(config *Configuration, preserve bool) (UnvalidatedIngressRule, bool) {
+	if (preserve || !r.manageCatchAll) && len(config.Ingress) > 0 {
+		last := config.Ingress[len(config.Ingress)-1]
+		if last.Hostname == "" && last.Path == "" {
+			r.log.Info("Preserving existing catch-all ingress", "service", last.Service)
+			return last, true
+		}
+	}
+	if !r.manageCatchAll {
+		return UnvalidatedIngressRule{}, false
+	}
+	return UnvalidatedIngressRule{
+		Service: r.fallbackTarget,
+	}, true
+}
+
+// bindingsForSecret returns reconcile requests for the TunnelBindings whose Tunnel or ClusterTunnel uses
+// the Cloudflare credentials in the Secret, so that DNS records are re-asserted with rotated credentials.
+func (r *TunnelBindingReconciler) bindingsForSecret(secret client.Object) []reconcile.Request {
+	ctx := context.Background()
+	log := ctrllog.Log.WithValues("secret", secret.GetName(), "namespace", secret.GetNamespace())
+
+	// Tunnels (lowercase kind + name) using the Secret
+	tunnels := map[string]map[string]bool{"tunnel": {}, "clustertunnel": {}}
+
+	tunnelList := &networkingv1alpha1.TunnelList{}
+	if err := r.List(ctx, tunnelList, client.InNamespace(secret.GetNamespace())); err != nil {
+		log.Error(err, "failed to list Tunnels for Secret")
+		return nil
+	}
+	for _, tunnel := range tunnelList.Items {
+		if tunnel.Spec.Cloudflare.Secret == secret.GetName() {
+			tunnels["tunnel"][tunnel.Name] = true
+		}
+	}
+
+	if secret.GetNamespace() == r.Namespace {
+		clusterTunnelList := &networkingv1alpha1.ClusterTunnelList{}
+		if err := r.List(ctx, clusterTunnelList); err != nil {
+			log.Error(err, "failed to list ClusterTunnels for Secret")
+			return nil
+		}
+		for _, clusterTunnel := range clusterTunnelList.Items {
+			if clusterTunnel.Spec.Cloudflare.Secret == secret.GetName() {
+				tunnels["clustertunnel"][clusterTunnel.Name] = true
+			}
+		}
+	}
+
+	if len(tunnels["tunnel"]) == 0 && len(tunnels["clustertunnel"]) == 0 {
+		return nil
+	}
+
+	listOpts := []client.ListOption{}
+	if len(tunnels["clustertunnel"]) == 0 {
+		listOpts = append(listOpts, client.InNamespace(secret.GetNamespace()))
+	}
+	bindingList := &networkingv1alpha1.TunnelBindingList{}
+	if err := r.List(ctx, bindingList, listOpts...); err != nil {
+		log.Error(err, "failed to list TunnelBindings for Secret")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, binding := range bindingList.Items {
+		kind := strings.ToLower(binding.TunnelRef.Kind)
+		// Tunnels are namespaced, only bindings in the same namespace can reference them
+		if kind == "tunnel" && binding.Namespace != secret.GetNamespace() {
+			continue
+		}
+		if tunnels[kind][binding.TunnelRef.Name] {
+			requests = append(requests, reconcile.Request{NamespacedName: apitypes.NamespacedName{Name: binding.Name, Namespace: binding.Namespace}})
+		}
+	}
+	return requests
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *TunnelBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.Recorder = mgr.GetEventRecorderFor("cloudflare-operator")
+	r.breaker = newCircuitBreaker(r.CircuitBreakerThreshold, r.CircuitBreakerCooldown)
+
+	// Index TunnelBindings by the tunnel they reference, to find the bindings of a tunnel without iterating all of them
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &networkingv1alpha1.TunnelBinding{}, tunnelRefIndex, func(obj client.Object) []string {
+		return []string{tunnelKeyForBinding(obj.(*networkingv1alpha1.TunnelBinding), r.Namespace).String()}
+	}); err != nil {
+		return err
+	}
+
+	// The labels, finalizer and status are written by the operator itself, only reconcile on spec and annotation changes
+	// to not trigger a reconcile for each of these writes. Deletion bumps the generation while finalizers are pending.
+	// Labels removed or changed by hand are re-applied, as they are used to select the bindings of a tunnel with kubectl.
+	labelsStale := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		binding, ok := obj.(*networkingv1alpha1.TunnelBinding)
+		return ok && bindingLabelsStale(binding)
+	})
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&networkingv1alpha1.TunnelBinding{}).
+		For(&networkingv1alpha1.TunnelBinding{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}, labelsStale))).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.bindingsForSecret)).
 		Complete(r)
 }
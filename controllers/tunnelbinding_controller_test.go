@@ -0,0 +1,759 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	yaml "gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// newTestTunnel returns the Tunnel default/tunnel and its ConfigMap with the initial configuration
+func newTestTunnel() (*networkingv1alpha1.Tunnel, *corev1.ConfigMap) {
+	tunnel := &networkingv1alpha1.Tunnel{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tunnel"}}
+	tunnel.Spec.FallbackTarget = "http_status:404"
+	tunnel.Spec.Cloudflare.Domain = "example.com"
+	tunnel.Status.TunnelId = "tunnel-id"
+	config, _ := yaml.Marshal(initialConfigurationForTunnel(TunnelAdapter{tunnel}))
+	configmap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tunnel"},
+		Data:       map[string]string{configmapKey: string(config)},
+	}
+	return tunnel, configmap
+}
+
+// newTestBinding returns a TunnelBinding of the Tunnel default/tunnel with a subject per hostname, its status caught up
+func newTestBinding(name string, hostnames ...string) *networkingv1alpha1.TunnelBinding {
+	binding := &networkingv1alpha1.TunnelBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	binding.TunnelRef = networkingv1alpha1.TunnelRef{Kind: "Tunnel", Name: "tunnel"}
+	for _, hostname := range hostnames {
+		binding.Subjects = append(binding.Subjects, networkingv1alpha1.TunnelBindingSubject{Name: hostname})
+		binding.Status.Services = append(binding.Status.Services, networkingv1alpha1.ServiceInfo{Hostname: hostname, Target: "http://" + name + ".default.svc:80"})
+	}
+	return binding
+}
+
+// newTestBindingReconciler returns a reconciler of the binding as initStruct sets it up, with the Tunnel and ConfigMap
+// of newTestTunnel and the objects in a fake client
+func newTestBindingReconciler(t *testing.T, api *CloudflareAPI, binding *networkingv1alpha1.TunnelBinding, objects ...runtime.Object) *TunnelBindingReconciler {
+	tunnel, configmap := newTestTunnel()
+	objects = append(objects, tunnel, configmap, binding)
+	return &TunnelBindingReconciler{
+		Client:         newFakeClient(t, objects...),
+		Recorder:       record.NewFakeRecorder(1000),
+		ctx:            context.Background(),
+		log:            logr.Discard(),
+		binding:        binding,
+		configmap:      configmap.DeepCopy(),
+		fallbackTarget: tunnel.Spec.FallbackTarget,
+		manageCatchAll: true,
+		tunnel:         TunnelAdapter{tunnel},
+		cfAPI:          api,
+	}
+}
+
+// configuredIngress returns the ingress rules in the ConfigMap of the tunnel
+func configuredIngress(t *testing.T, r *TunnelBindingReconciler) []UnvalidatedIngressRule {
+	configmap := &corev1.ConfigMap{}
+	if err := r.Get(context.Background(), apitypes.NamespacedName{Namespace: "default", Name: "tunnel"}, configmap); err != nil {
+		t.Fatal(err)
+	}
+	config := &Configuration{}
+	if err := yaml.Unmarshal([]byte(configmap.Data[configmapKey]), config); err != nil {
+		t.Fatal(err)
+	}
+	return config.Ingress
+}
+
+func TestDeletionLogicBackstop(t *testing.T) {
+	f, api := newFakeCloudflare(t)
+	// The records of app.example.com are found but Cloudflare keeps failing to delete them, other.example.com has none
+	txt, _ := json.Marshal(DnsManagedRecordTxt{DnsId: "cname-id", TunnelName: "tunnel", TunnelId: "tunnel-id"})
+	f.fallback = func(w http.ResponseWriter, r *http.Request, _ []byte) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/zone/dns_records" && r.URL.Query().Get("type") == "TXT" && r.URL.Query().Get("name") == "_managed.app.example.com":
+			writeFakeResponse(w, http.StatusOK, []cloudflare.DNSRecord{{ID: "txt-id", Type: "TXT", Content: string(txt)}})
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/zone/dns_records" && r.URL.Query().Get("type") == "CNAME":
+			writeFakeResponse(w, http.StatusOK, []cloudflare.DNSRecord{{ID: "cname-id", Type: "CNAME"}})
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/zone/dns_records":
+			writeFakeResponse(w, http.StatusOK, []cloudflare.DNSRecord{})
+		case r.Method == http.MethodDelete:
+			writeFakeResponse(w, http.StatusInternalServerError, "internal error")
+		default:
+			writeFakeResponse(w, http.StatusNotFound, "no route")
+		}
+	}
+
+	binding := newTestBinding("app", "app.example.com", "other.example.com")
+	binding.Finalizers = []string{tunnelFinalizer}
+	now := metav1.Now()
+	binding.DeletionTimestamp = &now
+	r := newTestBindingReconciler(t, api, binding)
+	r.MaxDNSDeleteAttempts = 3
+
+	for attempt := 1; attempt < r.MaxDNSDeleteAttempts; attempt++ {
+		if err := r.deletionLogic(); err == nil {
+			t.Fatalf("attempt %d: deletionLogic() error = nil, want the DNS deletion failure to requeue", attempt)
+		}
+		if !controllerutil.ContainsFinalizer(r.binding, tunnelFinalizer) {
+			t.Fatalf("attempt %d: finalizer removed before MaxDNSDeleteAttempts", attempt)
+		}
+		if r.binding.Status.DNSDeleteFailures != attempt {
+			t.Errorf("attempt %d: DNSDeleteFailures = %d", attempt, r.binding.Status.DNSDeleteFailures)
+		}
+	}
+	if err := r.deletionLogic(); err != nil {
+		t.Fatalf("deletionLogic() error = %v after MaxDNSDeleteAttempts, want the finalizer removed", err)
+	}
+	// Without finalizer, the binding being deleted is gone
+	saved := &networkingv1alpha1.TunnelBinding{}
+	if err := r.Get(context.Background(), apitypes.NamespacedName{Namespace: "default", Name: "app"}, saved); !apierrors.IsNotFound(err) {
+		t.Errorf("binding %+v still exists after MaxDNSDeleteAttempts, error %v", saved.ObjectMeta, err)
+	}
+	if deletes := f.count(http.MethodDelete, "/zones/zone/dns_records/cname-id"); deletes != r.MaxDNSDeleteAttempts {
+		t.Errorf("deleted the record %d times, want once per attempt", deletes)
+	}
+	// The rules of the binding are removed with it
+	if ingress := configuredIngress(t, r); len(ingress) != 1 || ingress[0].Service != "http_status:404" {
+		t.Errorf("ingress = %+v, want only the catch-all", ingress)
+	}
+}
+
+// slowGetClient delays reads, widening the window between the read and the write of a read-modify-write
+type slowGetClient struct {
+	client.Client
+}
+
+func (c slowGetClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	err := c.Client.Get(ctx, key, obj, opts...)
+	time.Sleep(10 * time.Millisecond)
+	return err
+}
+
+func TestConfigureCloudflareDaemonConcurrent(t *testing.T) {
+	_, api := newFakeCloudflare(t)
+	base := newTestBindingReconciler(t, api, newTestBinding("binding-0", "app-0.example.com"))
+
+	// Each reconcile adds its TunnelBinding then writes the configuration, like reconciles of new bindings of a tunnel
+	const reconciles = 10
+	errs := make(chan error, reconciles)
+	var wg sync.WaitGroup
+	for i := 1; i <= reconciles; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			binding := newTestBinding(fmt.Sprintf("binding-%d", i), fmt.Sprintf("app-%d.example.com", i))
+			if err := base.Create(context.Background(), binding); err != nil {
+				errs <- err
+				return
+			}
+			r := *base
+			r.Client = slowGetClient{base.Client}
+			r.binding = binding
+			r.configmap = base.configmap.DeepCopy()
+			errs <- r.configureCloudflareDaemon()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("configureCloudflareDaemon() error = %v", err)
+		}
+	}
+
+	hostnames := map[string]bool{}
+	for _, rule := range configuredIngress(t, base) {
+		hostnames[rule.Hostname] = true
+	}
+	for i := 0; i <= reconciles; i++ {
+		if hostname := fmt.Sprintf("app-%d.example.com", i); !hostnames[hostname] {
+			t.Errorf("ingress rule of %s lost", hostname)
+		}
+	}
+}
+
+func TestCreationLogicWaitsForTunnel(t *testing.T) {
+	f, api := newFakeCloudflare(t)
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tunnel"}}
+	deployment.Status.ReadyReplicas = 0
+	r := newTestBindingReconciler(t, api, newTestBinding("app", "app.example.com"), deployment)
+	r.WaitForTunnelReady = true
+
+	result, err := r.creationLogic()
+	if err != nil {
+		t.Fatalf("creationLogic() error = %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Errorf("creationLogic() = %+v, want a requeue while the Deployment has no ready replicas", result)
+	}
+	if len(f.requests) != 0 {
+		t.Errorf("requests = %v, want no DNS entries created", f.requests)
+	}
+	if r.dnsCondition == nil || r.dnsCondition.Reason != "WaitingForTunnel" {
+		t.Errorf("DNSReady condition = %+v, want WaitingForTunnel", r.dnsCondition)
+	}
+	if events := r.Recorder.(*record.FakeRecorder).Events; !hasEvent(events, "WaitingForTunnel") {
+		t.Errorf("no WaitingForTunnel event")
+	}
+}
+
+// hasEvent drains the events of a FakeRecorder, reporting if one has the reason
+func hasEvent(events chan string, reason string) bool {
+	found := false
+	for {
+		select {
+		case event := <-events:
+			found = found || strings.Contains(event, " "+reason+" ")
+		default:
+			return found
+		}
+	}
+}
+
+func TestConfigureCloudflareDaemonKeepsCatchAll(t *testing.T) {
+	tests := []struct {
+		name             string
+		preserveFallback bool
+		manageCatchAll   bool
+		want             string
+	}{
+		{name: "managed", manageCatchAll: true, want: "http_status:404"},
+		{name: "preserveFallbackTarget", preserveFallback: true, manageCatchAll: true, want: "http_status:503"},
+		{name: "manageCatchAll false", want: "http_status:503"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, api := newFakeCloudflare(t)
+			r := newTestBindingReconciler(t, api, newTestBinding("app", "app.example.com"))
+			r.preserveFallback = tt.preserveFallback
+			r.manageCatchAll = tt.manageCatchAll
+
+			// The owner of the tunnel set its own catch-all
+			config := initialConfigurationForTunnel(r.tunnel)
+			config.Ingress = []UnvalidatedIngressRule{{Service: "http_status:503"}}
+			raw, _ := yaml.Marshal(config)
+			r.configmap.Data[configmapKey] = string(raw)
+			if err := r.Update(context.Background(), r.configmap); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := r.configureCloudflareDaemon(); err != nil {
+				t.Fatalf("configureCloudflareDaemon() error = %v", err)
+			}
+			ingress := configuredIngress(t, r)
+			if len(ingress) != 2 || ingress[0].Hostname != "app.example.com" || ingress[1].Service != tt.want {
+				t.Errorf("ingress = %+v, want the rule of app.example.com then the catch-all %s", ingress, tt.want)
+			}
+		})
+	}
+}
+
+func TestForceDNSSyncCorrectsDrift(t *testing.T) {
+	tests := []struct {
+		name string
+		// applied is false when Cloudflare ignores the update
+		applied bool
+		wantErr bool
+	}{
+		{name: "corrected", applied: true},
+		{name: "still drifted", applied: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, api := newFakeCloudflare(t)
+			// The managed record of app.example.com was changed in the dashboard to point elsewhere
+			txt, _ := json.Marshal(DnsManagedRecordTxt{DnsId: "cname-id", TunnelName: "tunnel", TunnelId: "tunnel-id"})
+			cname := cloudflare.DNSRecord{ID: "cname-id", Type: "CNAME", Name: "app.example.com", Content: "elsewhere.example.net", Proxied: ptr(true), TTL: 1}
+			f.fallback = func(w http.ResponseWriter, r *http.Request, body []byte) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Query().Get("type") == "TXT":
+					writeFakeResponse(w, http.StatusOK, []cloudflare.DNSRecord{{ID: "txt-id", Type: "TXT", Content: string(txt)}})
+				case r.Method == http.MethodGet && r.URL.Query().Get("type") == "CNAME":
+					writeFakeResponse(w, http.StatusOK, []cloudflare.DNSRecord{cname})
+				case r.Method == http.MethodPatch && r.URL.Path == "/zones/zone/dns_records/cname-id":
+					update := cloudflare.DNSRecord{}
+					_ = json.Unmarshal(body, &update)
+					if tt.applied {
+						cname.Content = update.Content
+					}
+					writeFakeResponse(w, http.StatusOK, cname)
+				case r.Method == http.MethodPatch && r.URL.Path == "/zones/zone/dns_records/txt-id":
+					writeFakeResponse(w, http.StatusOK, cloudflare.DNSRecord{ID: "txt-id"})
+				default:
+					writeFakeResponse(w, http.StatusNotFound, "no route")
+				}
+			}
+
+			binding := newTestBinding("app", "app.example.com")
+			binding.Annotations = map[string]string{tunnelForceDNSSyncAnnotation: "1"}
+			r := newTestBindingReconciler(t, api, binding)
+
+			err := r.createDNSLogic("app.example.com", DNSRecordOptions{Proxied: true, TTL: 1})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createDNSLogic() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n := f.count(http.MethodPatch, "/zones/zone/dns_records/cname-id"); n != 1 {
+				t.Errorf("updated the record %d times, want once", n)
+			}
+			events := r.Recorder.(*record.FakeRecorder).Events
+			if !hasEvent(events, "DriftedDns") {
+				t.Errorf("no DriftedDns event")
+			}
+			if tt.applied && cname.Content != api.TunnelCName() {
+				t.Errorf("record points to %s, want %s", cname.Content, api.TunnelCName())
+			}
+		})
+	}
+}
+
+func TestConfigureCloudflareDaemonHttp2OriginOverride(t *testing.T) {
+	_, api := newFakeCloudflare(t)
+	binding := newTestBinding("app", "inherit.example.com", "override.example.com")
+	binding.Subjects[1].Spec.Http2Origin = ptr(false)
+	r := newTestBindingReconciler(t, api, binding)
+	r.tunnel.(TunnelAdapter).Tunnel.Spec.OriginRequest.Http2Origin = true
+
+	if err := r.configureCloudflareDaemon(); err != nil {
+		t.Fatalf("configureCloudflareDaemon() error = %v", err)
+	}
+	configmap := &corev1.ConfigMap{}
+	if err := r.Get(context.Background(), apitypes.NamespacedName{Namespace: "default", Name: "tunnel"}, configmap); err != nil {
+		t.Fatal(err)
+	}
+	config := &Configuration{}
+	if err := yaml.Unmarshal([]byte(configmap.Data[configmapKey]), config); err != nil {
+		t.Fatal(err)
+	}
+	if http2Origin := config.OriginRequest.Http2Origin; http2Origin == nil || !*http2Origin {
+		t.Errorf("tunnel http2Origin = %v, want the default true", http2Origin)
+	}
+	if len(config.Ingress) != 3 {
+		t.Fatalf("ingress = %+v, want the rules of both subjects and the catch-all", config.Ingress)
+	}
+	for _, rule := range config.Ingress {
+		switch http2Origin := rule.OriginRequest.Http2Origin; rule.Hostname {
+		case "inherit.example.com":
+			if http2Origin != nil {
+				t.Errorf("http2Origin of %s = %v, want unset to inherit the default", rule.Hostname, *http2Origin)
+			}
+		case "override.example.com":
+			if http2Origin == nil || *http2Origin {
+				t.Errorf("http2Origin of %s = %v, want an explicit false", rule.Hostname, http2Origin)
+			}
+		}
+	}
+}
+
+func TestRuleOwners(t *testing.T) {
+	older, newer := metav1.Unix(1000, 0), metav1.Unix(2000, 0)
+	binding := func(namespace, name string, created metav1.Time, hostnames ...string) networkingv1alpha1.TunnelBinding {
+		b := newTestBinding(name, hostnames...)
+		b.Namespace, b.CreationTimestamp = namespace, created
+		return *b
+	}
+	withPath := func(b networkingv1alpha1.TunnelBinding, subject int, path string) networkingv1alpha1.TunnelBinding {
+		b.Subjects[subject].Spec.Path = path
+		return b
+	}
+	noSkip := func(*networkingv1alpha1.TunnelBinding) bool { return false }
+	noCanary := func(*networkingv1alpha1.TunnelBinding) map[int]string { return nil }
+
+	tests := []struct {
+		name     string
+		bindings []networkingv1alpha1.TunnelBinding
+		skip     func(*networkingv1alpha1.TunnelBinding) bool
+		origins  func(*networkingv1alpha1.TunnelBinding) map[int]string
+		// want is the owner of each rule key, as namespace/name/subject
+		want map[string]string
+	}{
+		{
+			name:     "oldest binding",
+			bindings: []networkingv1alpha1.TunnelBinding{binding("default", "a", newer, "app.example.com"), binding("default", "b", older, "App.example.com")},
+			want:     map[string]string{"app.example.com ": "default/b/0"},
+		},
+		{
+			name:     "same age by namespace and name",
+			bindings: []networkingv1alpha1.TunnelBinding{binding("team", "a", older, "app.example.com"), binding("default", "b", older, "app.example.com")},
+			want:     map[string]string{"app.example.com ": "default/b/0"},
+		},
+		{
+			name:     "first subject of a binding",
+			bindings: []networkingv1alpha1.TunnelBinding{binding("default", "a", older, "app.example.com", "app.example.com")},
+			want:     map[string]string{"app.example.com ": "default/a/0"},
+		},
+		{
+			name: "different paths",
+			bindings: []networkingv1alpha1.TunnelBinding{
+				withPath(binding("default", "a", older, "app.example.com"), 0, "/api"), binding("default", "b", newer, "app.example.com"),
+			},
+			want: map[string]string{"app.example.com /api": "default/a/0", "app.example.com ": "default/b/0"},
+		},
+		{
+			name: "invalid path claims the hostname",
+			bindings: []networkingv1alpha1.TunnelBinding{
+				binding("default", "a", older, "app.example.com"), withPath(binding("default", "b", newer, "app.example.com"), 0, "/api("),
+			},
+			want: map[string]string{"app.example.com ": "default/a/0"},
+		},
+		{
+			name:     "skipped binding",
+			bindings: []networkingv1alpha1.TunnelBinding{binding("default", "a", older, "app.example.com"), binding("default", "b", newer, "app.example.com")},
+			skip:     func(b *networkingv1alpha1.TunnelBinding) bool { return b.Name == "a" },
+			want:     map[string]string{"app.example.com ": "default/b/0"},
+		},
+		{
+			name:     "canary origin",
+			bindings: []networkingv1alpha1.TunnelBinding{binding("default", "a", older, "app.example.com")},
+			origins:  func(*networkingv1alpha1.TunnelBinding) map[int]string { return map[int]string{0: "a.app.example.com"} },
+			want:     map[string]string{"a.app.example.com ": "default/a/0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip, origins := tt.skip, tt.origins
+			if skip == nil {
+				skip = noSkip
+			}
+			if origins == nil {
+				origins = noCanary
+			}
+			got := map[string]string{}
+			for key, owner := range ruleOwners(tt.bindings, skip, origins) {
+				got[key] = fmt.Sprintf("%s/%s/%d", owner.binding.Namespace, owner.binding.Name, owner.subject)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ruleOwners() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigureCloudflareDaemonRuleConflict(t *testing.T) {
+	_, api := newFakeCloudflare(t)
+	// Two Services claim app.example.com, the one of the older TunnelBinding keeps it
+	older := newTestBinding("older", "app.example.com")
+	older.CreationTimestamp = metav1.Unix(1000, 0)
+	newer := newTestBinding("newer", "app.example.com")
+	newer.CreationTimestamp = metav1.Unix(2000, 0)
+	r := newTestBindingReconciler(t, api, newer, older)
+
+	if err := r.configureCloudflareDaemon(); err != nil {
+		t.Fatalf("configureCloudflareDaemon() error = %v", err)
+	}
+	ingress := configuredIngress(t, r)
+	if len(ingress) != 2 || ingress[0].Service != "http://older.default.svc:80" {
+		t.Errorf("ingress = %+v, want app.example.com routed to the Service of the older TunnelBinding only", ingress)
+	}
+	if events := r.Recorder.(*record.FakeRecorder).Events; !hasEvent(events, "HostnameConflict") {
+		t.Errorf("no HostnameConflict event")
+	}
+}
+
+func TestConfigureCloudflareDaemonStaleStatus(t *testing.T) {
+	_, api := newFakeCloudflare(t)
+	// A subject was added to the other TunnelBinding since its last reconcile, its status is not set yet
+	stale := newTestBinding("stale", "stale.example.com", "added.example.com")
+	stale.Status.Services = stale.Status.Services[:1]
+	r := newTestBindingReconciler(t, api, newTestBinding("app", "app.example.com"), stale)
+	r.ManageLoadBalancers = true
+
+	if err := r.configureCloudflareDaemon(); err != nil {
+		t.Fatalf("configureCloudflareDaemon() error = %v", err)
+	}
+	if got := hostnames(configuredIngress(t, r)); got != "app.example.com stale.example.com -" {
+		t.Errorf("ingress = %s, want the rules of the subjects with a status and the catch-all", got)
+	}
+}
+
+func TestCanaryGroups(t *testing.T) {
+	weighted := func(binding *networkingv1alpha1.TunnelBinding, weights ...int) *networkingv1alpha1.TunnelBinding {
+		for i, weight := range weights {
+			if weight >= 0 {
+				binding.Subjects[i].Spec.Weight = ptr(weight)
+			}
+		}
+		return binding
+	}
+	tests := []struct {
+		name       string
+		binding    *networkingv1alpha1.TunnelBinding
+		want       map[string][]int
+		wantErrors int
+	}{
+		{name: "unweighted", binding: newTestBinding("app", "app.example.com", "app.example.com"), want: map[string][]int{}},
+		{
+			name:    "weighted",
+			binding: weighted(newTestBinding("app", "App.example.com", "app.example.com", "other.example.com"), 90, 10),
+			want:    map[string][]int{"app.example.com": {0, 1}},
+		},
+		{
+			name:       "sum below 100",
+			binding:    weighted(newTestBinding("app", "app.example.com", "app.example.com"), 50, 10),
+			want:       map[string][]int{},
+			wantErrors: 1,
+		},
+		{
+			name:       "unweighted subject of a weighted hostname",
+			binding:    weighted(newTestBinding("app", "app.example.com", "app.example.com"), 100, -1),
+			want:       map[string][]int{},
+			wantErrors: 1,
+		},
+		{
+			name:       "wildcard",
+			binding:    weighted(newTestBinding("app", "*.example.com", "*.example.com"), 50, 50),
+			want:       map[string][]int{},
+			wantErrors: 1,
+		},
+		{
+			name: "path",
+			binding: func() *networkingv1alpha1.TunnelBinding {
+				binding := weighted(newTestBinding("app", "app.example.com", "app.example.com"), 50, 50)
+				binding.Subjects[1].Spec.Path = "/api"
+				return binding
+			}(),
+			want:       map[string][]int{},
+			wantErrors: 1,
+		},
+		{
+			name: "subject without status",
+			binding: func() *networkingv1alpha1.TunnelBinding {
+				binding := weighted(newTestBinding("app", "app.example.com", "app.example.com"), 90, 10)
+				binding.Status.Services = binding.Status.Services[:1]
+				return binding
+			}(),
+			want:       map[string][]int{},
+			wantErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := canaryGroups(tt.binding)
+			if !reflect.DeepEqual(got, tt.want) || len(errs) != tt.wantErrors {
+				t.Errorf("canaryGroups() = %v, %v, want %v with %d errors", got, errs, tt.want, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestCreateLoadBalancerLogic(t *testing.T) {
+	f, api := newFakeCloudflare(t)
+	owner := loadBalancerDescriptionPrefix + "default/app"
+	// The binding owns the Load Balancer of old.example.com, not weighted anymore
+	f.respond(http.MethodGet, "/zones/zone/load_balancers", http.StatusOK, []cloudflare.LoadBalancer{
+		{ID: "old-lb", Name: "old.example.com", Description: owner},
+		{ID: "other-lb", Name: "other.example.com", Description: loadBalancerDescriptionPrefix + "default/other"},
+	})
+	f.respond(http.MethodGet, "/accounts/account/load_balancers/pools", http.StatusOK, []cloudflare.LoadBalancerPool{
+		{ID: "old-pool", Name: "old-example-com", Description: owner},
+	})
+	f.respond(http.MethodDelete, "/zones/zone/load_balancers/old-lb", http.StatusOK, cloudflare.LoadBalancer{ID: "old-lb"})
+	f.respond(http.MethodDelete, "/accounts/account/load_balancers/pools/old-pool", http.StatusOK, cloudflare.LoadBalancerPool{ID: "old-pool"})
+	f.respond(http.MethodPost, "/accounts/account/load_balancers/pools", http.StatusOK, cloudflare.LoadBalancerPool{ID: "app-pool"})
+	f.respond(http.MethodPost, "/zones/zone/load_balancers", http.StatusOK, cloudflare.LoadBalancer{ID: "app-lb"})
+
+	binding := newTestBinding("app", "app.example.com", "app.example.com")
+	binding.Subjects[0].Spec.Weight, binding.Subjects[1].Spec.Weight = ptr(90), ptr(10)
+	r := newTestBindingReconciler(t, api, binding)
+	r.ManageLoadBalancers = true
+
+	if err := r.createLoadBalancerLogic(); err != nil {
+		t.Fatalf("createLoadBalancerLogic() error = %v", err)
+	}
+	if f.count(http.MethodDelete, "/zones/zone/load_balancers/old-lb") != 1 || f.count(http.MethodDelete, "/accounts/account/load_balancers/pools/old-pool") != 1 {
+		t.Errorf("requests = %v, want the Load Balancer and pool of old.example.com deleted", f.requests)
+	}
+
+	pools := f.bodies["POST /accounts/account/load_balancers/pools"]
+	if len(pools) != 1 {
+		t.Fatalf("created %d pools, want 1", len(pools))
+	}
+	pool := cloudflare.LoadBalancerPool{}
+	if err := json.Unmarshal([]byte(pools[0]), &pool); err != nil {
+		t.Fatal(err)
+	}
+	if pool.Name != "app-example-com" || pool.Description != owner || len(pool.Origins) != 2 {
+		t.Fatalf("pool = %+v, want the pool of app.example.com with an origin per subject", pool)
+	}
+	for i, want := range []struct {
+		host   string
+		weight float64
+	}{{"canary-0.app.example.com", 0.9}, {"canary-1.app.example.com", 0.1}} {
+		origin := pool.Origins[i]
+		if origin.Address != api.TunnelCName() || origin.Weight != want.weight || len(origin.Header["Host"]) != 1 || origin.Header["Host"][0] != want.host {
+			t.Errorf("origin %d = %+v, want weight %v to the tunnel with Host %s", i, origin, want.weight, want.host)
+		}
+	}
+
+	loadBalancers := f.bodies["POST /zones/zone/load_balancers"]
+	if len(loadBalancers) != 1 {
+		t.Fatalf("created %d Load Balancers, want 1", len(loadBalancers))
+	}
+	loadBalancer := cloudflare.LoadBalancer{}
+	if err := json.Unmarshal([]byte(loadBalancers[0]), &loadBalancer); err != nil {
+		t.Fatal(err)
+	}
+	if loadBalancer.Name != "app.example.com" || loadBalancer.FallbackPool != "app-pool" || !reflect.DeepEqual(loadBalancer.DefaultPools, []string{"app-pool"}) {
+		t.Errorf("Load Balancer = %+v, want app.example.com with the created pool", loadBalancer)
+	}
+}
+
+func TestCreationLogicInvalidZone(t *testing.T) {
+	f, api := newFakeCloudflare(t)
+	r := newTestBindingReconciler(t, api, newTestBinding("app", "app.example.com"))
+	// The tunnel controller did not find the domain in the zones of the account
+	r.zoneCondition = &metav1.Condition{Type: conditionZoneValid, Status: metav1.ConditionFalse, Reason: "ZoneNotFound", Message: "no zone"}
+
+	if _, err := r.creationLogic(); err != nil {
+		t.Fatalf("creationLogic() error = %v", err)
+	}
+	if len(f.requests) != 0 {
+		t.Errorf("requests = %v, want no DNS entries created", f.requests)
+	}
+	if r.dnsCondition == nil || r.dnsCondition.Reason != "InvalidZone" {
+		t.Errorf("DNSReady condition = %+v, want InvalidZone", r.dnsCondition)
+	}
+	if events := r.Recorder.(*record.FakeRecorder).Events; !hasEvent(events, "InvalidZone") {
+		t.Errorf("no InvalidZone event")
+	}
+}
+
+func TestConfigureCloudflareDaemonMetrics(t *testing.T) {
+	_, api := newFakeCloudflare(t)
+	binding := newTestBinding("app", "a.example.com", "b.example.com")
+	r := newTestBindingReconciler(t, api, binding)
+	t.Cleanup(func() { deleteTunnelMetrics(tunnelKey{Kind: "tunnel", Namespace: "default", Name: "tunnel"}) })
+
+	gauges := func() (float64, float64) {
+		return testutil.ToFloat64(tunnelManagedServices.WithLabelValues("tunnel", "default", "tunnel")),
+			testutil.ToFloat64(tunnelIngressRules.WithLabelValues("tunnel", "default", "tunnel"))
+	}
+	if err := r.configureCloudflareDaemon(); err != nil {
+		t.Fatalf("configureCloudflareDaemon() error = %v", err)
+	}
+	if services, rules := gauges(); services != 2 || rules != 3 {
+		t.Errorf("managed services = %v, ingress rules = %v, want 2 and 3", services, rules)
+	}
+
+	// Removing a subject lowers the gauges
+	binding.Subjects, binding.Status.Services = binding.Subjects[:1], binding.Status.Services[:1]
+	if err := r.Update(context.Background(), binding); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.configureCloudflareDaemon(); err != nil {
+		t.Fatalf("configureCloudflareDaemon() error = %v", err)
+	}
+	if services, rules := gauges(); services != 1 || rules != 2 {
+		t.Errorf("managed services = %v, ingress rules = %v after removing a subject, want 1 and 2", services, rules)
+	}
+}
+
+func TestTunnelNotFoundDeletesMetrics(t *testing.T) {
+	tunnelManagedServices.WithLabelValues("tunnel", "default", "gone").Set(2)
+	tunnelIngressRules.WithLabelValues("tunnel", "default", "gone").Set(3)
+	tunnelManagedServices.WithLabelValues("clustertunnel", "cloudflare-operator-system", "gone").Set(2)
+	tunnelIngressRules.WithLabelValues("clustertunnel", "cloudflare-operator-system", "gone").Set(3)
+
+	request := ctrl.Request{NamespacedName: apitypes.NamespacedName{Namespace: "default", Name: "gone"}}
+	if _, err := (&TunnelReconciler{Client: newFakeClient(t)}).Reconcile(context.Background(), request); err != nil {
+		t.Fatalf("TunnelReconciler.Reconcile() error = %v", err)
+	}
+	request = ctrl.Request{NamespacedName: apitypes.NamespacedName{Name: "gone"}}
+	clusterTunnels := &ClusterTunnelReconciler{Client: newFakeClient(t), Namespace: "cloudflare-operator-system"}
+	if _, err := clusterTunnels.Reconcile(context.Background(), request); err != nil {
+		t.Fatalf("ClusterTunnelReconciler.Reconcile() error = %v", err)
+	}
+
+	for _, gauge := range []*prometheus.GaugeVec{tunnelManagedServices, tunnelIngressRules} {
+		for _, labels := range []prometheus.Labels{
+			{"kind": "tunnel", "namespace": "default", "tunnel": "gone"},
+			{"kind": "clustertunnel", "namespace": "cloudflare-operator-system", "tunnel": "gone"},
+		} {
+			if gauge.Delete(labels) {
+				t.Errorf("series %v still reported after the tunnel is gone", labels)
+			}
+		}
+	}
+}
+
+func TestCreationLogicMixedProxied(t *testing.T) {
+	f, api := newFakeCloudflare(t)
+	f.fallback = func(w http.ResponseWriter, r *http.Request, body []byte) {
+		switch {
+		case r.Method == http.MethodGet:
+			writeFakeResponse(w, http.StatusOK, []cloudflare.DNSRecord{})
+		case r.Method == http.MethodPost && r.URL.Path == "/zones/zone/dns_records/batch":
+			batch := dnsBatch{}
+			_ = json.Unmarshal(body, &batch)
+			result := dnsBatchResult{}
+			for _, record := range batch.Posts {
+				result.Posts = append(result.Posts, cloudflare.DNSRecord{ID: record.Name + "-id", Name: record.Name})
+			}
+			writeFakeResponse(w, http.StatusOK, result)
+		default:
+			writeFakeResponse(w, http.StatusNotFound, "no route")
+		}
+	}
+	// The same Service under a proxied hostname and an unproxied one
+	binding := newTestBinding("app", "app.example.com", "app-direct.example.com")
+	binding.Subjects[0].Name = "app"
+	binding.Subjects[1].Name = "app"
+	binding.Subjects[1].Spec.Proxied = ptr(false)
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "tunnel"}}
+	deployment.Status.ReadyReplicas = 1
+	r := newTestBindingReconciler(t, api, binding, deployment)
+
+	if err := r.configureCloudflareDaemon(); err != nil {
+		t.Fatalf("configureCloudflareDaemon() error = %v", err)
+	}
+	targets := map[string]string{}
+	for _, rule := range configuredIngress(t, r) {
+		targets[rule.Hostname] = rule.Service
+	}
+	for _, hostname := range []string{"app.example.com", "app-direct.example.com"} {
+		if targets[hostname] != "http://app.default.svc:80" {
+			t.Errorf("ingress rule of %s = %q, want the Service", hostname, targets[hostname])
+		}
+	}
+
+	if _, err := r.creationLogic(); err != nil {
+		t.Fatalf("creationLogic() error = %v", err)
+	}
+	batches := f.bodies["POST /zones/zone/dns_records/batch"]
+	if len(batches) == 0 {
+		t.Fatalf("requests = %v, want the records upserted in a batch", f.requests)
+	}
+	cnames := dnsBatch{}
+	if err := json.Unmarshal([]byte(batches[0]), &cnames); err != nil {
+		t.Fatalf("invalid CNAME batch body: %v", err)
+	}
+	proxied := map[string]bool{}
+	for _, record := range cnames.Posts {
+		proxied[record.Name] = record.Proxied != nil && *record.Proxied
+	}
+	want := map[string]bool{"app.example.com": true, "app-direct.example.com": false}
+	if !reflect.DeepEqual(proxied, want) {
+		t.Errorf("proxied records = %v, want %v", proxied, want)
+	}
+}
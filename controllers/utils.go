@@ -2,43 +2,161 @@ package controllers
 
 import (
 	"context"
-
-	corev1 "k8s.io/api/core/v1"
-	apitypes "k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
 
 	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/go-logr/logr"
 )
 
+// DefaultAnnotationPrefix is the default prefix of the labels, annotations and finalizers managed by the operator
+const DefaultAnnotationPrefix = "cfargotunnel.com"
+
 const (
+	tunnelProtoHTTP  = "http"
+	tunnelProtoHTTPS = "https"
+	tunnelProtoRDP   = "rdp"
+	tunnelProtoSMB   = "smb"
+	tunnelProtoSSH   = "ssh"
+	tunnelProtoTCP   = "tcp"
+	tunnelProtoUDP   = "udp"
+
+	// Cache index of TunnelBindings on the key of the tunnel they reference
+	tunnelRefIndex = "tunnelRef"
+
+	// Ingress service of bastion mode rules
+	bastionService = "bastion"
+
+	// Ingress service of subjects in maintenance
+	maintenanceService = "http_status:503"
+
+	// Prefix of targets proxying to a unix socket, like unix:/var/run/app.sock
+	unixSocketPrefix = "unix:"
+
+	configmapKey = "config.yaml"
+
+	// Maximum size of the data of a ConfigMap, and the percentage of it past which the tunnel configurations are reported as nearly full
+	configMapMaxSize     = 1024 * 1024
+	configMapWarnPercent = 90
+
+	// Volume, directory and file name of the CA bundle of the tunnel spec.caPool in the cloudflared container
+	caPoolVolumeName = "ca-pool"
+	caPoolMountPath  = "/etc/cloudflared/ca"
+	caPoolFile       = "ca.crt"
+
+	// Key of the ConfigMap the last unparseable config is backed up to before rebuilding it
+	configmapCorruptKey = "config.yaml.corrupt"
+
+	// Condition of Tunnels and ClusterTunnels reporting whether the Cloudflare API credentials work
+	conditionAuthenticated = "Authenticated"
+
+	// Condition of Tunnels and ClusterTunnels reporting whether the domain is a zone of the account
+	conditionZoneValid = "ZoneValid"
+
+	// Condition of TunnelBindings reporting whether the ingress rules are in the tunnel configuration
+	conditionConfigReady = "ConfigReady"
+
+	// Condition of TunnelBindings reporting whether the DNS entries point to the tunnel
+	conditionDNSReady = "DNSReady"
+
+	// Condition of TunnelBindings reporting whether the edge certificates of the hostnames are active
+	conditionCertificateReady = "CertificateReady"
+)
+
+// Labels, annotations and finalizers, derived from the prefix set with SetAnnotationPrefix
+var (
 	// Protocol to use between cloudflared and the Service.
-	tunnelProtoAnnotation = "cfargotunnel.com/proto"
-	tunnelProtoHTTP       = "http"
-	tunnelProtoHTTPS      = "https"
-	tunnelProtoRDP        = "rdp"
-	tunnelProtoSMB        = "smb"
-	tunnelProtoSSH        = "ssh"
-	tunnelProtoTCP        = "tcp"
-	tunnelProtoUDP        = "udp"
+	tunnelProtoAnnotation string
+
+	// Force re-assertion and verification of the DNS records of a TunnelBinding, set to any new value to trigger
+	tunnelForceDNSSyncAnnotation string
+
+	// Skip creating the DNS records of a TunnelBinding while set to true, the ingress rules are still configured
+	tunnelDNSPausedAnnotation string
+
+	// Do not add the finalizer to a TunnelBinding while set to true, leaving its DNS records behind on deletion
+	tunnelNoFinalizerAnnotation string
+
+	// Hostnames a Service is tunneled on, set by the operator for visibility
+	tunnelResolvedFqdnAnnotation string
+
+	// RFC3339 time after which the DNS records and ingress rules of a TunnelBinding are removed, for preview environments
+	tunnelExpiresAtAnnotation string
+
+	// Delete the TunnelBinding itself once it expired while set to true
+	tunnelDeleteOnExpiryAnnotation string
+
+	// Group of the ingress rules of a TunnelBinding, rules of a group are kept together and groups sorted by name
+	tunnelGroupAnnotation string
+
+	// Keep the existing catch-all rule of the tunnel while set to true on any of its TunnelBindings, for a catch-all managed elsewhere
+	tunnelNoCatchAllAnnotation string
 
 	// Checksum of the config, used to restart pods in the deployment
-	tunnelConfigChecksum = "cfargotunnel.com/checksum"
+	tunnelConfigChecksum string
 
 	// Tunnel properties labels
-	tunnelLabel          = "cfargotunnel.com/tunnel"
-	clusterTunnelLabel   = "cfargotunnel.com/cluster-tunnel"
-	isClusterTunnelLabel = "cfargotunnel.com/is-cluster-tunnel"
-	tunnelIdLabel        = "cfargotunnel.com/id"
-	tunnelNameLabel      = "cfargotunnel.com/name"
-	tunnelKindLabel      = "cfargotunnel.com/kind"
-	tunnelAppLabel       = "cfargotunnel.com/app"
-	tunnelDomainLabel    = "cfargotunnel.com/domain"
-	tunnelFinalizer      = "cfargotunnel.com/finalizer"
-	configmapKey         = "config.yaml"
+	tunnelLabel          string
+	clusterTunnelLabel   string
+	isClusterTunnelLabel string
+	tunnelIdLabel        string
+	tunnelNameLabel      string
+	tunnelKindLabel      string
+	tunnelAppLabel       string
+	tunnelDomainLabel    string
+	tunnelFinalizer      string
+
+	// Keys set on Services by the Service controller of pre v0.9 versions, which are not used anymore
+	legacyServiceAnnotations []string
+	legacyServiceLabels      []string
 )
 
+func init() {
+	SetAnnotationPrefix(DefaultAnnotationPrefix)
+}
+
+// SetAnnotationPrefix sets the prefix of all labels, annotations and finalizers managed by the operator.
+// It must be called before the controllers are started.
+func SetAnnotationPrefix(prefix string) {
+	tunnelProtoAnnotation = prefix + "/proto"
+	tunnelForceDNSSyncAnnotation = prefix + "/force-dns-sync"
+	tunnelDNSPausedAnnotation = prefix + "/dns-paused"
+	tunnelNoFinalizerAnnotation = prefix + "/no-finalizer"
+	tunnelResolvedFqdnAnnotation = prefix + "/resolved-fqdn"
+	tunnelExpiresAtAnnotation = prefix + "/expires-at"
+	tunnelDeleteOnExpiryAnnotation = prefix + "/delete-on-expiry"
+	tunnelGroupAnnotation = prefix + "/group"
+	tunnelNoCatchAllAnnotation = prefix + "/no-catchall"
+	tunnelConfigChecksum = prefix + "/checksum"
+	tunnelLabel = prefix + "/tunnel"
+	clusterTunnelLabel = prefix + "/cluster-tunnel"
+	isClusterTunnelLabel = prefix + "/is-cluster-tunnel"
+	tunnelIdLabel = prefix + "/id"
+	tunnelNameLabel = prefix + "/name"
+	tunnelKindLabel = prefix + "/kind"
+	tunnelAppLabel = prefix + "/app"
+	tunnelDomainLabel = prefix + "/domain"
+	tunnelFinalizer = prefix + "/finalizer"
+	legacyServiceAnnotations = []string{
+		prefix + "/tunnel",
+		prefix + "/cluster-tunnel",
+		prefix + "/fqdn",
+		prefix + "/proto",
+		prefix + "/target",
+		prefix + "/caPool",
+		prefix + "/noTlsVerify",
+	}
+	legacyServiceLabels = []string{tunnelLabel, clusterTunnelLabel, isClusterTunnelLabel, tunnelIdLabel, tunnelNameLabel, tunnelKindLabel, tunnelDomainLabel}
+}
+
 var tunnelValidProtoMap map[string]bool = map[string]bool{
 	tunnelProtoHTTP:  true,
 	tunnelProtoHTTPS: true,
@@ -49,25 +167,26 @@ var tunnelValidProtoMap map[string]bool = map[string]bool{
 	tunnelProtoUDP:   true,
 }
 
-func getAPIDetails(ctx context.Context, c client.Client, log logr.Logger, tunnelSpec networkingv1alpha1.TunnelSpec, tunnelStatus networkingv1alpha1.TunnelStatus, namespace string) (*CloudflareAPI, *corev1.Secret, error) {
+// getAPIDetails returns the Cloudflare API client of the tunnel, and the credentials it was built from
+func getAPIDetails(ctx context.Context, provider CredentialProvider, log logr.Logger, tunnelSpec networkingv1alpha1.TunnelSpec, tunnelStatus networkingv1alpha1.TunnelStatus, namespace string) (*CloudflareAPI, map[string][]byte, error) {
 
-	// Get secret containing API token
-	cfSecret := &corev1.Secret{}
-	if err := c.Get(ctx, apitypes.NamespacedName{Name: tunnelSpec.Cloudflare.Secret, Namespace: namespace}, cfSecret); err != nil {
-		log.Error(err, "secret not found", "secret", tunnelSpec.Cloudflare.Secret)
-		return &CloudflareAPI{}, &corev1.Secret{}, err
+	// Get credentials containing API token
+	credentials, err := provider.Credentials(ctx, tunnelSpec, namespace)
+	if err != nil {
+		log.Error(err, "credentials not found", "secret", tunnelSpec.Cloudflare.Secret)
+		return &CloudflareAPI{}, nil, err
 	}
 
-	// Read secret for API Token
-	cfAPITokenB64, ok := cfSecret.Data[tunnelSpec.Cloudflare.CLOUDFLARE_API_TOKEN]
+	// Read credentials for API Token
+	cfAPITokenB64, ok := credentials[tunnelSpec.Cloudflare.CLOUDFLARE_API_TOKEN]
 	if !ok {
-		log.Info("key not found in secret", "secret", tunnelSpec.Cloudflare.Secret, "key", tunnelSpec.Cloudflare.CLOUDFLARE_API_TOKEN)
+		log.Info("key not found in credentials", "secret", tunnelSpec.Cloudflare.Secret, "key", tunnelSpec.Cloudflare.CLOUDFLARE_API_TOKEN)
 	}
 
-	// Read secret for API Key
-	cfAPIKeyB64, ok := cfSecret.Data[tunnelSpec.Cloudflare.CLOUDFLARE_API_KEY]
+	// Read credentials for API Key
+	cfAPIKeyB64, ok := credentials[tunnelSpec.Cloudflare.CLOUDFLARE_API_KEY]
 	if !ok {
-		log.Info("key not found in secret", "secret", tunnelSpec.Cloudflare.Secret, "key", tunnelSpec.Cloudflare.CLOUDFLARE_API_KEY)
+		log.Info("key not found in credentials", "secret", tunnelSpec.Cloudflare.Secret, "key", tunnelSpec.Cloudflare.CLOUDFLARE_API_KEY)
 	}
 
 	apiToken := string(cfAPITokenB64)
@@ -90,11 +209,11 @@ func getAPIDetails(ctx context.Context, c client.Client, log logr.Logger, tunnel
 	cloudflareClient, err := getCloudflareClient(apiKey, apiEmail, apiToken)
 	if err != nil {
 		log.Error(err, "error initializing cloudflare api client", "client", cloudflareClient)
-		return &CloudflareAPI{}, &corev1.Secret{}, err
+		return &CloudflareAPI{}, nil, fmt.Errorf("failed to initialize cloudflare api client: %w", err)
 	}
 	cfAPI.CloudflareClient = cloudflareClient
 
-	return cfAPI, cfSecret, nil
+	return cfAPI, credentials, nil
 }
 
 // getCloudflareClient returns an initialized *cloudflare.API using either an API Key + Email or an API Token
@@ -108,3 +227,237 @@ func getCloudflareClient(apiKey, apiEmail, apiToken string) (*cloudflare.API, er
 	}
 	return cloudflareClient, err
 }
+
+// validateHostname checks the hostname fits the DNS length limits, which Cloudflare rejects with an opaque error
+func validateHostname(hostname string) error {
+	if len(hostname) > 253 {
+		return fmt.Errorf("hostname %s too long: %d characters, maximum is 253", hostname, len(hostname))
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if len(label) > 63 {
+			return fmt.Errorf("hostname label too long: %s in %s has %d characters, maximum is 63", label, hostname, len(label))
+		}
+	}
+	return nil
+}
+
+// validateServiceURL checks the generated service URL parses with a host and a valid port,
+// cloudflared refuses to start with an invalid one, taking down all the services of the tunnel
+func validateServiceURL(serviceURL string) error {
+	parsed, err := url.Parse(serviceURL)
+	if err != nil {
+		return fmt.Errorf("service %s is not a valid URL: %w", serviceURL, err)
+	}
+	if parsed.Scheme == "" || parsed.Hostname() == "" {
+		return fmt.Errorf("service %s must have a scheme and a host", serviceURL)
+	}
+	if port := parsed.Port(); port != "" {
+		if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+			return fmt.Errorf("service %s has an invalid port %s", serviceURL, port)
+		}
+	}
+	return nil
+}
+
+// validatePath checks the ingress path compiles as a Go regular expression, cloudflared refuses to start otherwise
+func validatePath(pathRegex string) error {
+	if _, err := regexp.Compile(pathRegex); err != nil {
+		return fmt.Errorf("path %s is not a valid regular expression: %w", pathRegex, err)
+	}
+	return nil
+}
+
+// imageReferenceRegex matches a container image reference, [registry[:port]/]repository[:tag][@digest]
+var imageReferenceRegex = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9.-]*[a-zA-Z0-9])?(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// validateImage checks the image is a well-formed image reference, the pods would fail to pull it otherwise
+func validateImage(image string) error {
+	if image != "" && !imageReferenceRegex.MatchString(image) {
+		return fmt.Errorf("image %q is not a valid image reference", image)
+	}
+	return nil
+}
+
+// clusterDomainRegex matches a lowercase DNS domain without leading or trailing dots, like cluster.local
+var clusterDomainRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// validateClusterDomain checks the cluster domain is a DNS domain, the generated targets would not resolve otherwise
+func validateClusterDomain(clusterDomain string) error {
+	if clusterDomain != "" && !clusterDomainRegex.MatchString(clusterDomain) {
+		return fmt.Errorf("clusterDomain %q is not a valid DNS domain", clusterDomain)
+	}
+	return nil
+}
+
+// serviceHost returns the in-cluster host of the Service, <service>.<namespace>.svc followed by the cluster domain
+// of the tunnel if set. An invalid cluster domain is reported on the tunnel and ignored here.
+func serviceHost(tunnel Tunnel, name string, namespace string) string {
+	host := fmt.Sprintf("%s.%s.svc", name, namespace)
+	if tunnel == nil {
+		return host
+	}
+	if clusterDomain := tunnel.GetSpec().ClusterDomain; clusterDomain != "" && validateClusterDomain(clusterDomain) == nil {
+		host += "." + clusterDomain
+	}
+	return host
+}
+
+// configChecksum returns the checksum of the tunnel configuration set on the Deployment template to restart the pods on changes
+func configChecksum(configStr string) string {
+	hash := md5.Sum([]byte(configStr))
+	return hex.EncodeToString(hash[:])
+}
+
+// accessTeamDomainSuffix is the domain of the Cloudflare Zero Trust teams
+const accessTeamDomainSuffix = ".cloudflareaccess.com"
+
+// accessTeamNameRegex matches a Cloudflare Zero Trust team name, a single DNS label
+var accessTeamNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// validateAccess checks the Access config is complete, cloudflared refuses to start with a partial one.
+// It returns the config to emit, nil if access is not configured.
+func validateAccess(access *networkingv1alpha1.AccessSpec) (*AccessConfig, error) {
+	if access == nil {
+		return nil, nil
+	}
+	teamName := strings.TrimSuffix(strings.ToLower(access.TeamName), accessTeamDomainSuffix)
+	if teamName == "" {
+		return nil, fmt.Errorf("access teamName is required")
+	}
+	if !accessTeamNameRegex.MatchString(teamName) {
+		return nil, fmt.Errorf("access teamName %q is not a Cloudflare team name like myteam or myteam%s", access.TeamName, accessTeamDomainSuffix)
+	}
+	if len(access.AudTag) == 0 {
+		return nil, fmt.Errorf("access audTag is required")
+	}
+	for _, audTag := range access.AudTag {
+		if audTag == "" {
+			return nil, fmt.Errorf("access audTag cannot be empty")
+		}
+	}
+	return &AccessConfig{
+		Required: access.Required == nil || *access.Required,
+		TeamName: teamName,
+		AudTag:   access.AudTag,
+	}, nil
+}
+
+// normalizeUnixSocketTarget returns the unix socket target in the unix:/path form used by cloudflared,
+// accepting the URL form unix:///path as well. The socket path must be absolute.
+func normalizeUnixSocketTarget(target string) (string, error) {
+	socketPath := strings.TrimPrefix(target, unixSocketPrefix)
+	if strings.HasPrefix(socketPath, "///") {
+		socketPath = strings.TrimPrefix(socketPath, "//")
+	}
+	if !path.IsAbs(socketPath) {
+		return "", fmt.Errorf("unix socket path in target %s must be absolute", target)
+	}
+	return unixSocketPrefix + path.Clean(socketPath), nil
+}
+
+// parseIPTarget splits a target with an IP literal host, like tcp://10.0.0.5:5432, 10.0.0.5:5432 or [fd00::5]:5432,
+// into its scheme, empty if not set, host and port. ok is false if the target host is not an IP literal.
+// IPv6 addresses must be in brackets, fd00::5:5432 is ambiguous and rejected.
+func parseIPTarget(target string) (scheme string, host string, port int32, ok bool, err error) {
+	hostPort := target
+	if i := strings.Index(target, "://"); i >= 0 {
+		scheme, hostPort = target[:i], target[i+len("://"):]
+	}
+	host, portString, splitErr := net.SplitHostPort(hostPort)
+	if splitErr != nil {
+		if net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(hostPort, "["), "]")) != nil {
+			return "", "", 0, true, fmt.Errorf("IP target %s must be in the ip:port form, with IPv6 addresses in brackets", target)
+		}
+		return "", "", 0, false, nil
+	}
+	if net.ParseIP(host) == nil {
+		return "", "", 0, false, nil
+	}
+	number, err := strconv.Atoi(portString)
+	if err != nil || number < 1 || number > 65535 {
+		return "", "", 0, true, fmt.Errorf("IP target %s has an invalid port %s", target, portString)
+	}
+	if scheme != "" && !tunnelValidProtoMap[scheme] {
+		return "", "", 0, true, fmt.Errorf("IP target %s has an unsupported protocol %s", target, scheme)
+	}
+	return scheme, host, int32(number), true, nil
+}
+
+// isDirectTarget checks if the target reaches the backend without going through the Service of the subject,
+// like unix sockets and IP literals
+func isDirectTarget(target string) bool {
+	if strings.HasPrefix(target, unixSocketPrefix) {
+		return true
+	}
+	_, _, _, ok, _ := parseIPTarget(target)
+	return ok
+}
+
+// protoForAppProtocol returns the cloudflared protocol for the appProtocol of a TCP Service port, or empty if it has none.
+// gRPC needs HTTP/2, which cloudflared only speaks to TLS origins, so it maps to https and needs http2Origin as well.
+func protoForAppProtocol(appProtocol string) string {
+	if isH2CAppProtocol(appProtocol) {
+		return tunnelProtoHTTP
+	}
+	switch appProtocol = strings.ToLower(appProtocol); appProtocol {
+	case "kubernetes.io/ws":
+		return tunnelProtoHTTP
+	case "kubernetes.io/wss", "grpc":
+		return tunnelProtoHTTPS
+	case tunnelProtoUDP:
+		return ""
+	}
+	if tunnelValidProtoMap[appProtocol] {
+		return appProtocol
+	}
+	return ""
+}
+
+// isH2CAppProtocol checks if the appProtocol of a Service port is HTTP/2 over cleartext
+func isH2CAppProtocol(appProtocol string) bool {
+	switch strings.ToLower(appProtocol) {
+	case "kubernetes.io/h2c", "h2c":
+		return true
+	}
+	return false
+}
+
+// isHTTPService checks if the cloudflared ingress service is served over HTTP, like http(s) origins, unix sockets
+// and the built-in http_status and hello_world services
+func isHTTPService(service string) bool {
+	for _, prefix := range []string{tunnelProtoHTTP + "://", tunnelProtoHTTPS + "://", unixSocketPrefix, "http_status:", "hello_world"} {
+		if strings.HasPrefix(service, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBastionSubject rejects HTTP only options on bastion mode subjects, which would silently be ignored
+func validateBastionSubject(subject networkingv1alpha1.TunnelBindingSubject) error {
+	var httpOptions []string
+	if subject.Spec.HttpHostHeader != "" {
+		httpOptions = append(httpOptions, "httpHostHeader")
+	}
+	if subject.Spec.Http2Origin != nil && *subject.Spec.Http2Origin {
+		httpOptions = append(httpOptions, "http2Origin")
+	}
+	if subject.Spec.Path != "" {
+		httpOptions = append(httpOptions, "path")
+	}
+	if subject.Spec.CaPool != "" {
+		httpOptions = append(httpOptions, "caPool")
+	}
+	if subject.Spec.NoTlsVerify {
+		httpOptions = append(httpOptions, "noTlsVerify")
+	}
+	if subject.Spec.Target != "" {
+		httpOptions = append(httpOptions, "target")
+	}
+	if len(httpOptions) > 0 {
+		return fmt.Errorf("bastion mode subject %s cannot set %s", subject.Name, strings.Join(httpOptions, ", "))
+	}
+	return nil
+}
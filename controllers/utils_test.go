@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	networkingv1alpha1 "github.com/adyanth/cloudflare-operator/api/v1alpha1"
+)
+
+func TestValidateHostname(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname string
+		wantErr  bool
+	}{
+		{name: "valid", hostname: "app.example.com"},
+		{name: "longest label", hostname: strings.Repeat("a", 63) + ".example.com"},
+		{name: "label too long", hostname: strings.Repeat("a", 64) + ".example.com", wantErr: true},
+		{name: "longest hostname", hostname: strings.Repeat(strings.Repeat("a", 62)+".", 4) + "a"},
+		{name: "hostname too long", hostname: strings.Repeat(strings.Repeat("a", 62)+".", 4) + "ab", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateHostname(tt.hostname); (err != nil) != tt.wantErr {
+				t.Errorf("validateHostname(%q) error = %v, wantErr %v", tt.hostname, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeUnixSocketTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{target: "unix:/run/app.sock", want: "unix:/run/app.sock"},
+		{target: "unix:///run/app.sock", want: "unix:/run/app.sock"},
+		{target: "unix:/run/../var/run//app.sock", want: "unix:/var/run/app.sock"},
+		{target: "unix:run/app.sock", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := normalizeUnixSocketTarget(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeUnixSocketTarget(%q) error = %v, wantErr %v", tt.target, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeUnixSocketTarget(%q) = %q, want %q", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateBastionSubject(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    networkingv1alpha1.TunnelBindingSubjectSpec
+		wantErr string
+	}{
+		{name: "bastion only", spec: networkingv1alpha1.TunnelBindingSubjectSpec{BastionMode: true}},
+		{name: "http2Origin false", spec: networkingv1alpha1.TunnelBindingSubjectSpec{BastionMode: true, Http2Origin: ptr(false)}},
+		{
+			name:    "http options",
+			spec:    networkingv1alpha1.TunnelBindingSubjectSpec{BastionMode: true, HttpHostHeader: "app", Path: "/api", NoTlsVerify: true},
+			wantErr: "bastion mode subject ssh cannot set httpHostHeader, path, noTlsVerify",
+		},
+		{
+			name:    "target",
+			spec:    networkingv1alpha1.TunnelBindingSubjectSpec{BastionMode: true, Target: "ssh://localhost:22"},
+			wantErr: "bastion mode subject ssh cannot set target",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBastionSubject(networkingv1alpha1.TunnelBindingSubject{Name: "ssh", Spec: tt.spec})
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("validateBastionSubject() error = %v", err)
+			} else if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("validateBastionSubject() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: "/api"},
+		{path: "^/api/v[0-9]+/.*$"},
+		{path: "/(api|static)"},
+		{path: "/api/(v1", wantErr: true},
+		{path: "/api[", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if err := validatePath(tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("validatePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateServiceURL(t *testing.T) {
+	tests := []struct {
+		service string
+		wantErr bool
+	}{
+		{service: "http://app.default.svc:80"},
+		{service: "https://app.default.svc"},
+		{service: "tcp://[fd00::1]:5432"},
+		{service: "app.default.svc:80", wantErr: true},
+		{service: "http://:80", wantErr: true},
+		{service: "http://app.default.svc:0", wantErr: true},
+		{service: "http://app.default.svc:65536", wantErr: true},
+		{service: "http://app default.svc:80", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			if err := validateServiceURL(tt.service); (err != nil) != tt.wantErr {
+				t.Errorf("validateServiceURL(%q) error = %v, wantErr %v", tt.service, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAccess(t *testing.T) {
+	audTag := []string{"aud"}
+	tests := []struct {
+		name    string
+		access  *networkingv1alpha1.AccessSpec
+		want    *AccessConfig
+		wantErr bool
+	}{
+		{name: "not configured"},
+		{
+			name:   "required by default",
+			access: &networkingv1alpha1.AccessSpec{TeamName: "myteam", AudTag: audTag},
+			want:   &AccessConfig{Required: true, TeamName: "myteam", AudTag: audTag},
+		},
+		{
+			name:   "team domain",
+			access: &networkingv1alpha1.AccessSpec{Required: ptr(false), TeamName: "MyTeam.cloudflareaccess.com", AudTag: audTag},
+			want:   &AccessConfig{Required: false, TeamName: "myteam", AudTag: audTag},
+		},
+		{name: "no teamName", access: &networkingv1alpha1.AccessSpec{AudTag: audTag}, wantErr: true},
+		{name: "invalid teamName", access: &networkingv1alpha1.AccessSpec{TeamName: "my.team", AudTag: audTag}, wantErr: true},
+		{name: "no audTag", access: &networkingv1alpha1.AccessSpec{TeamName: "myteam"}, wantErr: true},
+		{name: "empty audTag", access: &networkingv1alpha1.AccessSpec{TeamName: "myteam", AudTag: []string{"aud", ""}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateAccess(tt.access)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateAccess() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateAccess() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateImage(t *testing.T) {
+	tests := []struct {
+		image   string
+		wantErr bool
+	}{
+		{image: ""},
+		{image: "cloudflared"},
+		{image: "cloudflare/cloudflared:2023.2.1"},
+		{image: "registry.example.com:5000/mirror/cloudflared:latest"},
+		{image: "cloudflare/cloudflared@sha256:" + strings.Repeat("a", 64)},
+		{image: "cloudflare/Cloudflared", wantErr: true},
+		{image: "cloudflare/cloudflared:", wantErr: true},
+		{image: "cloudflare/cloudflared@sha256:abc", wantErr: true},
+		{image: "cloudflare/cloudflared latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if err := validateImage(tt.image); (err != nil) != tt.wantErr {
+				t.Errorf("validateImage(%q) error = %v, wantErr %v", tt.image, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseIPTarget(t *testing.T) {
+	tests := []struct {
+		target     string
+		wantScheme string
+		wantHost   string
+		wantPort   int32
+		wantOk     bool
+		wantErr    bool
+	}{
+		{target: "10.0.0.1:8080", wantHost: "10.0.0.1", wantPort: 8080, wantOk: true},
+		{target: "tcp://10.0.0.1:5432", wantScheme: "tcp", wantHost: "10.0.0.1", wantPort: 5432, wantOk: true},
+		{target: "[fd00::1]:443", wantHost: "fd00::1", wantPort: 443, wantOk: true},
+		{target: "app.default.svc:80"},
+		{target: "http://app.default.svc"},
+		{target: "10.0.0.1", wantOk: true, wantErr: true},
+		{target: "fd00::1", wantOk: true, wantErr: true},
+		{target: "10.0.0.1:0", wantOk: true, wantErr: true},
+		{target: "10.0.0.1:http", wantOk: true, wantErr: true},
+		{target: "ftp://10.0.0.1:21", wantOk: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			scheme, host, port, ok, err := parseIPTarget(tt.target)
+			if (err != nil) != tt.wantErr || ok != tt.wantOk {
+				t.Fatalf("parseIPTarget(%q) ok = %v, error = %v, want ok %v, wantErr %v", tt.target, ok, err, tt.wantOk, tt.wantErr)
+			}
+			if scheme != tt.wantScheme || host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("parseIPTarget(%q) = %q, %q, %d, want %q, %q, %d", tt.target, scheme, host, port, tt.wantScheme, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestProtoForAppProtocol(t *testing.T) {
+	tests := []struct {
+		appProtocol string
+		want        string
+	}{
+		{appProtocol: "", want: ""},
+		{appProtocol: "http", want: tunnelProtoHTTP},
+		{appProtocol: "HTTPS", want: tunnelProtoHTTPS},
+		{appProtocol: "kubernetes.io/h2c", want: tunnelProtoHTTP},
+		{appProtocol: "kubernetes.io/ws", want: tunnelProtoHTTP},
+		{appProtocol: "kubernetes.io/wss", want: tunnelProtoHTTPS},
+		{appProtocol: "grpc", want: tunnelProtoHTTPS},
+		{appProtocol: "ssh", want: tunnelProtoSSH},
+		{appProtocol: "udp", want: ""},
+		{appProtocol: "example.com/custom", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.appProtocol, func(t *testing.T) {
+			if got := protoForAppProtocol(tt.appProtocol); got != tt.want {
+				t.Errorf("protoForAppProtocol(%q) = %q, want %q", tt.appProtocol, got, tt.want)
+			}
+		})
+	}
+}
@@ -18,14 +18,17 @@ package main
 
 import (
 	"flag"
-	"go.uber.org/zap/zapcore"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -56,10 +59,46 @@ func main() {
 	var probeAddr string
 	var clusterResourceNamespace string
 	var overwriteUnmanaged bool
+	var waitForTunnelReady bool
+	var annotationPrefix string
+	var originMonitorInterval time.Duration
+	var logFormat string
+	var enableExport bool
+	var enableDNSSweep bool
+	var allowedServiceTypes string
+	var enableGatewayAPI bool
+	var migrateLegacyServices bool
+	var shutdownDrainTimeout time.Duration
+	var validateIngress bool
+	var manageCacheRules bool
+	var manageLoadBalancers bool
+	var maxDNSDeleteAttempts int
+	var circuitBreakerThreshold int
+	var circuitBreakerCooldown time.Duration
+	var deploymentCheckInterval time.Duration
+	var reportCertificateStatus bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&clusterResourceNamespace, "cluster-resource-namespace", "cloudflare-operator-system", "The default namespace for cluster scoped resources.")
 	flag.BoolVar(&overwriteUnmanaged, "overwrite-unmanaged-dns", false, "Overwrite DNS records that do not have a corresponding managed TXT record, defaults to false.")
+	flag.BoolVar(&waitForTunnelReady, "wait-for-tunnel-ready", false, "Wait for the tunnel Deployment to have a ready replica before creating DNS records, defaults to false.")
+	flag.StringVar(&annotationPrefix, "annotation-prefix", controllers.DefaultAnnotationPrefix, "The prefix of the labels, annotations and finalizers managed by the operator.")
+	flag.DurationVar(&originMonitorInterval, "origin-monitor-interval", 0, "Interval to check cloudflared metrics for origin errors and report them as Events on TunnelBindings, disabled if 0.")
+	flag.StringVar(&logFormat, "log-format", "", "The log encoding, json or console. Defaults to the zap-encoder flag, which defaults to console.")
+	flag.BoolVar(&enableExport, "enable-export", false, "Serve a YAML export of the managed tunnels, ingress rules and DNS records on "+controllers.ExportPath+" of the metrics endpoint.")
+	flag.BoolVar(&enableDNSSweep, "enable-dns-sweep", false, "Re-assert the DNS records of all TunnelBindings on a POST to "+controllers.DNSSweepPath+" of the metrics endpoint.")
+	flag.StringVar(&allowedServiceTypes, "allowed-service-types", "", "Comma separated Service types that can be tunneled, like LoadBalancer,NodePort. All types are allowed if empty.")
+	flag.BoolVar(&enableGatewayAPI, "enable-gateway-api", false, "Configure the Gateway API HTTPRoutes with a Tunnel or ClusterTunnel parentRef, requires the Gateway API CRDs.")
+	flag.BoolVar(&migrateLegacyServices, "migrate-legacy-services", false, "Remove the annotations, labels and finalizer set by pre v0.9 versions from the Services of TunnelBindings.")
+	flag.DurationVar(&shutdownDrainTimeout, "shutdown-drain-timeout", 30*time.Second, "How long to wait on shutdown for in-flight reconciles to finish writing the tunnel configurations.")
+	flag.BoolVar(&validateIngress, "validate-ingress", false, "Validate the ingress rules like cloudflared before writing a tunnel configuration, keeping the previous one if invalid.")
+	flag.BoolVar(&manageCacheRules, "manage-cache-rules", false, "Set the cache settings of TunnelBinding subjects as cache rules of the zone, requires the Cache Rules edit permission.")
+	flag.BoolVar(&manageLoadBalancers, "manage-load-balancers", false, "Split the hostnames of weighted TunnelBinding subjects with Cloudflare Load Balancers, requires the Load Balancers edit permission.")
+	flag.IntVar(&maxDNSDeleteAttempts, "max-dns-delete-attempts", 0, "Remove the finalizer of a deleted TunnelBinding after this many failed attempts to delete its DNS entries, leaving them orphaned. Retries forever if 0.")
+	flag.IntVar(&circuitBreakerThreshold, "circuit-breaker-threshold", 0, "Back off the DNS updates of a tunnel after this many consecutive Cloudflare API failures. Disabled if 0.")
+	flag.DurationVar(&circuitBreakerCooldown, "circuit-breaker-cooldown", 5*time.Minute, "How long a tunnel is backed off once its circuit breaker opens, before a single reconcile probes the Cloudflare API again.")
+	flag.DurationVar(&deploymentCheckInterval, "deployment-check-interval", 0, "Interval to check the cloudflared Deployments managed by the operator, recreating them if deleted out-of-band. Disabled if 0, relying on the Deployment watch only.")
+	flag.BoolVar(&reportCertificateStatus, "report-certificate-status", false, "Report whether the edge certificates of the TunnelBinding hostnames are active in their CertificateReady condition, requires the SSL and Certificates read permission.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", true,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -70,8 +109,21 @@ func main() {
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	switch logFormat {
+	case "":
+	case "json":
+		zap.JSONEncoder()(&opts)
+	case "console":
+		zap.ConsoleEncoder()(&opts)
+	default:
+		fmt.Fprintf(os.Stderr, "invalid log format %q, must be json or console\n", logFormat)
+		os.Exit(1)
+	}
+
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	controllers.SetAnnotationPrefix(annotationPrefix)
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                  scheme,
 		MetricsBindAddress:      metricsAddr,
@@ -80,35 +132,99 @@ func main() {
 		LeaderElection:          enableLeaderElection,
 		LeaderElectionID:        "9f193cf8.cfargotunnel.com",
 		LeaderElectionNamespace: clusterResourceNamespace,
+		GracefulShutdownTimeout: &shutdownDrainTimeout,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if err = (&controllers.TunnelBindingReconciler{
-		Client:    mgr.GetClient(),
-		Scheme:    mgr.GetScheme(),
-		Namespace: clusterResourceNamespace,
-	}).SetupWithManager(mgr); err != nil {
+	var serviceTypes []corev1.ServiceType
+	for _, serviceType := range strings.Split(allowedServiceTypes, ",") {
+		switch serviceType := corev1.ServiceType(strings.TrimSpace(serviceType)); serviceType {
+		case "":
+		case corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer, corev1.ServiceTypeExternalName:
+			serviceTypes = append(serviceTypes, serviceType)
+		default:
+			setupLog.Error(fmt.Errorf("invalid service type %q", serviceType), "unable to parse allowed service types")
+			os.Exit(1)
+		}
+	}
+
+	tunnelBindingReconciler := &controllers.TunnelBindingReconciler{
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Namespace:               clusterResourceNamespace,
+		WaitForTunnelReady:      waitForTunnelReady,
+		AllowedServiceTypes:     serviceTypes,
+		GatewayAPI:              enableGatewayAPI,
+		MigrateLegacyServices:   migrateLegacyServices,
+		DrainTimeout:            shutdownDrainTimeout,
+		ValidateIngress:         validateIngress,
+		ManageCacheRules:        manageCacheRules,
+		ManageLoadBalancers:     manageLoadBalancers,
+		MaxDNSDeleteAttempts:    maxDNSDeleteAttempts,
+		CircuitBreakerThreshold: circuitBreakerThreshold,
+		CircuitBreakerCooldown:  circuitBreakerCooldown,
+		ReportCertificateStatus: reportCertificateStatus,
+	}
+	if err = tunnelBindingReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "TunnelBinding")
 		os.Exit(1)
 	}
+	if enableGatewayAPI {
+		if err = (&controllers.HTTPRouteReconciler{
+			Client:   mgr.GetClient(),
+			Bindings: tunnelBindingReconciler,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "HTTPRoute")
+			os.Exit(1)
+		}
+	}
 	if err = (&controllers.TunnelReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		DeploymentCheckInterval: deploymentCheckInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Tunnel")
 		os.Exit(1)
 	}
 	if err = (&controllers.ClusterTunnelReconciler{
-		Client:    mgr.GetClient(),
-		Scheme:    mgr.GetScheme(),
-		Namespace: clusterResourceNamespace,
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Namespace:               clusterResourceNamespace,
+		DeploymentCheckInterval: deploymentCheckInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterTunnel")
 		os.Exit(1)
 	}
+	if enableExport {
+		if err = (&controllers.ManagedExport{
+			Client:    mgr.GetClient(),
+			Namespace: clusterResourceNamespace,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to set up export", "path", controllers.ExportPath)
+			os.Exit(1)
+		}
+	}
+	if enableDNSSweep {
+		if err = (&controllers.DNSSweep{
+			Bindings: tunnelBindingReconciler,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to set up DNS sweep", "path", controllers.DNSSweepPath)
+			os.Exit(1)
+		}
+	}
+	if originMonitorInterval > 0 {
+		if err = (&controllers.OriginMonitor{
+			Client:    mgr.GetClient(),
+			Namespace: clusterResourceNamespace,
+			Interval:  originMonitorInterval,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create origin monitor")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {